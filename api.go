@@ -3,4 +3,49 @@ package pcconfig
 type Config struct {
 	ControlKey string   `json:"controlKey"`
 	Cameras    []Camera `json:"cameras"`
+
+	// Overrides are the per-PC overrides that were merged into Cameras.
+	Overrides *AppliedOverrides `json:"overrides,omitempty"`
+}
+
+// ConfigSchemaV2 is the schema version of ConfigV2.
+const ConfigSchemaV2 = 2
+
+// ConfigV2 is the expanded config served by the v2 api.
+type ConfigV2 struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Version is the version of the config, which is what PCs send when they check in.
+	Version string `json:"version"`
+
+	Hostname     string `json:"hostname"`
+	Room         string `json:"room"`
+	ControlGroup string `json:"controlGroup"`
+
+	// Mapping is the hostname (or prefix of it) of the pc mapping Hostname matched.
+	Mapping string `json:"mapping,omitempty"`
+
+	Cameras    []Camera    `json:"cameras"`
+	ControlKey *ControlKey `json:"controlKey,omitempty"`
+
+	// Overrides are the per-PC overrides that were merged into Cameras.
+	Overrides *AppliedOverrides `json:"overrides,omitempty"`
+}
+
+// ControlKey is the key used to control a room.
+type ControlKey struct {
+	Key string `json:"key"`
+}
+
+// RoomConfig is the config of every control group in a room.
+type RoomConfig struct {
+	Room          string               `json:"room"`
+	ControlGroups []ControlGroupConfig `json:"controlGroups"`
+}
+
+// ControlGroupConfig is the config of a single control group, without a PC.
+type ControlGroupConfig struct {
+	Name       string   `json:"name"`
+	Cameras    []Camera `json:"cameras"`
+	ControlKey string   `json:"controlKey,omitempty"`
 }
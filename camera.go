@@ -0,0 +1,157 @@
+package pcconfig
+
+// CapabilityType identifies a feature a camera supports.
+type CapabilityType string
+
+const (
+	CapabilityPTZ      CapabilityType = "ptz"
+	CapabilityZoom     CapabilityType = "zoom"
+	CapabilityFocus    CapabilityType = "focus"
+	CapabilityExposure CapabilityType = "exposure"
+	CapabilityTracking CapabilityType = "tracking"
+	CapabilityPrivacy  CapabilityType = "privacy"
+)
+
+// Names of the actions a CameraCapability can expose. The pan/tilt/zoom
+// actions share their names with the legacy Camera fields.
+const (
+	ActionTiltUp      = "tiltUp"
+	ActionTiltDown    = "tiltDown"
+	ActionPanLeft     = "panLeft"
+	ActionPanRight    = "panRight"
+	ActionPanTiltStop = "panTiltStop"
+
+	ActionZoomIn   = "zoomIn"
+	ActionZoomOut  = "zoomOut"
+	ActionZoomStop = "zoomStop"
+
+	ActionFocusNear = "focusNear"
+	ActionFocusFar  = "focusFar"
+	ActionFocusStop = "focusStop"
+	ActionFocusAuto = "focusAuto"
+
+	ActionIrisOpen     = "irisOpen"
+	ActionIrisClose    = "irisClose"
+	ActionExposureAuto = "exposureAuto"
+
+	ActionTrackingOn  = "trackingOn"
+	ActionTrackingOff = "trackingOff"
+
+	ActionPrivacyOn  = "privacyOn"
+	ActionPrivacyOff = "privacyOff"
+
+	// ActionSetPreset recalls one of the camera's presets
+	ActionSetPreset = "setPreset"
+
+	// ActionSavePreset stores the camera's position in one of its presets
+	ActionSavePreset = "savePreset"
+)
+
+// CameraCapability describes one feature of a camera and how to control it.
+type CameraCapability struct {
+	Type    CapabilityType `json:"type"`
+	Actions []CameraAction `json:"actions"`
+
+	// Parameters holds capability specific settings (speeds, ranges, etc.)
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// CameraAction is a single control a capability exposes.
+type CameraAction struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// Parameters holds action specific settings
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// Capability returns the capability of type t, if the camera has it.
+func (c Camera) Capability(t CapabilityType) (CameraCapability, bool) {
+	for _, capability := range c.Capabilities {
+		if capability.Type == t {
+			return capability, true
+		}
+	}
+
+	return CameraCapability{}, false
+}
+
+// Action returns the action named name from any of the camera's capabilities.
+func (c Camera) Action(name string) (CameraAction, bool) {
+	for _, capability := range c.Capabilities {
+		for _, action := range capability.Actions {
+			if action.Name == name {
+				return action, true
+			}
+		}
+	}
+
+	return CameraAction{}, false
+}
+
+// NormalizeCapabilities makes the legacy pan/tilt/zoom fields and the
+// capabilities on c agree with each other. Capabilities are built from the
+// legacy fields for cameras that don't define any, and the legacy fields are
+// filled in from the ptz/zoom capabilities so older PCs keep working.
+func NormalizeCapabilities(c Camera) Camera {
+	legacy := []struct {
+		capability CapabilityType
+		action     string
+		url        *string
+	}{
+		{CapabilityPTZ, ActionTiltUp, &c.TiltUp},
+		{CapabilityPTZ, ActionTiltDown, &c.TiltDown},
+		{CapabilityPTZ, ActionPanLeft, &c.PanLeft},
+		{CapabilityPTZ, ActionPanRight, &c.PanRight},
+		{CapabilityPTZ, ActionPanTiltStop, &c.PanTiltStop},
+		{CapabilityZoom, ActionZoomIn, &c.ZoomIn},
+		{CapabilityZoom, ActionZoomOut, &c.ZoomOut},
+		{CapabilityZoom, ActionZoomStop, &c.ZoomStop},
+	}
+
+	if len(c.Capabilities) == 0 {
+		var caps []CameraCapability
+		index := make(map[CapabilityType]int)
+
+		for _, l := range legacy {
+			if *l.url == "" {
+				continue
+			}
+
+			i, ok := index[l.capability]
+			if !ok {
+				i = len(caps)
+				index[l.capability] = i
+				caps = append(caps, CameraCapability{Type: l.capability})
+			}
+
+			caps[i].Actions = append(caps[i].Actions, CameraAction{
+				Name: l.action,
+				URL:  *l.url,
+			})
+		}
+
+		c.Capabilities = caps
+		return c
+	}
+
+	for _, l := range legacy {
+		if *l.url != "" {
+			continue
+		}
+
+		capability, ok := c.Capability(l.capability)
+		if !ok {
+			continue
+		}
+
+		for _, action := range capability.Actions {
+			if action.Name == l.action {
+				*l.url = action.URL
+				break
+			}
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,74 @@
+package pcconfig
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeCapabilitiesFromLegacy(t *testing.T) {
+	cam := Camera{
+		DisplayName: "fixed zoom",
+		ZoomIn:      "https://zoomIn",
+		ZoomOut:     "https://zoomOut",
+		ZoomStop:    "https://zoomStop",
+	}
+
+	expected := []CameraCapability{
+		{
+			Type: CapabilityZoom,
+			Actions: []CameraAction{
+				{Name: ActionZoomIn, URL: "https://zoomIn"},
+				{Name: ActionZoomOut, URL: "https://zoomOut"},
+				{Name: ActionZoomStop, URL: "https://zoomStop"},
+			},
+		},
+	}
+
+	got := NormalizeCapabilities(cam)
+	if diff := cmp.Diff(expected, got.Capabilities); diff != "" {
+		t.Errorf("generated incorrect capabilities (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNormalizeCapabilitiesToLegacy(t *testing.T) {
+	cam := Camera{
+		DisplayName: "ptz",
+		Capabilities: []CameraCapability{
+			{
+				Type: CapabilityPTZ,
+				Actions: []CameraAction{
+					{Name: ActionTiltUp, URL: "https://tiltUp"},
+					{Name: ActionPanTiltStop, URL: "https://panTiltStop"},
+				},
+			},
+			{
+				Type: CapabilityFocus,
+				Actions: []CameraAction{
+					{Name: ActionFocusAuto, URL: "https://focusAuto"},
+				},
+			},
+		},
+	}
+
+	got := NormalizeCapabilities(cam)
+	switch {
+	case got.TiltUp != "https://tiltUp":
+		t.Fatalf("expected tiltUp to be filled in, got %q", got.TiltUp)
+	case got.PanTiltStop != "https://panTiltStop":
+		t.Fatalf("expected panTiltStop to be filled in, got %q", got.PanTiltStop)
+	case got.ZoomIn != "":
+		t.Fatalf("expected zoomIn to be empty, got %q", got.ZoomIn)
+	}
+
+	if diff := cmp.Diff(cam.Capabilities, got.Capabilities); diff != "" {
+		t.Errorf("capabilities changed (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNormalizeCapabilitiesFixed(t *testing.T) {
+	got := NormalizeCapabilities(Camera{DisplayName: "fixed", Stream: "https://stream"})
+	if len(got.Capabilities) != 0 {
+		t.Fatalf("expected no capabilities, got %v", got.Capabilities)
+	}
+}
@@ -0,0 +1,69 @@
+// Package cameras defines the drivers pc-config uses to control cameras
+// directly, rather than through the URLs in a pcconfig.Camera.
+package cameras
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// ErrUnsupported is returned when a driver can't do the requested action.
+var ErrUnsupported = errors.New("action not supported by this camera")
+
+// Driver controls a single camera.
+type Driver interface {
+	TiltUp(ctx context.Context) error
+	TiltDown(ctx context.Context) error
+	PanLeft(ctx context.Context) error
+	PanRight(ctx context.Context) error
+	PanTiltStop(ctx context.Context) error
+
+	ZoomIn(ctx context.Context) error
+	ZoomOut(ctx context.Context) error
+	ZoomStop(ctx context.Context) error
+
+	// GoToPreset moves the camera to preset.
+	GoToPreset(ctx context.Context, preset string) error
+}
+
+// PresetSaver is a Driver that can store the current position in a preset.
+type PresetSaver interface {
+	SavePreset(ctx context.Context, preset string) error
+}
+
+// PresetLister is a Driver that can list the presets stored on the camera.
+type PresetLister interface {
+	Presets(ctx context.Context) ([]pcconfig.CameraPreset, error)
+}
+
+// NewDriverFunc builds a Driver for the camera at address.
+type NewDriverFunc func(address string) (Driver, error)
+
+// Do runs the pcconfig action named action on d. preset is only used for preset actions.
+func Do(ctx context.Context, d Driver, action, preset string) error {
+	switch action {
+	case pcconfig.ActionTiltUp:
+		return d.TiltUp(ctx)
+	case pcconfig.ActionTiltDown:
+		return d.TiltDown(ctx)
+	case pcconfig.ActionPanLeft:
+		return d.PanLeft(ctx)
+	case pcconfig.ActionPanRight:
+		return d.PanRight(ctx)
+	case pcconfig.ActionPanTiltStop:
+		return d.PanTiltStop(ctx)
+	case pcconfig.ActionZoomIn:
+		return d.ZoomIn(ctx)
+	case pcconfig.ActionZoomOut:
+		return d.ZoomOut(ctx)
+	case pcconfig.ActionZoomStop:
+		return d.ZoomStop(ctx)
+	case pcconfig.ActionSetPreset:
+		return d.GoToPreset(ctx, preset)
+	}
+
+	return fmt.Errorf("%s: %w", action, ErrUnsupported)
+}
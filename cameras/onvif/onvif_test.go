@@ -0,0 +1,181 @@
+package onvif
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/cameras"
+	"github.com/google/go-cmp/cmp"
+)
+
+// stubCamera is a local ONVIF SOAP server that records every request body.
+type stubCamera struct {
+	*httptest.Server
+	requests []string
+}
+
+func newStubCamera(t *testing.T) *stubCamera {
+	t.Helper()
+
+	stub := &stubCamera{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		stub.requests = append(stub.requests, r.URL.Path+" "+string(body))
+
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+
+		switch {
+		case strings.Contains(string(body), "GetProfiles"):
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:trt="http://www.onvif.org/ver10/media/wsdl">
+	<s:Body>
+		<trt:GetProfilesResponse>
+			<trt:Profiles token="profile_1" fixed="true"/>
+			<trt:Profiles token="profile_2" fixed="true"/>
+		</trt:GetProfilesResponse>
+	</s:Body>
+</s:Envelope>`))
+		case strings.Contains(string(body), "GetPresets"):
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl" xmlns:tt="http://www.onvif.org/ver10/schema">
+	<s:Body>
+		<tptz:GetPresetsResponse>
+			<tptz:Preset token="1"><tt:Name>Podium</tt:Name></tptz:Preset>
+			<tptz:Preset token="2"><tt:Name>Whiteboard</tt:Name></tptz:Preset>
+			<tptz:Preset token="3"></tptz:Preset>
+		</tptz:GetPresetsResponse>
+	</s:Body>
+</s:Envelope>`))
+		case strings.Contains(string(body), "GotoPreset") && strings.Contains(string(body), "<tptz:PresetToken>99</tptz:PresetToken>"):
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">
+	<s:Body>
+		<s:Fault>
+			<s:Code><s:Value>s:Sender</s:Value></s:Code>
+			<s:Reason><s:Text xml:lang="en">No such preset</s:Text></s:Reason>
+		</s:Fault>
+	</s:Body>
+</s:Envelope>`))
+		default:
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body/></s:Envelope>`))
+		}
+	}))
+
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func TestCommands(t *testing.T) {
+	stub := newStubCamera(t)
+
+	d, err := New(stub.URL)
+	if err != nil {
+		t.Fatalf("unable to create driver: %s", err)
+	}
+
+	tests := []struct {
+		action   string
+		preset   string
+		expected string
+	}{
+		{pcconfig.ActionTiltUp, "", `<tt:PanTilt x="0" y="0.5"/>`},
+		{pcconfig.ActionTiltDown, "", `<tt:PanTilt x="0" y="-0.5"/>`},
+		{pcconfig.ActionPanLeft, "", `<tt:PanTilt x="-0.5" y="0"/>`},
+		{pcconfig.ActionPanRight, "", `<tt:PanTilt x="0.5" y="0"/>`},
+		{pcconfig.ActionPanTiltStop, "", `<tptz:PanTilt>true</tptz:PanTilt><tptz:Zoom>false</tptz:Zoom>`},
+		{pcconfig.ActionZoomIn, "", `<tt:Zoom x="0.5"/>`},
+		{pcconfig.ActionZoomOut, "", `<tt:Zoom x="-0.5"/>`},
+		{pcconfig.ActionZoomStop, "", `<tptz:PanTilt>false</tptz:PanTilt><tptz:Zoom>true</tptz:Zoom>`},
+		{pcconfig.ActionSetPreset, "2", `<tptz:GotoPreset xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl"><tptz:ProfileToken>profile_1</tptz:ProfileToken><tptz:PresetToken>2</tptz:PresetToken></tptz:GotoPreset>`},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	for _, tt := range tests {
+		if err := cameras.Do(ctx, d, tt.action, tt.preset); err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.action, err)
+		}
+
+		last := stub.requests[len(stub.requests)-1]
+		switch {
+		case !strings.HasPrefix(last, _defaultPTZPath+" "):
+			t.Fatalf("%s: expected request to the ptz service, got %s", tt.action, last)
+		case !strings.Contains(last, tt.expected):
+			t.Fatalf("%s: expected request to contain %s, got %s", tt.action, tt.expected, last)
+		case !strings.Contains(last, "<tptz:ProfileToken>profile_1</tptz:ProfileToken>"):
+			t.Fatalf("%s: expected the first profile to be used, got %s", tt.action, last)
+		}
+	}
+
+	// profiles should only be looked up once
+	if !strings.HasPrefix(stub.requests[0], _defaultMediaPath+" ") || len(stub.requests) != len(tests)+1 {
+		t.Fatalf("unexpected requests: %v", stub.requests)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	stub := newStubCamera(t)
+	cam := &Camera{Address: stub.URL, ProfileToken: "profile_2"}
+
+	presets, err := cam.Presets(context.Background())
+	if err != nil {
+		t.Fatalf("unable to get presets: %s", err)
+	}
+
+	expected := []pcconfig.CameraPreset{
+		{DisplayName: "Podium", Preset: "1"},
+		{DisplayName: "Whiteboard", Preset: "2"},
+		{DisplayName: "3", Preset: "3"},
+	}
+
+	if diff := cmp.Diff(expected, presets); diff != "" {
+		t.Errorf("got incorrect presets (-want, +got):\n%s", diff)
+	}
+
+	if len(stub.requests) != 1 || !strings.Contains(stub.requests[0], "<tptz:ProfileToken>profile_2</tptz:ProfileToken>") {
+		t.Fatalf("unexpected requests: %v", stub.requests)
+	}
+}
+
+func TestFault(t *testing.T) {
+	stub := newStubCamera(t)
+	cam := &Camera{Address: stub.URL, ProfileToken: "profile_1"}
+
+	err := cam.GoToPreset(context.Background(), "99")
+	if err == nil || !strings.Contains(err.Error(), "No such preset") {
+		t.Fatalf("expected fault error, got %v", err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	stub := newStubCamera(t)
+
+	d, err := New(strings.Replace(stub.URL, "http://", "http://admin:secret@", 1))
+	if err != nil {
+		t.Fatalf("unable to create driver: %s", err)
+	}
+
+	cam := d.(*Camera)
+	cam.ProfileToken = "profile_1"
+
+	if err := cam.PanTiltStop(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	switch {
+	case cam.Address != stub.URL:
+		t.Fatalf("expected credentials to be removed from address, got %s", cam.Address)
+	case !strings.Contains(stub.requests[0], "<wsse:Username>admin</wsse:Username>"):
+		t.Fatalf("expected a security header, got %s", stub.requests[0])
+	case strings.Contains(stub.requests[0], "secret"):
+		t.Fatalf("password was sent in plain text: %s", stub.requests[0])
+	}
+}
@@ -0,0 +1,205 @@
+// Package visca controls cameras using VISCA over IP.
+package visca
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/byuoitav/pc-config/cameras"
+)
+
+const (
+	_defaultPort    = "52381"
+	_defaultTimeout = 2 * time.Second
+
+	_defaultPanSpeed  = 0x0c
+	_defaultTiltSpeed = 0x0a
+	_defaultZoomSpeed = 0x04
+)
+
+// payload types in the VISCA over IP header
+var (
+	_typeCommand = []byte{0x01, 0x00}
+	_typeReply   = []byte{0x01, 0x11}
+)
+
+// Camera is a camera controlled with VISCA over UDP.
+type Camera struct {
+	// Address is the host:port of the camera. Port 52381 is used if it isn't included.
+	Address string
+
+	// PanSpeed (1-0x18), TiltSpeed (1-0x17), and ZoomSpeed (0-7) control how
+	// fast the camera moves. Reasonable defaults are used if they are 0.
+	PanSpeed  byte
+	TiltSpeed byte
+	ZoomSpeed byte
+
+	// Timeout is how long to wait for the camera to acknowledge a command.
+	Timeout time.Duration
+
+	seq uint32
+}
+
+// New returns a driver for the camera at address.
+func New(address string) (cameras.Driver, error) {
+	if address == "" {
+		return nil, errors.New("address is required")
+	}
+
+	return &Camera{Address: address}, nil
+}
+
+func (c *Camera) TiltUp(ctx context.Context) error {
+	return c.panTilt(ctx, 0x03, 0x01)
+}
+
+func (c *Camera) TiltDown(ctx context.Context) error {
+	return c.panTilt(ctx, 0x03, 0x02)
+}
+
+func (c *Camera) PanLeft(ctx context.Context) error {
+	return c.panTilt(ctx, 0x01, 0x03)
+}
+
+func (c *Camera) PanRight(ctx context.Context) error {
+	return c.panTilt(ctx, 0x02, 0x03)
+}
+
+func (c *Camera) PanTiltStop(ctx context.Context) error {
+	return c.panTilt(ctx, 0x03, 0x03)
+}
+
+func (c *Camera) ZoomIn(ctx context.Context) error {
+	return c.send(ctx, []byte{0x81, 0x01, 0x04, 0x07, 0x20 | c.zoomSpeed(), 0xff})
+}
+
+func (c *Camera) ZoomOut(ctx context.Context) error {
+	return c.send(ctx, []byte{0x81, 0x01, 0x04, 0x07, 0x30 | c.zoomSpeed(), 0xff})
+}
+
+func (c *Camera) ZoomStop(ctx context.Context) error {
+	return c.send(ctx, []byte{0x81, 0x01, 0x04, 0x07, 0x00, 0xff})
+}
+
+// GoToPreset recalls preset, which must be a preset number (0-255).
+func (c *Camera) GoToPreset(ctx context.Context, preset string) error {
+	num, err := presetNumber(preset)
+	if err != nil {
+		return err
+	}
+
+	return c.send(ctx, []byte{0x81, 0x01, 0x04, 0x3f, 0x02, num, 0xff})
+}
+
+// SavePreset stores the current position in preset, which must be a preset number (0-255).
+func (c *Camera) SavePreset(ctx context.Context, preset string) error {
+	num, err := presetNumber(preset)
+	if err != nil {
+		return err
+	}
+
+	return c.send(ctx, []byte{0x81, 0x01, 0x04, 0x3f, 0x01, num, 0xff})
+}
+
+func presetNumber(preset string) (byte, error) {
+	num, err := strconv.ParseUint(preset, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid preset %q: %w", preset, err)
+	}
+
+	return byte(num), nil
+}
+
+func (c *Camera) panTilt(ctx context.Context, pan, tilt byte) error {
+	panSpeed, tiltSpeed := c.PanSpeed, c.TiltSpeed
+	if panSpeed == 0 {
+		panSpeed = _defaultPanSpeed
+	}
+
+	if tiltSpeed == 0 {
+		tiltSpeed = _defaultTiltSpeed
+	}
+
+	return c.send(ctx, []byte{0x81, 0x01, 0x06, 0x01, panSpeed, tiltSpeed, pan, tilt, 0xff})
+}
+
+func (c *Camera) zoomSpeed() byte {
+	if c.ZoomSpeed == 0 {
+		return _defaultZoomSpeed
+	}
+
+	return c.ZoomSpeed & 0x07
+}
+
+func (c *Camera) address() string {
+	if _, _, err := net.SplitHostPort(c.Address); err == nil {
+		return c.Address
+	}
+
+	return net.JoinHostPort(c.Address, _defaultPort)
+}
+
+// send sends cmd to the camera and waits for it to be acknowledged.
+func (c *Camera) send(ctx context.Context, cmd []byte) error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = _defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", c.address())
+	if err != nil {
+		return fmt.Errorf("unable to dial camera: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("unable to set deadline: %w", err)
+		}
+	}
+
+	seq := atomic.AddUint32(&c.seq, 1)
+
+	msg := make([]byte, 8, 8+len(cmd))
+	copy(msg, _typeCommand)
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(cmd)))
+	binary.BigEndian.PutUint32(msg[4:], seq)
+	msg = append(msg, cmd...)
+
+	if _, err := conn.Write(msg); err != nil {
+		return fmt.Errorf("unable to write command: %w", err)
+	}
+
+	buf := make([]byte, 64)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return fmt.Errorf("unable to read reply: %w", err)
+		}
+
+		if n < 11 || buf[0] != _typeReply[0] || buf[1] != _typeReply[1] || binary.BigEndian.Uint32(buf[4:]) != seq {
+			// not a reply to our command
+			continue
+		}
+
+		reply := buf[8:n]
+		switch reply[1] & 0xf0 {
+		case 0x40, 0x50: // ack, completion
+			return nil
+		case 0x60:
+			return fmt.Errorf("camera returned error 0x%02x", reply[2])
+		default:
+			return fmt.Errorf("unexpected reply from camera: % x", reply)
+		}
+	}
+}
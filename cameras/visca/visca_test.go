@@ -0,0 +1,160 @@
+package visca
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/cameras"
+)
+
+// fakeCamera is an in-process VISCA over IP responder.
+type fakeCamera struct {
+	conn     net.PacketConn
+	commands chan []byte
+
+	// reply is the VISCA reply sent for every command
+	reply []byte
+}
+
+func newFakeCamera(t *testing.T, reply []byte) *fakeCamera {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %s", err)
+	}
+
+	f := &fakeCamera{
+		conn:     conn,
+		commands: make(chan []byte, 16),
+		reply:    reply,
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	go f.serve()
+	return f
+}
+
+func (f *fakeCamera) serve() {
+	buf := make([]byte, 64)
+	for {
+		n, addr, err := f.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		if n < 8 || buf[0] != 0x01 || buf[1] != 0x00 {
+			continue
+		}
+
+		cmd := make([]byte, n-8)
+		copy(cmd, buf[8:n])
+		f.commands <- cmd
+
+		msg := make([]byte, 8, 8+len(f.reply))
+		copy(msg, _typeReply)
+		binary.BigEndian.PutUint16(msg[2:], uint16(len(f.reply)))
+		copy(msg[4:], buf[4:8])
+		msg = append(msg, f.reply...)
+
+		_, _ = f.conn.WriteTo(msg, addr)
+	}
+}
+
+var ack = []byte{0x90, 0x41, 0xff}
+
+func TestCommands(t *testing.T) {
+	fake := newFakeCamera(t, ack)
+	cam := &Camera{Address: fake.conn.LocalAddr().String()}
+
+	tests := []struct {
+		action   string
+		preset   string
+		expected []byte
+	}{
+		{pcconfig.ActionTiltUp, "", []byte{0x81, 0x01, 0x06, 0x01, 0x0c, 0x0a, 0x03, 0x01, 0xff}},
+		{pcconfig.ActionTiltDown, "", []byte{0x81, 0x01, 0x06, 0x01, 0x0c, 0x0a, 0x03, 0x02, 0xff}},
+		{pcconfig.ActionPanLeft, "", []byte{0x81, 0x01, 0x06, 0x01, 0x0c, 0x0a, 0x01, 0x03, 0xff}},
+		{pcconfig.ActionPanRight, "", []byte{0x81, 0x01, 0x06, 0x01, 0x0c, 0x0a, 0x02, 0x03, 0xff}},
+		{pcconfig.ActionPanTiltStop, "", []byte{0x81, 0x01, 0x06, 0x01, 0x0c, 0x0a, 0x03, 0x03, 0xff}},
+		{pcconfig.ActionZoomIn, "", []byte{0x81, 0x01, 0x04, 0x07, 0x24, 0xff}},
+		{pcconfig.ActionZoomOut, "", []byte{0x81, 0x01, 0x04, 0x07, 0x34, 0xff}},
+		{pcconfig.ActionZoomStop, "", []byte{0x81, 0x01, 0x04, 0x07, 0x00, 0xff}},
+		{pcconfig.ActionSetPreset, "3", []byte{0x81, 0x01, 0x04, 0x3f, 0x02, 0x03, 0xff}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	for _, tt := range tests {
+		if err := cameras.Do(ctx, cam, tt.action, tt.preset); err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.action, err)
+		}
+
+		if got := <-fake.commands; !bytes.Equal(got, tt.expected) {
+			t.Fatalf("%s: expected % x, got % x", tt.action, tt.expected, got)
+		}
+	}
+}
+
+func TestSavePreset(t *testing.T) {
+	fake := newFakeCamera(t, []byte{0x90, 0x51, 0xff})
+	cam := &Camera{Address: fake.conn.LocalAddr().String()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := cam.SavePreset(ctx, "12"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []byte{0x81, 0x01, 0x04, 0x3f, 0x01, 0x0c, 0xff}
+	if got := <-fake.commands; !bytes.Equal(got, expected) {
+		t.Fatalf("expected % x, got % x", expected, got)
+	}
+}
+
+func TestInvalidPreset(t *testing.T) {
+	cam := &Camera{Address: "127.0.0.1"}
+
+	err := cam.GoToPreset(context.Background(), "podium")
+	if err == nil || !strings.Contains(err.Error(), "invalid preset") {
+		t.Fatalf("expected invalid preset error, got %v", err)
+	}
+}
+
+func TestCameraError(t *testing.T) {
+	fake := newFakeCamera(t, []byte{0x90, 0x60, 0x02, 0xff})
+	cam := &Camera{Address: fake.conn.LocalAddr().String()}
+
+	err := cam.ZoomIn(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "camera returned error 0x02") {
+		t.Fatalf("expected camera error, got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %s", err)
+	}
+	defer conn.Close()
+
+	cam := &Camera{
+		Address: conn.LocalAddr().String(),
+		Timeout: 50 * time.Millisecond,
+	}
+
+	if err := cam.PanTiltStop(context.Background()); err == nil {
+		t.Fatalf("expected a timeout error")
+	}
+}
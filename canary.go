@@ -0,0 +1,41 @@
+package pcconfig
+
+import (
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// Canary is the PCs a change set is served to before it is applied to everyone.
+type Canary struct {
+	// Hostnames are PCs that always get the change set.
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Percent of all other PCs that get the change set. The same PCs are chosen every
+	// time, and raising it keeps the PCs that were already chosen.
+	Percent int `json:"percent,omitempty"`
+
+	// Fetched is when each PC first fetched the change set.
+	Fetched map[string]time.Time `json:"fetched,omitempty"`
+}
+
+// Targets returns true if cs should be served to hostname.
+func (cs ChangeSet) Targets(hostname string) bool {
+	if cs.Canary == nil {
+		return true
+	}
+
+	for _, h := range cs.Canary.Hostnames {
+		if strings.EqualFold(h, hostname) {
+			return true
+		}
+	}
+
+	if cs.Canary.Percent <= 0 {
+		return false
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(cs.ID + "/" + strings.ToUpper(hostname)))
+	return int(h.Sum32()%100) < cs.Canary.Percent
+}
@@ -0,0 +1,49 @@
+package pcconfig
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestChangeSetTargets(t *testing.T) {
+	cs := ChangeSet{
+		ID: "fall",
+		Canary: &Canary{
+			Hostnames: []string{"ITB-1101-CP1"},
+		},
+	}
+
+	switch {
+	case !cs.Targets("itb-1101-cp1"):
+		t.Fatalf("expected named hostname to be targeted")
+	case cs.Targets("ITB-1101-CP2"):
+		t.Fatalf("expected other hostnames not to be targeted")
+	case !(ChangeSet{ID: "all"}).Targets("ITB-1101-CP2"):
+		t.Fatalf("expected change sets without a canary to target everyone")
+	}
+
+	targeted := func(percent int) map[string]bool {
+		cs.Canary.Percent = percent
+
+		m := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			hostname := fmt.Sprintf("ITB-%d-CP1", i)
+			if cs.Targets(hostname) {
+				m[hostname] = true
+			}
+		}
+
+		return m
+	}
+
+	ten, fifty := targeted(10), targeted(50)
+	if len(ten) < 50 || len(ten) > 150 || len(fifty) < 400 || len(fifty) > 600 {
+		t.Fatalf("expected about 10%% and 50%% of PCs to be targeted, got %d and %d", len(ten), len(fifty))
+	}
+
+	for hostname := range ten {
+		if !fifty[hostname] {
+			t.Fatalf("expected %s to stay targeted when the percent is raised", hostname)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package pcconfig
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CheckIn is what a PC reports after it applies its config.
+type CheckIn struct {
+	Hostname string `json:"hostname"`
+
+	// ConfigVersion is the version (the ETag) of the config the PC applied.
+	ConfigVersion string `json:"configVersion"`
+
+	AppVersion    string `json:"appVersion,omitempty"`
+	UptimeSeconds int64  `json:"uptimeSeconds,omitempty"`
+
+	// Time is when pc-config received the check in.
+	Time time.Time `json:"time"`
+}
+
+// Version returns a hash of the configuration in c, which changes whenever it does. The control key
+// and the status of each camera aren't part of it, since they change without the configuration changing.
+func (c Config) Version() (string, error) {
+	cams := make([]Camera, len(c.Cameras))
+	for i, cam := range c.Cameras {
+		cam.Status = ""
+		cams[i] = cam
+	}
+
+	data, err := json.Marshal(struct {
+		Cameras   []Camera          `json:"cameras"`
+		Overrides *AppliedOverrides `json:"overrides,omitempty"`
+	}{cams, c.Overrides})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal config: %w", err)
+	}
+
+	return fmt.Sprintf("%x", sha1.Sum(data)), nil
+}
+
+// NormalizeVersion strips the quotes, weak prefix, and body hash from a config version that was sent as an ETag.
+func NormalizeVersion(version string) string {
+	version = strings.Trim(strings.TrimPrefix(strings.TrimSpace(version), "W/"), `"`)
+	if i := strings.Index(version, "-"); i >= 0 {
+		version = version[:i]
+	}
+
+	return version
+}
@@ -0,0 +1,34 @@
+package pcconfig
+
+import "testing"
+
+func TestConfigVersion(t *testing.T) {
+	config := Config{
+		ControlKey: "1234",
+		Cameras:    []Camera{{DisplayName: "Front", Stream: "https://front/stream", Status: "up"}},
+	}
+
+	version, err := config.Version()
+	if err != nil {
+		t.Fatalf("unable to get version: %s", err)
+	}
+
+	// the control key and camera status aren't configuration
+	same := Config{
+		ControlKey: "5678",
+		Cameras:    []Camera{{DisplayName: "Front", Stream: "https://front/stream", Status: "down"}},
+	}
+
+	if got, _ := same.Version(); got != version {
+		t.Errorf("expected version %s without the control key and status, got %s", version, got)
+	}
+
+	for _, changed := range []Config{
+		{Cameras: []Camera{{DisplayName: "Front", Stream: "https://front/stream-low"}}},
+		{Cameras: same.Cameras, Overrides: &AppliedOverrides{Source: "ITB-1101-CP1"}},
+	} {
+		if got, _ := changed.Version(); got == version {
+			t.Errorf("expected the version of %+v to change", changed)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+// commands are the subcommands pc-config can run instead of starting the server.
+// each returns the code to exit with.
+var commands = map[string]func(args []string) int{
+	"validate": validateCmd,
+	"report":   reportCmd,
+	"snapshot": snapshotCmd,
+	"sql":      sqlCmd,
+}
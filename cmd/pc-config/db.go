@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/couch"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+// dbFlags are the flags used to connect to the database.
+type dbFlags struct {
+	addr     string
+	username string
+	password string
+	insecure bool
+
+	// snapshot, if set, is the snapshot to read the config from instead of the database.
+	snapshot string
+
+	// sqlDSN, if set, is the PostgreSQL database to use instead of couchdb.
+	sqlDSN string
+
+	auth         string
+	passwordFile string
+	jwtFile      string
+	proxySecret  string
+	proxyRoles   []string
+
+	validation string
+
+	timeout         time.Duration
+	retries         int
+	retryBackoff    time.Duration
+	breakerFailures int
+	breakerCooldown time.Duration
+
+	caFile   string
+	certFile string
+	keyFile  string
+
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	dialTimeout         time.Duration
+
+	// log, if set, is where problems with documents are logged.
+	log *zap.Logger
+}
+
+func (f *dbFlags) register(fs *pflag.FlagSet) {
+	fs.StringVar(&f.addr, "db-address", "", "database address")
+	fs.StringVar(&f.username, "db-username", "", "database username")
+	fs.StringVar(&f.password, "db-password", "", "database password")
+	fs.BoolVar(&f.insecure, "db-insecure", false, "don't use SSL in database connection")
+	fs.StringVar(&f.sqlDSN, "sql-dsn", "", "PostgreSQL connection string. if set, config is stored in PostgreSQL instead of couchdb")
+	fs.StringVar(&f.snapshot, "snapshot", "", "read the config from this snapshot, made with pc-config snapshot export, instead of the database")
+
+	fs.StringVar(&f.auth, "db-auth", "basic", "how to authenticate to the database: basic, cookie, jwt, or proxy")
+	fs.StringVar(&f.passwordFile, "db-password-file", "", "file to read the database password from, instead of db-password. it is read again when it changes")
+	fs.StringVar(&f.jwtFile, "db-jwt-file", "", "file to read the JWT to authenticate with from, when db-auth is jwt. it is read again when it changes")
+	fs.StringVar(&f.proxySecret, "db-proxy-secret", "", "the database's proxy authentication secret, when db-auth is proxy")
+	fs.StringSliceVar(&f.proxyRoles, "db-proxy-roles", nil, "roles to authenticate with, when db-auth is proxy")
+	fs.StringVar(&f.validation, "db-validation", "off", "how to handle documents that don't match their schema: off, lenient (log them and skip invalid cameras), or strict (reject them)")
+
+	fs.DurationVar(&f.timeout, "db-timeout", 5*time.Second, "how long each database request can take. 0 means no limit")
+	fs.IntVar(&f.retries, "db-retries", 2, "how many times to retry database reads that fail because the database is unavailable")
+	fs.DurationVar(&f.retryBackoff, "db-retry-backoff", 100*time.Millisecond, "how long to wait before the first retry. it doubles after each retry")
+	fs.IntVar(&f.breakerFailures, "db-breaker-failures", 5, "how many database requests in a row can fail before requests fail fast. 0 disables the circuit breaker")
+	fs.DurationVar(&f.breakerCooldown, "db-breaker-cooldown", 10*time.Second, "how long requests fail fast before the database is tried again")
+
+	fs.StringVar(&f.caFile, "db-ca-file", "", "PEM bundle of the CAs to trust for the database's certificate, instead of the system's")
+	fs.StringVar(&f.certFile, "db-cert-file", "", "client certificate to present to the database")
+	fs.StringVar(&f.keyFile, "db-key-file", "", "key of the client certificate")
+
+	fs.IntVar(&f.maxIdleConnsPerHost, "db-max-idle-conns", 0, "how many idle connections to the database to keep open. 0 uses the default")
+	fs.IntVar(&f.maxConnsPerHost, "db-max-conns", 0, "the most connections to the database to open at once. 0 means no limit")
+	fs.DurationVar(&f.idleConnTimeout, "db-idle-conn-timeout", 0, "how long idle connections to the database are kept open. 0 uses the default")
+	fs.DurationVar(&f.dialTimeout, "db-dial-timeout", 0, "how long connecting to the database can take. 0 uses the default")
+}
+
+// configService builds a config service that reads from the snapshot, the sql database, or couchdb, whichever is set first.
+func (f *dbFlags) configService(ctx context.Context) (pcconfig.ConfigService, error) {
+	switch {
+	case f.snapshot != "":
+		mode, err := couch.ParseValidationMode(f.validation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid db-validation: %w", err)
+		}
+
+		snap, err := readSnapshotFile(f.snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		opts := []couch.Option{couch.WithValidation(mode)}
+		if f.log != nil {
+			opts = append(opts, couch.WithLogger(f.log))
+		}
+
+		return couch.NewFromSnapshot(snap, opts...)
+	case f.sqlDSN != "":
+		return f.sqlService(ctx)
+	}
+
+	return f.couchService(ctx)
+}
+
+// couchService builds a config service connected to couchdb.
+func (f *dbFlags) couchService(ctx context.Context) (pcconfig.ConfigService, error) {
+	addr := "https://" + f.addr
+	if f.insecure {
+		addr = "http://" + f.addr
+	}
+
+	mode, err := couch.ParseValidationMode(f.validation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid db-validation: %w", err)
+	}
+
+	opts := []couch.Option{
+		couch.WithValidation(mode),
+		couch.WithRequestTimeout(f.timeout),
+		couch.WithRetries(f.retries, f.retryBackoff),
+		couch.WithCircuitBreaker(f.breakerFailures, f.breakerCooldown),
+		couch.WithTransportConfig(couch.TransportConfig{
+			MaxIdleConns:        f.maxIdleConnsPerHost,
+			MaxIdleConnsPerHost: f.maxIdleConnsPerHost,
+			MaxConnsPerHost:     f.maxConnsPerHost,
+			IdleConnTimeout:     f.idleConnTimeout,
+			DialTimeout:         f.dialTimeout,
+		}),
+	}
+
+	if f.caFile != "" || f.certFile != "" || f.keyFile != "" {
+		cfg, err := couch.LoadTLSConfig(f.caFile, f.certFile, f.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load db tls config: %w", err)
+		}
+
+		opts = append(opts, couch.WithTLSConfig(cfg))
+	}
+
+	auth, err := f.authOption()
+	if err != nil {
+		return nil, err
+	}
+
+	if auth != nil {
+		opts = append(opts, auth)
+	}
+
+	if f.log != nil {
+		opts = append(opts, couch.WithLogger(f.log))
+	}
+
+	return couch.New(ctx, addr, opts...)
+}
+
+// authOption returns the option to authenticate to the database with, or nil if no credentials were given.
+func (f *dbFlags) authOption() (couch.Option, error) {
+	creds := couch.StaticCredentials(f.username, f.password)
+	if f.passwordFile != "" {
+		creds = couch.FileCredentials(f.username, f.passwordFile)
+	}
+
+	hasCreds := f.username != ""
+
+	switch f.auth {
+	case "basic":
+		switch {
+		case !hasCreds:
+			return nil, nil
+		case f.passwordFile != "":
+			return couch.WithBasicAuthFrom(creds), nil
+		}
+
+		return couch.WithBasicAuth(f.username, f.password), nil
+	case "cookie":
+		if !hasCreds {
+			return nil, fmt.Errorf("db-username is required for cookie auth")
+		}
+
+		return couch.WithCookieAuth(creds), nil
+	case "jwt":
+		if f.jwtFile == "" {
+			return nil, fmt.Errorf("db-jwt-file is required for jwt auth")
+		}
+
+		return couch.WithJWTFile(f.jwtFile), nil
+	case "proxy":
+		if !hasCreds {
+			return nil, fmt.Errorf("db-username is required for proxy auth")
+		}
+
+		return couch.WithProxyAuth(f.username, f.proxySecret, f.proxyRoles), nil
+	}
+
+	return nil, fmt.Errorf("invalid db-auth %q: must be basic, cookie, jwt, or proxy", f.auth)
+}
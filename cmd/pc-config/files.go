@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/couch"
+)
+
+// fileLister is a pcconfig.ConfigLister for pc-mapping, ui-configuration, and camera template docs stored in files.
+type fileLister struct {
+	mappings []pcconfig.PCMapping
+	rooms    []pcconfig.Room
+}
+
+// newFileLister reads each of paths, which must be a pc-mapping, ui-configuration, or camera template doc.
+// The doc's id is its _id, or the file's name if it doesn't have one.
+func newFileLister(paths ...string) (*fileLister, error) {
+	var l fileLister
+	templates := make(map[string]pcconfig.Camera)
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if raw, ok := doc["_id"]; ok {
+			if err := json.Unmarshal(raw, &id); err != nil {
+				return nil, fmt.Errorf("%s: invalid _id: %w", path, err)
+			}
+		}
+
+		switch {
+		case doc["presets"] != nil:
+			room, err := couch.DecodeRoom(id, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+
+			l.rooms = append(l.rooms, room)
+		case doc["uiConfig"] != nil:
+			mapping, err := couch.DecodePCMapping(id, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+
+			l.mappings = append(l.mappings, mapping)
+		case doc["camera"] != nil:
+			tmpl, err := couch.DecodeTemplate(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+
+			templates[id] = tmpl
+		default:
+			return nil, fmt.Errorf("%s: not a pc-mapping, ui-configuration, or camera template doc", path)
+		}
+	}
+
+	for i := range l.rooms {
+		// cameras that can't be resolved are left for validation to report
+		l.rooms[i], _ = pcconfig.ResolveRoomTemplates(l.rooms[i], templates)
+	}
+
+	return &l, nil
+}
+
+func (l *fileLister) PCMappings(ctx context.Context) ([]pcconfig.PCMapping, error) {
+	return l.mappings, nil
+}
+
+func (l *fileLister) Rooms(ctx context.Context) ([]pcconfig.Room, error) {
+	return l.rooms, nil
+}
@@ -9,35 +9,62 @@ import (
 	"os"
 	"time"
 
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/cameras"
+	"github.com/byuoitav/pc-config/cameras/onvif"
+	"github.com/byuoitav/pc-config/cameras/visca"
 	"github.com/byuoitav/pc-config/couch"
 	"github.com/byuoitav/pc-config/handlers"
 	"github.com/byuoitav/pc-config/keys"
+	"github.com/byuoitav/pc-config/probe"
+	"github.com/byuoitav/pc-config/schedule"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/time/rate"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
 	var (
 		port     int
 		logLevel string
 
-		dbAddr     string
-		dbUsername string
-		dbPassword string
-		dbInsecure bool
+		db dbFlags
 
 		keyServiceAddr string
+
+		cameraProxyURL   string
+		cameraProxyRate  float64
+		cameraProxyBurst int
+		presetInterval   time.Duration
+
+		adminToken string
+
+		probeInterval        time.Duration
+		annotateCameraStatus bool
+
+		scheduleInterval time.Duration
 	)
 
 	pflag.IntVarP(&port, "port", "P", 8080, "port to run the server on")
 	pflag.StringVarP(&logLevel, "log-level", "L", "", "level to log at. refer to https://godoc.org/go.uber.org/zap/zapcore#Level for options")
-	pflag.StringVar(&dbAddr, "db-address", "", "database address")
-	pflag.StringVar(&dbUsername, "db-username", "", "database username")
-	pflag.StringVar(&dbPassword, "db-password", "", "database password")
-	pflag.BoolVar(&dbInsecure, "db-insecure", false, "don't use SSL in database connection")
+	db.register(pflag.CommandLine)
 	pflag.StringVar(&keyServiceAddr, "key-service", "control-keys.av.byu.edu", "address of the control keys service")
+	pflag.StringVar(&cameraProxyURL, "camera-proxy", "", "if set, camera urls sent to PCs are rewritten to go through this pc-config address")
+	pflag.Float64Var(&cameraProxyRate, "camera-proxy-rate", 5, "max PTZ commands per second to a single camera through the camera proxy. 0 disables the limit")
+	pflag.IntVar(&cameraProxyBurst, "camera-proxy-burst", 10, "max burst of PTZ commands to a single camera through the camera proxy")
+	pflag.DurationVar(&presetInterval, "preset-discovery-interval", 10*time.Minute, "how often the camera proxy lists the presets stored on cameras that don't have any configured. 0 disables discovery")
+	pflag.StringVar(&adminToken, "admin-token", "", "bearer token required for admin endpoints. admin endpoints are disabled if it is empty")
+	pflag.DurationVar(&probeInterval, "probe-interval", 0, "how often to check that every camera can be reached. 0 disables probing")
+	pflag.BoolVar(&annotateCameraStatus, "annotate-camera-status", false, "add each camera's status from the prober to the config sent to PCs")
+	pflag.DurationVar(&scheduleInterval, "schedule-interval", time.Minute, "how often to apply change sets that have taken effect and reload the change sets served to PCs. 0 disables applying them, and change sets are read for every config")
 	pflag.Parse()
 
 	var level zapcore.Level
@@ -84,29 +111,95 @@ func main() {
 	defer cancel()
 
 	// build the config service
-	if dbInsecure {
-		dbAddr = "http://" + dbAddr
-	} else {
-		dbAddr = "https://" + dbAddr
+	db.log = log
+	cs, err := db.configService(ctx)
+	if err != nil {
+		log.Fatal("unable to create config service", zap.Error(err))
 	}
 
-	var csOpts []couch.Option
-	if dbUsername != "" {
-		csOpts = append(csOpts, couch.WithBasicAuth(dbUsername, dbPassword))
+	var proxy *handlers.CameraProxy
+	if cameraProxyURL != "" {
+		proxy = &handlers.CameraProxy{
+			BaseURL: cameraProxyURL,
+			Client: &http.Client{
+				Timeout: 5 * time.Second,
+			},
+			Drivers: map[string]cameras.NewDriverFunc{
+				"visca": visca.New,
+				"onvif": onvif.New,
+			},
+			Limit: rate.Limit(cameraProxyRate),
+			Burst: cameraProxyBurst,
+		}
 	}
 
-	cs, err := couch.New(ctx, dbAddr, csOpts...)
-	if err != nil {
-		log.Fatal("unable to create config service", zap.Error(err))
+	if lister, ok := cs.(pcconfig.ConfigLister); ok && proxy != nil && presetInterval > 0 {
+		go proxy.RunDiscovery(context.Background(), lister, presetInterval) // runs until the server exits
 	}
 
+	var prober *probe.Prober
+	if probeInterval > 0 {
+		lister, ok := cs.(pcconfig.ConfigLister)
+		if !ok {
+			log.Fatal("camera probing is not supported by this datastore")
+		}
+
+		prober = &probe.Prober{
+			Lister:   lister,
+			Client:   &http.Client{Timeout: 5 * time.Second},
+			Interval: probeInterval,
+		}
+
+		go prober.Run(context.Background()) // runs until the server exits
+	}
+
+	var applier *schedule.Applier
+	if ss, ok := cs.(pcconfig.ScheduleService); ok && scheduleInterval > 0 {
+		applier = &schedule.Applier{
+			Service:  ss,
+			Interval: scheduleInterval,
+		}
+
+		go applier.Run(context.Background()) // runs until the server exits
+	}
+
+	requireAdmin := handlers.RequireToken(adminToken)
+
 	handlers := handlers.Handlers{
 		ConfigService: cs,
 		ControlKeyService: &keys.ControlKeyService{
 			Address: keyServiceAddr,
 		},
+		CameraProxy:          proxy,
+		CameraStatus:         prober,
+		AnnotateCameraStatus: annotateCameraStatus,
+		Schedule:             applier,
+		DocumentSchemas:      couch.Schemas(),
+	}
+
+	if thumbnails, ok := cs.(pcconfig.ThumbnailStore); ok {
+		handlers.Thumbnails = thumbnails
+	}
+
+	r := newRouter(&handlers, requireAdmin, adminToken != "", prober != nil)
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		log.Fatal("unable to bind listener", zap.Error(err))
+	}
+
+	log.Info("Starting server", zap.String("on", lis.Addr().String()))
+	err = r.RunListener(lis)
+	switch {
+	case errors.Is(err, http.ErrServerClosed):
+	case err != nil:
+		log.Fatal("failed to serve", zap.Error(err))
 	}
+}
 
+// newRouter registers every route pc-config serves. Admin routes are only registered if admin is true,
+// and the metrics route if metrics is true. Routes added here also have to be added to handlers.Spec.
+func newRouter(h *handlers.Handlers, requireAdmin gin.HandlerFunc, admin, metrics bool) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
@@ -118,18 +211,55 @@ func main() {
 			c.String(http.StatusNotFound, "404 page not found")
 		}
 	})
-	r.GET("/:hostname/config", handlers.ConfigForPC)
+	r.GET("/:hostname/config", h.ConfigForPC)
+	r.GET("/v1/pcs/:hostname/config", h.ConfigForPCV1)
+	r.GET("/v2/pcs/:hostname/config", h.ConfigForPCV2)
 
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		log.Fatal("unable to bind listener", zap.Error(err))
+	for _, prefix := range handlers.PCPrefixes {
+		pc := r.Group(prefix)
+		pc.POST("/checkin", h.CheckIn)
+		pc.GET("/cameras/:camera/:action", h.ControlCamera)
+		pc.PUT("/cameras/:camera/presets/:slot", h.SaveCameraPreset)
+		pc.GET("/cameras/:camera/presets/:slot/thumbnail", h.PresetThumbnail)
+		pc.POST("/cameras/:camera/presets/:slot/thumbnail", h.CaptureThumbnail)
 	}
 
-	log.Info("Starting server", zap.String("on", lis.Addr().String()))
-	err = r.RunListener(lis)
-	switch {
-	case errors.Is(err, http.ErrServerClosed):
-	case err != nil:
-		log.Fatal("failed to serve", zap.Error(err))
+	r.POST("/configs", h.BulkConfigs)
+	r.GET("/rooms/:room/config", h.RoomConfig)
+	r.GET("/rooms/:room/controlgroups/:controlGroup/config", h.ControlGroupConfig)
+
+	r.GET("/openapi.json", h.OpenAPI)
+	r.GET("/schemas", h.Schemas)
+	r.GET("/schemas/:name", h.Schema)
+
+	if metrics {
+		r.GET("/metrics", h.CameraMetrics)
 	}
+
+	if admin {
+		admin := r.Group("/admin", requireAdmin)
+		admin.PUT("/rooms/:room/controlgroups/:controlGroup/cameras/:camera/presets/:slot/thumbnail", h.UploadThumbnail)
+		admin.GET("/validate", h.Validate)
+		admin.GET("/report", h.Consistency)
+		admin.GET("/cameras/status", h.CameraStatuses)
+		admin.GET("/rooms/:room/history", h.RoomHistory)
+		admin.GET("/rooms/:room/diff", h.RoomDiff)
+		admin.POST("/rooms/:room/rollback", h.RollbackRoom)
+		admin.GET("/mappings/:hostname/history", h.MappingHistory)
+		admin.GET("/mappings/:hostname/diff", h.MappingDiff)
+		admin.POST("/mappings/:hostname/rollback", h.RollbackMapping)
+		admin.GET("/changesets", h.ChangeSets)
+		admin.PUT("/changesets/:id", h.PutChangeSet)
+		admin.DELETE("/changesets/:id", h.DeleteChangeSet)
+		admin.POST("/changesets/:id/apply", h.ApplyChangeSet)
+		admin.GET("/changesets/:id/rollout", h.Rollout)
+		admin.POST("/changesets/:id/promote", h.PromoteRollout)
+		admin.POST("/changesets/:id/abort", h.AbortRollout)
+		admin.GET("/checkins", h.CheckIns)
+		admin.POST("/configs", h.BulkConfigs)
+		admin.GET("/rooms/:room/config", h.RoomConfig)
+		admin.GET("/rooms/:room/controlgroups/:controlGroup/config", h.ControlGroupConfig)
+	}
+
+	return r
 }
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/byuoitav/pc-config/handlers"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSpecRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(&handlers.Handlers{}, handlers.RequireToken("token"), true, true)
+
+	var routes []string
+	for _, route := range r.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+
+	sort.Strings(routes)
+
+	if diff := cmp.Diff(handlers.Spec().Routes(), routes); diff != "" {
+		t.Errorf("handlers.Spec is out of date with the router (-spec, +router):\n%s", diff)
+	}
+}
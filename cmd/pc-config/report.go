@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/byuoitav/pc-config/validate"
+	"github.com/spf13/pflag"
+)
+
+// reportCmd prints a consistency report of the pc mappings and room configurations.
+func reportCmd(args []string) int {
+	var (
+		db      dbFlags
+		jsonOut bool
+	)
+
+	fs := pflag.NewFlagSet("report", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pc-config report [flags] [pc-mapping/ui-configuration/camera template docs...]\n\n")
+		fmt.Fprintf(os.Stderr, "compares the pc mappings and ui configurations in the docs given, or in the database if none are given.\n\n")
+		fs.PrintDefaults()
+	}
+
+	db.register(fs)
+	fs.BoolVar(&jsonOut, "json", false, "print the report as json")
+	_ = fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	lister, err := newLister(ctx, &db, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	report, err := validate.Consistency(ctx, lister)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to build report: %s\n", err)
+		return 2
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		_ = enc.Encode(report)
+	} else {
+		for _, m := range report.MissingRooms {
+			fmt.Printf("%s: mapped to room %q, which doesn't exist\n", m.Hostname, m.Room)
+		}
+
+		for _, m := range report.MissingControlGroups {
+			fmt.Printf("%s: mapped to control group %q, which isn't in %s\n", m.Hostname, m.ControlGroup, m.Room)
+		}
+
+		for _, cg := range report.UnmappedControlGroups {
+			fmt.Printf("%s %s: has %d cameras, but no PCs are mapped to it\n", cg.Room, cg.ControlGroup, cg.Cameras)
+		}
+
+		for _, a := range report.AmbiguousMappings {
+			if a.Other != nil {
+				fmt.Printf("%s: %s (%s)\n", a.Mapping.Hostname, a.Reason, a.Other.Hostname)
+			} else {
+				fmt.Printf("%s: %s\n", a.Mapping.Hostname, a.Reason)
+			}
+		}
+	}
+
+	if !report.Consistent() {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/byuoitav/pc-config/couch"
+	"github.com/spf13/pflag"
+)
+
+// snapshotter is a datastore that can copy its config into a snapshot.
+type snapshotter interface {
+	Snapshot(ctx context.Context) (*couch.Snapshot, error)
+}
+
+// snapshotCmd runs the snapshot subcommands.
+func snapshotCmd(args []string) int {
+	if len(args) > 0 && args[0] == "export" {
+		return snapshotExportCmd(args[1:])
+	}
+
+	fmt.Fprintf(os.Stderr, "usage: pc-config snapshot export [flags] [file]\n\n")
+	fmt.Fprintf(os.Stderr, "the snapshot can be served with pc-config --snapshot <file>.\n")
+	return 2
+}
+
+// snapshotExportCmd writes a snapshot of the config in the database to a file, or to stdout.
+func snapshotExportCmd(args []string) int {
+	var db dbFlags
+
+	fs := pflag.NewFlagSet("snapshot export", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pc-config snapshot export [flags] [file]\n\n")
+		fmt.Fprintf(os.Stderr, "copies the pc mappings, ui configurations, and camera templates in couchdb to file, or to stdout if it isn't given.\n\n")
+		fs.PrintDefaults()
+	}
+
+	db.register(fs)
+	_ = fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	// the flags are shared with the other commands, but only couchdb can be exported
+	if db.snapshot != "" || db.sqlDSN != "" {
+		fmt.Fprintf(os.Stderr, "snapshots can only be exported from couchdb, so --snapshot and --sql-dsn can't be used\n")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cs, err := db.configService(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create config service: %s\n", err)
+		return 2
+	}
+
+	s, ok := cs.(snapshotter)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "database doesn't support snapshots\n")
+		return 2
+	}
+
+	snap, err := s.Snapshot(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to take snapshot: %s\n", err)
+		return 1
+	}
+
+	if fs.NArg() == 0 {
+		if err := couch.WriteSnapshot(os.Stdout, snap); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+	} else if err := writeSnapshotFile(fs.Arg(0), snap); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d pc mappings, %d ui configurations, and %d camera templates\n", len(snap.PCMappings), len(snap.UIConfigs), len(snap.Templates))
+	return 0
+}
+
+// writeSnapshotFile writes snap to path. It is written to a temporary file first, so
+// that path is never left with half of a snapshot.
+func writeSnapshotFile(path string, snap *couch.Snapshot) error {
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot file: %w", err)
+	}
+
+	if err := couch.WriteSnapshot(f, snap); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("unable to write snapshot file: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("unable to write snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// readSnapshotFile reads the snapshot at path.
+func readSnapshotFile(path string) (*couch.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	return couch.ReadSnapshot(f)
+}
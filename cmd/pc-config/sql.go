@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/couch"
+	"github.com/byuoitav/pc-config/sqlstore"
+	_ "github.com/lib/pq"
+	"github.com/spf13/pflag"
+)
+
+// importer is a datastore that config can be copied into.
+type importer interface {
+	Import(ctx context.Context, mappings []pcconfig.PCMapping, rooms []pcconfig.Room, templates map[string]pcconfig.Camera) error
+}
+
+// sqlService builds a config service connected to the sql database.
+func (f *dbFlags) sqlService(ctx context.Context) (pcconfig.ConfigService, error) {
+	db, err := f.openSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlstore.New(ctx, db)
+}
+
+func (f *dbFlags) openSQL() (*sql.DB, error) {
+	db, err := sql.Open("postgres", f.sqlDSN)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sql database: %w", err)
+	}
+
+	return db, nil
+}
+
+// sqlCmd runs the sql subcommands.
+func sqlCmd(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			return sqlMigrateCmd(args[1:])
+		case "import":
+			return sqlImportCmd(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "usage: pc-config sql migrate|import [flags]\n\n")
+	fmt.Fprintf(os.Stderr, "migrate updates the schema of the database in --sql-dsn. it must be run before pc-config can use the database.\n")
+	fmt.Fprintf(os.Stderr, "import copies the config in couchdb (or in --snapshot) to the database in --sql-dsn.\n")
+	return 2
+}
+
+// sqlMigrateCmd applies the migrations the sql database doesn't have yet.
+func sqlMigrateCmd(args []string) int {
+	var db dbFlags
+
+	fs := pflag.NewFlagSet("sql migrate", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pc-config sql migrate --sql-dsn <dsn>\n\n")
+		fmt.Fprintf(os.Stderr, "updates the schema of the database to the latest version. pc-config doesn't update it when it starts,\n")
+		fmt.Fprintf(os.Stderr, "so run this (once) before starting a new version of pc-config.\n\n")
+		fs.PrintDefaults()
+	}
+
+	db.register(fs)
+	_ = fs.Parse(args)
+
+	if db.sqlDSN == "" {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	sqlDB, err := db.openSQL()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	defer sqlDB.Close()
+
+	version, err := sqlstore.Migrate(ctx, sqlDB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to migrate: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("database is at schema version %d\n", version)
+	return 0
+}
+
+// sqlImportCmd copies the pc mappings, ui configurations, and camera templates in couchdb to the sql database.
+func sqlImportCmd(args []string) int {
+	var db dbFlags
+
+	fs := pflag.NewFlagSet("sql import", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pc-config sql import --sql-dsn <dsn> [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "copies the pc mappings, ui configurations, and camera templates in couchdb, or in --snapshot, to the sql database.\n")
+		fmt.Fprintf(os.Stderr, "existing config with the same ids is replaced, and it can be run again to copy new changes.\n\n")
+		fs.PrintDefaults()
+	}
+
+	db.register(fs)
+	_ = fs.Parse(args)
+
+	if db.sqlDSN == "" {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	snap, err := db.couchSnapshot(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	mappings, rooms, templates, err := decodeSnapshot(snap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	cs, err := db.sqlService(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create sql config service: %s\n", err)
+		return 2
+	}
+
+	i, ok := cs.(importer)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "sql database doesn't support importing\n")
+		return 2
+	}
+
+	if err := i.Import(ctx, mappings, rooms, templates); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to import: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("imported %d pc mappings, %d rooms, and %d camera templates\n", len(mappings), len(rooms), len(templates))
+	return 0
+}
+
+// couchSnapshot reads the snapshot, or takes one of couchdb if there isn't one.
+func (f *dbFlags) couchSnapshot(ctx context.Context) (*couch.Snapshot, error) {
+	if f.snapshot != "" {
+		return readSnapshotFile(f.snapshot)
+	}
+
+	cs, err := f.couchService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create couch config service: %w", err)
+	}
+
+	s, ok := cs.(snapshotter)
+	if !ok {
+		return nil, fmt.Errorf("couchdb doesn't support snapshots")
+	}
+
+	snap, err := s.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read couchdb: %w", err)
+	}
+
+	return snap, nil
+}
+
+// decodeSnapshot decodes every doc in snap. Templates aren't resolved, so that rooms keep using them.
+func decodeSnapshot(snap *couch.Snapshot) ([]pcconfig.PCMapping, []pcconfig.Room, map[string]pcconfig.Camera, error) {
+	var mappings []pcconfig.PCMapping
+	for id, raw := range snap.PCMappings {
+		mapping, err := couch.DecodePCMapping(id, raw)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("pc mapping %q: %w", id, err)
+		}
+
+		mappings = append(mappings, mapping)
+	}
+
+	var rooms []pcconfig.Room
+	for id, raw := range snap.UIConfigs {
+		room, err := couch.DecodeRoom(id, raw)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("ui config %q: %w", id, err)
+		}
+
+		rooms = append(rooms, room)
+	}
+
+	templates := make(map[string]pcconfig.Camera, len(snap.Templates))
+	for id, raw := range snap.Templates {
+		tmpl, err := couch.DecodeTemplate(raw)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("camera template %q: %w", id, err)
+		}
+
+		templates[id] = tmpl
+	}
+
+	return mappings, rooms, templates, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/validate"
+	"github.com/spf13/pflag"
+)
+
+// validateCmd validates the configuration in the database, or in the given files.
+func validateCmd(args []string) int {
+	var (
+		db      dbFlags
+		jsonOut bool
+	)
+
+	fs := pflag.NewFlagSet("validate", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: pc-config validate [flags] [pc-mapping/ui-configuration/camera template docs...]\n\n")
+		fmt.Fprintf(os.Stderr, "validates the docs given, or every doc in the database if none are given.\n\n")
+		fs.PrintDefaults()
+	}
+
+	db.register(fs)
+	fs.BoolVar(&jsonOut, "json", false, "print the report as json")
+	_ = fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	lister, err := newLister(ctx, &db, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	report, err := validate.All(ctx, lister)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to validate: %s\n", err)
+		return 2
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		_ = enc.Encode(report)
+	} else {
+		for _, p := range report.Problems {
+			fmt.Println(p)
+		}
+
+		fmt.Printf("checked %d rooms and %d pc mappings: %d errors, %d warnings\n", report.Rooms, report.PCMappings, report.Errors, report.Warnings)
+	}
+
+	if !report.Valid() {
+		return 1
+	}
+
+	return 0
+}
+
+// newLister returns a lister for the given files, or for the database if there aren't any.
+func newLister(ctx context.Context, db *dbFlags, files []string) (pcconfig.ConfigLister, error) {
+	if len(files) > 0 {
+		l, err := newFileLister(files...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read docs: %w", err)
+		}
+
+		return l, nil
+	}
+
+	cs, err := db.configService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create config service: %w", err)
+	}
+
+	lister, ok := cs.(pcconfig.ConfigLister)
+	if !ok {
+		return nil, fmt.Errorf("database doesn't support listing configuration")
+	}
+
+	return lister, nil
+}
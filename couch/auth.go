@@ -0,0 +1,271 @@
+package couch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// _sessionCookie is the name of the cookie CouchDB sessions are stored in.
+	_sessionCookie = "AuthSession"
+
+	// _sessionRenewal is how long a session is used before a new one is started. CouchDB's
+	// default session timeout is 10 minutes.
+	_sessionRenewal = 5 * time.Minute
+)
+
+// CredentialSource provides the credentials to authenticate to CouchDB with. It is asked for
+// them before each request that needs them, so rotated credentials are used without a restart.
+type CredentialSource interface {
+	Credentials() (username, password string, err error)
+}
+
+type staticCredentials struct {
+	username, password string
+}
+
+// StaticCredentials returns a CredentialSource that always returns username and password.
+func StaticCredentials(username, password string) CredentialSource {
+	return staticCredentials{username: username, password: password}
+}
+
+func (c staticCredentials) Credentials() (string, string, error) {
+	return c.username, c.password, nil
+}
+
+type fileCredentials struct {
+	username string
+	password *watchedFile
+}
+
+// FileCredentials returns a CredentialSource for username, with the password read from passwordFile.
+// The file is read again whenever it changes, like when a mounted secret is rotated.
+func FileCredentials(username, passwordFile string) CredentialSource {
+	return &fileCredentials{username: username, password: &watchedFile{path: passwordFile}}
+}
+
+func (c *fileCredentials) Credentials() (string, string, error) {
+	password, err := c.password.read()
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read password: %w", err)
+	}
+
+	return c.username, password, nil
+}
+
+// watchedFile is a file whose contents are cached until it is modified.
+type watchedFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+// read returns the contents of the file, without surrounding whitespace.
+func (f *watchedFile) read() (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.value != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.value, nil
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", f.path)
+	}
+
+	f.value, f.modTime, f.size = value, info.ModTime(), info.Size()
+	return f.value, nil
+}
+
+// basicAuthTransport adds basic auth with the current credentials to each request.
+type basicAuthTransport struct {
+	base  http.RoundTripper
+	creds CredentialSource
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	username, password, err := t.creds.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get credentials: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(username, password)
+	return t.base.RoundTrip(req)
+}
+
+// bearerTransport adds the token in a file as a bearer token to each request.
+type bearerTransport struct {
+	base  http.RoundTripper
+	token *watchedFile
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.token.read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// sessionTransport authenticates with a CouchDB session cookie. A new session is started when the
+// current one is old, when the credentials change, or when CouchDB rejects it.
+type sessionTransport struct {
+	base       http.RoundTripper
+	creds      CredentialSource
+	sessionURL string
+	now        func() time.Time
+
+	mu       sync.Mutex
+	cookie   *http.Cookie
+	started  time.Time
+	username string
+	password string
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cookie, err := t.session(req.Context(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.send(req, cookie)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// the session expired or was revoked, so try once more with a new one if the request can be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if cookie, err = t.session(req.Context(), true); err != nil {
+		return nil, err
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("unable to reset request body: %w", err)
+		}
+
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+
+	return t.send(req, cookie)
+}
+
+// send sends req with the session cookie, and keeps the refreshed cookie CouchDB sends back.
+func (t *sessionTransport) send(req *http.Request, cookie *http.Cookie) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.AddCookie(cookie)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range resp.Cookies() {
+		if c.Name == _sessionCookie && c.Value != "" {
+			t.mu.Lock()
+			t.cookie = c
+			t.mu.Unlock()
+		}
+	}
+
+	return resp, nil
+}
+
+// session returns the session cookie, starting a new session if needed or if renew is true.
+func (t *sessionTransport) session(ctx context.Context, renew bool) (*http.Cookie, error) {
+	username, password, err := t.creds.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get credentials: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case renew, t.cookie == nil:
+	case username != t.username || password != t.password:
+	case t.now().Sub(t.started) >= _sessionRenewal:
+	default:
+		return t.cookie, nil
+	}
+
+	cookie, err := t.login(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	t.cookie, t.started = cookie, t.now()
+	t.username, t.password = username, password
+	return cookie, nil
+}
+
+func (t *sessionTransport) login(ctx context.Context, username, password string) (*http.Cookie, error) {
+	body, err := json.Marshal(map[string]string{"name": username, "password": password})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode credentials: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.sessionURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to build session request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to start session: got status %d", resp.StatusCode)
+	}
+
+	for _, c := range resp.Cookies() {
+		if c.Name == _sessionCookie && c.Value != "" {
+			return c, nil
+		}
+	}
+
+	return nil, errors.New("unable to start session: no session cookie in response")
+}
@@ -0,0 +1,287 @@
+package couch
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// authCouch serves mockUIConfigDoc to requests that authorized returns true for.
+func authCouch(t *testing.T, authorized func(r *http.Request) bool) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		serveUIConfig(w)
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("unable to write %s: %s", path, err)
+	}
+}
+
+func TestBasicAuthFrom(t *testing.T) {
+	var mu sync.Mutex
+	password := "hunter2"
+
+	srv := authCouch(t, func(r *http.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		u, p, ok := r.BasicAuth()
+		return ok && u == "admin" && p == password
+	})
+
+	dir, err := ioutil.TempDir("", "pc-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	passwordFile := filepath.Join(dir, "password")
+	writeFile(t, passwordFile, "hunter2\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := New(ctx, srv.URL, WithBasicAuthFrom(FileCredentials("admin", passwordFile)))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	// the password is rotated in couch, and then the secret is updated
+	mu.Lock()
+	password = "correct horse battery staple"
+	mu.Unlock()
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err == nil {
+		t.Fatalf("expected the old password to be rejected")
+	}
+
+	writeFile(t, passwordFile, "correct horse battery staple")
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err != nil {
+		t.Fatalf("unable to get cameras with the new password: %s", err)
+	}
+}
+
+func TestCookieAuth(t *testing.T) {
+	var mu sync.Mutex
+	password := "hunter2"
+	sessions := make(map[string]bool)
+	logins := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/_session" {
+			var creds struct {
+				Name     string `json:"name"`
+				Password string `json:"password"`
+			}
+
+			if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			if creds.Name != "admin" || creds.Password != password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			logins++
+			session := fmt.Sprintf("session-%d", logins)
+			sessions[session] = true
+
+			http.SetCookie(w, &http.Cookie{Name: _sessionCookie, Value: session})
+			_, _ = w.Write([]byte(`{"ok": true}`))
+			return
+		}
+
+		cookie, err := r.Cookie(_sessionCookie)
+		if err != nil || !sessions[cookie.Value] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		serveUIConfig(w)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "pc-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	passwordFile := filepath.Join(dir, "password")
+	writeFile(t, passwordFile, "hunter2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := New(ctx, srv.URL, WithCookieAuth(FileCredentials("admin", passwordFile)))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	get := func(expectedLogins int) {
+		t.Helper()
+
+		if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err != nil {
+			t.Fatalf("unable to get cameras: %s", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if logins != expectedLogins {
+			t.Fatalf("expected %d logins, got %d", expectedLogins, logins)
+		}
+	}
+
+	// the session is reused
+	get(1)
+	get(1)
+
+	// the session expires
+	mu.Lock()
+	sessions = make(map[string]bool)
+	mu.Unlock()
+	get(2)
+
+	// the password is rotated
+	mu.Lock()
+	password = "correct horse battery staple"
+	mu.Unlock()
+	writeFile(t, passwordFile, "correct horse battery staple")
+	get(3)
+
+	// the session gets old
+	now := time.Now()
+	rt := &sessionTransport{
+		base:       http.DefaultTransport,
+		creds:      StaticCredentials("admin", "correct horse battery staple"),
+		sessionURL: srv.URL + "/_session",
+		now:        func() time.Time { return now },
+	}
+
+	client := &http.Client{Transport: rt}
+	for i, expectedLogins := range []int{4, 4, 5} {
+		if i == 2 {
+			now = now.Add(_sessionRenewal)
+		}
+
+		resp, err := client.Get(srv.URL + "/ui-configuration/ITB-1101")
+		if err != nil {
+			t.Fatalf("unable to send request: %s", err)
+		}
+
+		resp.Body.Close()
+
+		mu.Lock()
+		got := logins
+		mu.Unlock()
+
+		switch {
+		case resp.StatusCode != http.StatusOK:
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		case got != expectedLogins:
+			t.Fatalf("expected %d logins, got %d", expectedLogins, got)
+		}
+	}
+}
+
+func TestJWTFile(t *testing.T) {
+	var mu sync.Mutex
+	token := "token-1"
+
+	srv := authCouch(t, func(r *http.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return r.Header.Get("Authorization") == "Bearer "+token
+	})
+
+	dir, err := ioutil.TempDir("", "pc-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	writeFile(t, tokenFile, "token-1\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := New(ctx, srv.URL, WithJWTFile(tokenFile))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	mu.Lock()
+	token = "token-refreshed"
+	mu.Unlock()
+	writeFile(t, tokenFile, "token-refreshed\n")
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err != nil {
+		t.Fatalf("unable to get cameras with the refreshed token: %s", err)
+	}
+}
+
+func TestProxyAuth(t *testing.T) {
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write([]byte("pc-config"))
+	expectedToken := hex.EncodeToString(mac.Sum(nil))
+
+	srv := authCouch(t, func(r *http.Request) bool {
+		return r.Header.Get("X-Auth-CouchDB-UserName") == "pc-config" &&
+			r.Header.Get("X-Auth-CouchDB-Roles") == "reader,writer" &&
+			r.Header.Get("X-Auth-CouchDB-Token") == expectedToken
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := New(ctx, srv.URL, WithProxyAuth("pc-config", "secret", []string{"reader", "writer"}))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+}
@@ -0,0 +1,147 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+// getDocs gets every doc in ids from db with a single request. Docs that don't exist aren't returned.
+func (c *configService) getDocs(ctx context.Context, db string, ids []string) (map[string]json.RawMessage, error) {
+	rows, err := c.client.DB(ctx, db).AllDocs(ctx, kivik.Options{"keys": ids, "include_docs": true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get docs: %w", err)
+	}
+	defer rows.Close()
+
+	docs := make(map[string]json.RawMessage, len(ids))
+	for rows.Next() {
+		// missing docs don't have an id, and deleted docs don't have a doc
+		if rows.ID() == "" {
+			continue
+		}
+
+		var doc json.RawMessage
+		err := rows.ScanDoc(&doc)
+		switch {
+		case kivik.StatusCode(err) == http.StatusNotFound:
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("unable to scan %q: %w", rows.ID(), err)
+		case len(doc) == 0 || string(doc) == "null":
+			continue
+		}
+
+		docs[rows.ID()] = append(json.RawMessage(nil), doc...)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to iterate docs: %w", err)
+	}
+
+	return docs, nil
+}
+
+// PCMappingsFor gets the mappings of every hostname with a single request.
+func (c *configService) PCMappingsFor(ctx context.Context, hostnames []string) (map[string]pcconfig.PCMapping, error) {
+	var ids []string
+	seen := make(map[string]bool)
+
+	for _, hostname := range hostnames {
+		for _, id := range pcconfig.MappingCandidates(hostname) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	docs, err := c.getDocs(ctx, c.pcMappingDB, ids)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pc mappings: %w", err)
+	}
+
+	mappings := make(map[string]pcconfig.PCMapping)
+	for _, hostname := range hostnames {
+		for _, id := range pcconfig.MappingCandidates(hostname) {
+			raw, ok := docs[id]
+			if !ok {
+				continue
+			}
+
+			// invalid mappings are left out, like rooms are
+			if mapping, err := c.decodePCMapping(id, raw); err == nil {
+				mappings[hostname] = mapping.toPCMapping(id)
+			}
+
+			break
+		}
+	}
+
+	return mappings, nil
+}
+
+// RoomsFor gets the ui config of every room, and then every template they use, with a single request each.
+// Rooms that are invalid or use templates that can't be resolved are left out, like ones that don't exist.
+func (c *configService) RoomsFor(ctx context.Context, rooms []string) (map[string]pcconfig.Room, error) {
+	docs, err := c.getDocs(ctx, c.uiConfigDB, rooms)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ui configs: %w", err)
+	}
+
+	found := make(map[string]pcconfig.Room, len(docs))
+	var names []string
+	seen := make(map[string]bool)
+
+	for id, raw := range docs {
+		config, err := c.decodeUIConfig(id, raw)
+		if err != nil {
+			continue
+		}
+
+		room := config.toRoom(id)
+		for _, cg := range room.ControlGroups {
+			for _, cam := range cg.Cameras {
+				if cam.Template != "" && !seen[cam.Template] {
+					seen[cam.Template] = true
+					names = append(names, cam.Template)
+				}
+			}
+		}
+
+		found[id] = room
+	}
+
+	if len(names) == 0 {
+		return found, nil
+	}
+
+	sort.Strings(names)
+
+	templateDocs, err := c.getDocs(ctx, c.templateDB, names)
+	if err != nil && kivik.StatusCode(err) != http.StatusNotFound {
+		return nil, fmt.Errorf("unable to get templates: %w", err)
+	}
+
+	templates := make(map[string]pcconfig.Camera, len(templateDocs))
+	for name, raw := range templateDocs {
+		if tmpl, err := DecodeTemplate(raw); err == nil {
+			templates[name] = tmpl
+		}
+	}
+
+	for id, room := range found {
+		if resolved, err := resolveRoom(room, templates); err == nil {
+			found[id] = resolved
+		} else {
+			delete(found, id)
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,332 @@
+package couch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPCMappingsFor(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	hostnames := []string{"ITB-1101-CP1", "ITB-1101-CP2", "JFSB-CP1"}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, hostname := range hostnames {
+		for _, id := range pcconfig.MappingCandidates(hostname) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
+	db.ExpectAllDocs().WithOptions(map[string]interface{}{"keys": ids, "include_docs": true}).WillReturn(mappingRows(ids, map[string]string{
+		"ITB-1101-CP1": `{"uiConfig": "ITB-1101", "controlGroup": "Group 1"}`,
+		"ITB-1101-CP":  `{"uiConfig": "ITB-1101", "controlGroup": "Group 2", "overrides": {"cameras": [{"camera": "Front", "hide": true}]}}`,
+		"ITB":          `{"uiConfig": "ITB-1102", "controlGroup": "Group 1"}`,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	mappings, err := cs.(pcconfig.BatchService).PCMappingsFor(ctx, hostnames)
+	if err != nil {
+		t.Fatalf("unable to get pc mappings: %s", err)
+	}
+
+	expected := map[string]pcconfig.PCMapping{
+		"ITB-1101-CP1": {Hostname: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1"},
+		"ITB-1101-CP2": {
+			Hostname:     "ITB-1101-CP",
+			Room:         "ITB-1101",
+			ControlGroup: "Group 2",
+			Overrides: &pcconfig.PCOverrides{
+				Cameras: []pcconfig.CameraOverride{{Camera: "Front", Hide: true}},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, mappings); diff != "" {
+		t.Fatalf("got wrong mappings (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRoomsFor(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	rooms := []string{"ITB-1101", "ITB-1102", "ITB-1103"}
+
+	uiDB := mock.NewDB()
+	templateDB := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(uiDB)
+	uiDB.ExpectAllDocs().WithOptions(map[string]interface{}{"keys": rooms, "include_docs": true}).WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ITB-1101", Key: []byte(`"ITB-1101"`), Doc: []byte(`{
+			"presets": [{"name": "Group 1", "cameras": [{"displayName": "Front", "template": "ptz", "address": "10.0.0.5"}]}]
+		}`)}).
+		AddRow(&driver.Row{ID: "ITB-1102", Key: []byte(`"ITB-1102"`), Doc: []byte(`{
+			"presets": [{"name": "Group 1", "cameras": [{"displayName": "Front", "template": "missing"}]}]
+		}`)}).
+		AddRow(&driver.Row{Key: []byte(`"ITB-1103"`)}))
+	mock.ExpectDB().WithName(_defaultTemplateDB).WillReturn(templateDB)
+	templateDB.ExpectAllDocs().WithOptions(map[string]interface{}{"keys": []string{"missing", "ptz"}, "include_docs": true}).WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{Key: []byte(`"missing"`)}).
+		AddRow(&driver.Row{ID: "ptz", Key: []byte(`"ptz"`), Doc: []byte(mockTemplateDoc)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	found, err := cs.(pcconfig.BatchService).RoomsFor(ctx, rooms)
+	if err != nil {
+		t.Fatalf("unable to get rooms: %s", err)
+	}
+
+	// ITB-1102 can't be built, and ITB-1103 doesn't exist
+	expected := map[string]pcconfig.Room{
+		"ITB-1101": {
+			ID: "ITB-1101",
+			ControlGroups: []pcconfig.ControlGroup{
+				{
+					Name: "Group 1",
+					Cameras: []pcconfig.Camera{
+						{
+							DisplayName: "Front",
+							PanTiltStop: "http://control/ITB-1101/10.0.0.5/stop",
+							Stream:      "rtsp://10.0.0.5/main",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, found); diff != "" {
+		t.Fatalf("got wrong rooms (-want, +got):\n%s", diff)
+	}
+}
+
+// getMapping looks up hostname's mapping with a request for each id it could be, which is what
+// RoomAndControlGroup used to do. It is the baseline for the benchmarks.
+func getMapping(ctx context.Context, c *configService, hostname string) (pcMapping, error) {
+	for _, id := range pcconfig.MappingCandidates(hostname) {
+		var mapping pcMapping
+		err := c.client.DB(ctx, c.pcMappingDB).Get(ctx, id).ScanDoc(&mapping)
+		if kivik.StatusCode(err) == http.StatusNotFound {
+			continue
+		}
+
+		return mapping, err
+	}
+
+	return pcMapping{}, &kivik.Error{HTTPStatus: http.StatusNotFound}
+}
+
+// benchmarkLookups benchmarks lookup. expect is called with a new mock before each iteration
+// to set up the requests lookup makes, and returns how many round trips they are.
+func benchmarkLookups(b *testing.B, expect func(*kivikmock.Client) int, lookup func(context.Context, *configService) error) {
+	b.Helper()
+
+	ctx := context.Background()
+	trips := 0
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+
+		// a new mock each time, since finding the next expectation gets slower with every one that was met
+		client, mock, err := kivikmock.New()
+		if err != nil {
+			b.Fatalf("unable to create mock: %s", err)
+		}
+
+		cs, err := NewWithClient(ctx, client)
+		if err != nil {
+			b.Fatalf("unable to create config service: %s", err)
+		}
+
+		trips += expect(mock)
+		b.StartTimer()
+
+		if err := lookup(ctx, cs.(*configService)); err != nil {
+			b.Fatalf("lookup failed: %s", err)
+		}
+
+		b.StopTimer()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			b.Fatalf("unexpected requests: %s", err)
+		}
+	}
+
+	b.ReportMetric(float64(trips)/float64(b.N), "round-trips/op")
+}
+
+// A PC whose mapping is a few characters shorter than its hostname, like most are.
+func BenchmarkRoomAndControlGroup(b *testing.B) {
+	const hostname = "ITB-1101-CP1"
+	const doc = `{"uiConfig": "ITB-1101", "controlGroup": "Group 1"}`
+	notFound := &kivik.Error{HTTPStatus: http.StatusNotFound}
+
+	b.Run("get", func(b *testing.B) {
+		benchmarkLookups(b, func(mock *kivikmock.Client) int {
+			db := mock.NewDB()
+			trips := 0
+
+			for _, id := range pcconfig.MappingCandidates(hostname) {
+				mock.ExpectDB().WillReturn(db)
+				trips++
+
+				if id == "ITB-1101" {
+					db.ExpectGet().WithDocID(id).WillReturn(document(b, doc))
+					break
+				}
+
+				db.ExpectGet().WithDocID(id).WillReturnError(notFound)
+			}
+
+			return trips
+		}, func(ctx context.Context, c *configService) error {
+			_, err := getMapping(ctx, c, hostname)
+			return err
+		})
+	})
+
+	b.Run("all_docs", func(b *testing.B) {
+		ids := pcconfig.MappingCandidates(hostname)
+
+		benchmarkLookups(b, func(mock *kivikmock.Client) int {
+			db := mock.NewDB()
+			mock.ExpectDB().WillReturn(db)
+			db.ExpectAllDocs().WillReturn(mappingRows(ids, map[string]string{"ITB-1101": doc}))
+			return 1
+		}, func(ctx context.Context, c *configService) error {
+			_, _, err := c.RoomAndControlGroup(ctx, hostname)
+			return err
+		})
+	})
+}
+
+// A hundred PCs in ten rooms, with one mapping for each room.
+func BenchmarkBulkLookup(b *testing.B) {
+	var hostnames, rooms []string
+	mappings := make(map[string]string)
+	uiConfigs := make(map[string]string)
+
+	for i := 0; i < 10; i++ {
+		room := fmt.Sprintf("ITB-11%02d", i)
+		rooms = append(rooms, room)
+		mappings[room] = fmt.Sprintf(`{"uiConfig": %q, "controlGroup": "Group 1"}`, room)
+		uiConfigs[room] = `{"presets": [{"name": "Group 1", "cameras": [{"displayName": "Front"}]}]}`
+
+		for j := 0; j < 10; j++ {
+			hostnames = append(hostnames, fmt.Sprintf("%s-CP%d", room, j))
+		}
+	}
+
+	notFound := &kivik.Error{HTTPStatus: http.StatusNotFound}
+
+	b.Run("get", func(b *testing.B) {
+		benchmarkLookups(b, func(mock *kivikmock.Client) int {
+			mappingDB := mock.NewDB()
+			uiDB := mock.NewDB()
+			trips := 0
+
+			for _, hostname := range hostnames {
+				for _, id := range pcconfig.MappingCandidates(hostname) {
+					mock.ExpectDB().WillReturn(mappingDB)
+					trips++
+
+					if doc, ok := mappings[id]; ok {
+						mappingDB.ExpectGet().WithDocID(id).WillReturn(document(b, doc))
+						break
+					}
+
+					mappingDB.ExpectGet().WithDocID(id).WillReturnError(notFound)
+				}
+
+				mock.ExpectDB().WillReturn(uiDB)
+				uiDB.ExpectGet().WillReturn(document(b, uiConfigs[hostname[:8]]))
+				trips++
+			}
+
+			return trips
+		}, func(ctx context.Context, c *configService) error {
+			for _, hostname := range hostnames {
+				mapping, err := getMapping(ctx, c, hostname)
+				if err != nil {
+					return err
+				}
+
+				if _, err := c.Cameras(ctx, mapping.UIConfig, mapping.ControlGroup); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	})
+
+	b.Run("all_docs", func(b *testing.B) {
+		var ids []string
+		for _, hostname := range hostnames {
+			ids = append(ids, pcconfig.MappingCandidates(hostname)...)
+		}
+
+		benchmarkLookups(b, func(mock *kivikmock.Client) int {
+			mappingDB := mock.NewDB()
+			uiDB := mock.NewDB()
+
+			mock.ExpectDB().WillReturn(mappingDB)
+			mappingDB.ExpectAllDocs().WillReturn(mappingRows(ids, mappings))
+			mock.ExpectDB().WillReturn(uiDB)
+			uiDB.ExpectAllDocs().WillReturn(mappingRows(rooms, uiConfigs))
+			return 2
+		}, func(ctx context.Context, c *configService) error {
+			found, err := c.PCMappingsFor(ctx, hostnames)
+			if err != nil {
+				return err
+			}
+
+			var ids []string
+			seen := make(map[string]bool)
+			for _, mapping := range found {
+				if !seen[mapping.Room] {
+					seen[mapping.Room] = true
+					ids = append(ids, mapping.Room)
+				}
+			}
+
+			_, err = c.RoomsFor(ctx, ids)
+			return err
+		})
+	})
+}
+
+func document(b *testing.B, doc string) *driver.Document {
+	d, err := kivikmock.Document(doc)
+	if err != nil {
+		b.Fatalf("unable to create document: %s", err)
+	}
+
+	return d
+}
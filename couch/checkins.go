@@ -0,0 +1,75 @@
+package couch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+type checkIn struct {
+	ConfigVersion string    `json:"configVersion"`
+	AppVersion    string    `json:"appVersion,omitempty"`
+	UptimeSeconds int64     `json:"uptimeSeconds,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+func (c *configService) CheckIn(ctx context.Context, ci pcconfig.CheckIn) error {
+	updated, err := toMap(checkIn{
+		ConfigVersion: ci.ConfigVersion,
+		AppVersion:    ci.AppVersion,
+		UptimeSeconds: ci.UptimeSeconds,
+		Time:          ci.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.updateDoc(ctx, c.checkInDB, ci.Hostname, true, func(doc map[string]interface{}) error {
+		for k := range doc {
+			if k != "_id" && k != "_rev" {
+				delete(doc, k)
+			}
+		}
+
+		for k, v := range updated {
+			doc[k] = v
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to store check in: %w", err)
+	}
+
+	return nil
+}
+
+func (c *configService) CheckIns(ctx context.Context) ([]pcconfig.CheckIn, error) {
+	checkIns := []pcconfig.CheckIn{}
+
+	err := c.allDocs(ctx, c.checkInDB, func(id string, rows *kivik.Rows) error {
+		var doc checkIn
+		if err := rows.ScanDoc(&doc); err != nil {
+			return fmt.Errorf("unable to scan check in %q: %w", id, err)
+		}
+
+		checkIns = append(checkIns, pcconfig.CheckIn{
+			Hostname:      id,
+			ConfigVersion: doc.ConfigVersion,
+			AppVersion:    doc.AppVersion,
+			UptimeSeconds: doc.UptimeSeconds,
+			Time:          doc.Time,
+		})
+
+		return nil
+	})
+	if err != nil && kivik.StatusCode(err) != http.StatusNotFound {
+		return nil, err
+	}
+
+	return checkIns, nil
+}
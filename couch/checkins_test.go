@@ -0,0 +1,48 @@
+package couch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckIns(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultCheckInDB).WillReturn(db)
+	db.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ITB-1101-CP1", Doc: []byte(`{"_id": "ITB-1101-CP1", "configVersion": "abc", "appVersion": "1.2.3", "uptimeSeconds": 60, "time": "2026-08-24T06:00:00Z"}`)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	checkIns, err := cs.(pcconfig.CheckInService).CheckIns(ctx)
+	if err != nil {
+		t.Fatalf("unable to get check ins: %s", err)
+	}
+
+	expected := []pcconfig.CheckIn{
+		{
+			Hostname:      "ITB-1101-CP1",
+			ConfigVersion: "abc",
+			AppVersion:    "1.2.3",
+			UptimeSeconds: 60,
+			Time:          time.Date(2026, 8, 24, 6, 0, 0, 0, time.UTC),
+		},
+	}
+
+	if diff := cmp.Diff(expected, checkIns); diff != "" {
+		t.Errorf("got incorrect check ins (-want, +got):\n%s", diff)
+	}
+}
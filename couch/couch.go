@@ -2,19 +2,26 @@ package couch
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 
 	pcconfig "github.com/byuoitav/pc-config"
-	_ "github.com/go-kivik/couchdb/v3"
+	"github.com/go-kivik/couchdb/v3"
 	"github.com/go-kivik/kivik/v3"
+	"go.uber.org/zap"
 )
 
 type configService struct {
 	client      *kivik.Client
 	uiConfigDB  string
 	pcMappingDB string
+	templateDB  string
+	scheduleDB  string
+	checkInDB   string
+	validation  ValidationMode
+	log         *zap.Logger
 }
 
 // New creates a new ConfigService, created a couchdb client pointed at url.
@@ -24,19 +31,17 @@ func New(ctx context.Context, url string, opts ...Option) (pcconfig.ConfigServic
 		return nil, fmt.Errorf("unable to build client: %w", err)
 	}
 
+	// has to be set before any other authentication, since they wrap it
+	if err := client.Authenticate(ctx, couchdb.SetTransport(newOptions(opts).transport(url))); err != nil {
+		return nil, fmt.Errorf("unable to set transport: %w", err)
+	}
+
 	return NewWithClient(ctx, client, opts...)
 }
 
 // NewWithClient creates a new ConfigService using the given client.
 func NewWithClient(ctx context.Context, client *kivik.Client, opts ...Option) (pcconfig.ConfigService, error) {
-	options := options{
-		uiConfigDB:  _defaultUIConfigDB,
-		pcMappingDB: _defaultPCMappingDB,
-	}
-
-	for _, o := range opts {
-		o.apply(&options)
-	}
+	options := newOptions(opts)
 
 	if options.authFunc != nil {
 		if err := client.Authenticate(ctx, options.authFunc); err != nil {
@@ -48,48 +53,105 @@ func NewWithClient(ctx context.Context, client *kivik.Client, opts ...Option) (p
 		client:      client,
 		uiConfigDB:  options.uiConfigDB,
 		pcMappingDB: options.pcMappingDB,
+		templateDB:  options.templateDB,
+		scheduleDB:  options.scheduleDB,
+		checkInDB:   options.checkInDB,
+		validation:  options.validation,
+		log:         options.log,
 	}, nil
 }
 
 func (c *configService) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
-	var mapping pcMapping
-	var err error
-	db := c.client.DB(ctx, c.pcMappingDB)
-
-	for {
-		err = db.Get(ctx, hostname).ScanDoc(&mapping)
-		switch kivik.StatusCode(err) {
-		case 0:
-			return mapping.UIConfig, mapping.ControlGroup, nil
-		case http.StatusNotFound:
-			// try again
-		default:
-			return "", "", fmt.Errorf("unable to get/scan pc mapping: %w", err)
+	mapping, _, err := c.mapping(ctx, hostname)
+	if err != nil {
+		return "", "", err
+	}
+
+	return mapping.UIConfig, mapping.ControlGroup, nil
+}
+
+func (c *configService) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	mapping, id, err := c.mapping(ctx, hostname)
+	if err != nil {
+		return pcconfig.PCOverrides{}, "", err
+	}
+
+	return mapping.Overrides, id, nil
+}
+
+// mapping gets the longest pc mapping that hostname starts with, and its id. Every
+// mapping it could be is requested at once.
+func (c *configService) mapping(ctx context.Context, hostname string) (pcMapping, string, error) {
+	ids := pcconfig.MappingCandidates(hostname)
+
+	docs, err := c.getDocs(ctx, c.pcMappingDB, ids)
+	if err != nil {
+		return pcMapping{}, "", fmt.Errorf("unable to get/scan pc mapping: %w", err)
+	}
+
+	for _, id := range ids {
+		raw, ok := docs[id]
+		if !ok {
+			continue
 		}
 
-		if len(hostname) < 4 {
-			break
+		mapping, err := c.decodePCMapping(id, raw)
+		if err != nil {
+			return pcMapping{}, "", fmt.Errorf("unable to get/scan pc mapping: %w", err)
 		}
 
-		hostname = hostname[:len(hostname)-1]
+		return mapping, id, nil
 	}
 
-	return "", "", fmt.Errorf("unable to get/scan pc mapping: %w", err)
+	return pcMapping{}, "", fmt.Errorf("unable to get/scan pc mapping: %w", &kivik.Error{
+		HTTPStatus: http.StatusNotFound,
+		Message:    fmt.Sprintf("no pc mapping for %q", hostname),
+	})
 }
 
 func (c *configService) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
-	var config uiConfig
-
-	db := c.client.DB(ctx, c.uiConfigDB)
-	if err := db.Get(ctx, room).ScanDoc(&config); err != nil {
-		return []pcconfig.Camera{}, fmt.Errorf("unable to get/scan ui config: %w", err)
+	config, err := c.uiConfig(ctx, room)
+	if err != nil {
+		return []pcconfig.Camera{}, err
 	}
 
 	for _, cg := range config.ControlGroups {
 		if cg.ID == controlGroup {
-			return cg.Cameras, nil
+			r, err := c.resolve(ctx, pcconfig.Room{ID: room, ControlGroups: []pcconfig.ControlGroup{{Name: cg.ID, Cameras: cg.Cameras}}})
+			if err != nil {
+				return []pcconfig.Camera{}, err
+			}
+
+			return r.ControlGroups[0].Cameras, nil
 		}
 	}
 
 	return []pcconfig.Camera{}, errors.New("no matching control group found")
 }
+
+// Room returns every control group in room, with their templates resolved.
+func (c *configService) Room(ctx context.Context, room string) (pcconfig.Room, error) {
+	config, err := c.uiConfig(ctx, room)
+	if err != nil {
+		return pcconfig.Room{}, err
+	}
+
+	return c.resolve(ctx, config.toRoom(room))
+}
+
+// uiConfig gets the ui config for room.
+func (c *configService) uiConfig(ctx context.Context, room string) (uiConfig, error) {
+	var raw json.RawMessage
+
+	db := c.client.DB(ctx, c.uiConfigDB)
+	if err := db.Get(ctx, room).ScanDoc(&raw); err != nil {
+		return uiConfig{}, fmt.Errorf("unable to get/scan ui config: %w", err)
+	}
+
+	config, err := c.decodeUIConfig(room, raw)
+	if err != nil {
+		return uiConfig{}, fmt.Errorf("unable to get/scan ui config: %w", err)
+	}
+
+	return config, nil
+}
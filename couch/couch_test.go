@@ -12,6 +12,7 @@ import (
 	pcconfig "github.com/byuoitav/pc-config"
 	"github.com/go-kivik/couchdb/v3"
 	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
 	"github.com/go-kivik/kivikmock/v3"
 	"github.com/google/go-cmp/cmp"
 )
@@ -123,16 +124,38 @@ func TestNewClientWithAuthError(t *testing.T) {
 	}
 }
 
+// mappingRows returns the rows _all_docs returns for ids, where docs are the ones that exist.
+func mappingRows(ids []string, docs map[string]string) *kivikmock.Rows {
+	rows := kivikmock.NewRows()
+	for _, id := range ids {
+		key := []byte(`"` + id + `"`)
+
+		doc, ok := docs[id]
+		if !ok {
+			rows.AddRow(&driver.Row{Key: key})
+			continue
+		}
+
+		rows.AddRow(&driver.Row{ID: id, Key: key, Doc: []byte(doc)})
+	}
+
+	return rows
+}
+
 func TestRoomAndControlGroup(t *testing.T) {
 	client, mock := kivikmock.NewT(t)
 
+	ids := pcconfig.MappingCandidates("TEC-ITB-1101")
+
 	db := mock.NewDB()
 	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
-	db.ExpectGet().WithDocID("TEC-ITB-1101").WillReturn(kivikmock.DocumentT(t, `{
-		"_id": "TEC-ITB-1101",
-		"uiConfig": "ITB-1101",
-		"controlGroup": "Test Control Group"
-	}`))
+	db.ExpectAllDocs().WithOptions(map[string]interface{}{"keys": ids, "include_docs": true}).WillReturn(mappingRows(ids, map[string]string{
+		"TEC-ITB-1101": `{
+			"_id": "TEC-ITB-1101",
+			"uiConfig": "ITB-1101",
+			"controlGroup": "Test Control Group"
+		}`,
+	}))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -162,7 +185,7 @@ func TestRoomAndControlGroupError(t *testing.T) {
 
 	db := mock.NewDB()
 	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
-	db.ExpectGet().WithDocID("TEC-ITB-1101").WillReturnError(expected)
+	db.ExpectAllDocs().WillReturnError(expected)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -181,22 +204,27 @@ func TestRoomAndControlGroupError(t *testing.T) {
 func TestRoomAndControlGroupRetry(t *testing.T) {
 	client, mock := kivikmock.NewT(t)
 
-	kerr := &kivik.Error{
-		HTTPStatus: http.StatusNotFound,
-		Err:        errors.New("error"),
+	ids := pcconfig.MappingCandidates("TEC-ITB-1101-NEW")
+	if len(ids) != 14 || ids[4] != "TEC-ITB-1101" || ids[13] != "TEC" {
+		t.Fatalf("got wrong candidates: %q", ids)
 	}
 
 	db := mock.NewDB()
 	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
-	db.ExpectGet().WithDocID("TEC-ITB-1101-NEW").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-1101-NE").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-1101-N").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-1101-").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-1101").WillReturn(kivikmock.DocumentT(t, `{
-		"_id": "TEC-ITB-1101",
-		"uiConfig": "ITB-1101",
-		"controlGroup": "Test Control Group"
-	}`))
+	db.ExpectAllDocs().WithOptions(map[string]interface{}{"keys": ids, "include_docs": true}).WillReturn(mappingRows(ids, map[string]string{
+		// deleted
+		"TEC-ITB-1101-N": `null`,
+		"TEC-ITB-1101": `{
+			"_id": "TEC-ITB-1101",
+			"uiConfig": "ITB-1101",
+			"controlGroup": "Test Control Group"
+		}`,
+		"TEC-ITB": `{
+			"_id": "TEC-ITB",
+			"uiConfig": "ITB-1",
+			"controlGroup": "Shorter Control Group"
+		}`,
+	}))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -219,20 +247,16 @@ func TestRoomAndControlGroupRetry(t *testing.T) {
 	}
 }
 
-func TestRoomAndControlGroupRetryFail(t *testing.T) {
+func TestRoomAndControlGroupRowError(t *testing.T) {
 	client, mock := kivikmock.NewT(t)
 
-	kerr := &kivik.Error{
-		HTTPStatus: http.StatusNotFound,
-		Err:        errors.New("error"),
-	}
 	expected := errors.New("some error")
 
 	db := mock.NewDB()
 	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
-	db.ExpectGet().WithDocID("TEC-ITB-1101-NEW").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-1101-NE").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-1101-N").WillReturnError(expected)
+	db.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{Key: []byte(`"TEC-ITB-1101-NEW"`)}).
+		AddRowError(expected))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -251,23 +275,11 @@ func TestRoomAndControlGroupRetryFail(t *testing.T) {
 func TestRoomAndControlGroupTooShort(t *testing.T) {
 	client, mock := kivikmock.NewT(t)
 
-	kerr := &kivik.Error{
-		HTTPStatus: http.StatusNotFound,
-		Err:        errors.New("error"),
-	}
+	ids := pcconfig.MappingCandidates("TEC-ITB-1101")
 
 	db := mock.NewDB()
 	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
-	db.ExpectGet().WithDocID("TEC-ITB-1101").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-110").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-11").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-1").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB-").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-ITB").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-IT").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-I").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC-").WillReturnError(kerr)
-	db.ExpectGet().WithDocID("TEC").WillReturnError(kerr)
+	db.ExpectAllDocs().WithOptions(map[string]interface{}{"keys": ids, "include_docs": true}).WillReturn(mappingRows(ids, nil))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -283,6 +295,54 @@ func TestRoomAndControlGroupTooShort(t *testing.T) {
 	}
 }
 
+func TestOverrides(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	ids := pcconfig.MappingCandidates("ITB-1101-CP1")
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
+	db.ExpectAllDocs().WithOptions(map[string]interface{}{"keys": ids, "include_docs": true}).WillReturn(mappingRows(ids, map[string]string{
+		"ITB-1101-CP": `{
+			"_id": "ITB-1101-CP",
+			"uiConfig": "ITB-1101",
+			"controlGroup": "Test Control Group",
+			"overrides": {
+				"cameras": [
+					{"camera": "mock cam", "displayName": "renamed", "stream": "https://stream-low"}
+				]
+			}
+		}`,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	overrides, source, err := cs.(pcconfig.OverrideService).Overrides(ctx, "ITB-1101-CP1")
+	if err != nil {
+		t.Fatalf("failed to get overrides: %s", err)
+	}
+
+	expected := pcconfig.PCOverrides{
+		Cameras: []pcconfig.CameraOverride{
+			{Camera: "mock cam", DisplayName: "renamed", Stream: "https://stream-low"},
+		},
+	}
+
+	if source != "ITB-1101-CP" {
+		t.Fatalf("got wrong source: expected %q, got %q", "ITB-1101-CP", source)
+	}
+
+	if diff := cmp.Diff(expected, overrides); diff != "" {
+		t.Fatalf("got wrong overrides (-want, +got):\n%s", diff)
+	}
+}
+
 func TestCameras(t *testing.T) {
 	client, mock := kivikmock.NewT(t)
 
@@ -387,3 +447,46 @@ func TestCameraMissing(t *testing.T) {
 		t.Fatalf("expected no matching control group error, got: %s", err.Error())
 	}
 }
+
+func TestRoom(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	room, err := cs.(pcconfig.RoomService).Room(ctx, "ITB-1101")
+	if err != nil {
+		t.Fatalf("unable to get room: %s", err)
+	}
+
+	expected := pcconfig.Room{
+		ID: "ITB-1101",
+		ControlGroups: []pcconfig.ControlGroup{
+			{
+				Name: "Camera",
+				Cameras: []pcconfig.Camera{
+					{
+						DisplayName: "mock cam",
+						Stream:      "https://stream",
+						Presets: []pcconfig.CameraPreset{
+							{DisplayName: "mock preset 1", SetPreset: "https://mock preset 1", SavePreset: "https://save mock preset 1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, room); diff != "" {
+		t.Errorf("got incorrect room (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,130 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+type revsInfo struct {
+	RevsInfo []struct {
+		Rev    string `json:"rev"`
+		Status string `json:"status"`
+	} `json:"_revs_info"`
+}
+
+// RoomHistory returns the revisions couch still knows about for room. The contents
+// of old revisions are only available until the database is compacted.
+func (c *configService) RoomHistory(ctx context.Context, room string) ([]pcconfig.Revision, error) {
+	return c.history(ctx, c.uiConfigDB, room)
+}
+
+func (c *configService) RoomAt(ctx context.Context, room, rev string) (pcconfig.Room, error) {
+	var config uiConfig
+	if err := c.getRev(ctx, c.uiConfigDB, room, rev, &config); err != nil {
+		return pcconfig.Room{}, err
+	}
+
+	return config.toRoom(room), nil
+}
+
+func (c *configService) RollbackRoom(ctx context.Context, room, rev string) (string, error) {
+	return c.rollback(ctx, c.uiConfigDB, room, rev)
+}
+
+// MappingHistory returns the revisions couch still knows about for the pc mapping hostname.
+// The contents of old revisions are only available until the database is compacted.
+func (c *configService) MappingHistory(ctx context.Context, hostname string) ([]pcconfig.Revision, error) {
+	return c.history(ctx, c.pcMappingDB, hostname)
+}
+
+func (c *configService) MappingAt(ctx context.Context, hostname, rev string) (pcconfig.PCMapping, error) {
+	var mapping pcMapping
+	if err := c.getRev(ctx, c.pcMappingDB, hostname, rev, &mapping); err != nil {
+		return pcconfig.PCMapping{}, err
+	}
+
+	return mapping.toPCMapping(hostname), nil
+}
+
+func (c *configService) RollbackMapping(ctx context.Context, hostname, rev string) (string, error) {
+	return c.rollback(ctx, c.pcMappingDB, hostname, rev)
+}
+
+func (c *configService) history(ctx context.Context, db, id string) ([]pcconfig.Revision, error) {
+	var info revsInfo
+	if err := c.client.DB(ctx, db).Get(ctx, id, kivik.Options{"revs_info": true}).ScanDoc(&info); err != nil {
+		return nil, fmt.Errorf("unable to get/scan revisions: %w", err)
+	}
+
+	revs := make([]pcconfig.Revision, 0, len(info.RevsInfo))
+	for _, ri := range info.RevsInfo {
+		revs = append(revs, pcconfig.Revision{
+			Rev:       ri.Rev,
+			Available: ri.Status == "available",
+		})
+	}
+
+	return revs, nil
+}
+
+// getRev scans the doc id at rev into dest. If rev is empty, the current doc is used.
+func (c *configService) getRev(ctx context.Context, db, id, rev string, dest interface{}) error {
+	var opts kivik.Options
+	if rev != "" {
+		opts = kivik.Options{"rev": rev}
+	}
+
+	if err := c.client.DB(ctx, db).Get(ctx, id, opts).ScanDoc(dest); err != nil {
+		return fmt.Errorf("unable to get/scan %s at %q: %w", id, rev, err)
+	}
+
+	return nil
+}
+
+// rollback replaces the doc id with its contents at rev. Attachments (like
+// thumbnails) aren't rolled back, since old ones might not exist anymore.
+func (c *configService) rollback(ctx context.Context, db, id, rev string) (string, error) {
+	row := c.client.DB(ctx, db).Get(ctx, id, kivik.Options{"rev": rev})
+	if row.Err != nil {
+		return "", fmt.Errorf("unable to get %s at %q: %w", id, rev, row.Err)
+	}
+
+	var old map[string]interface{}
+	dec := json.NewDecoder(row.Body)
+	dec.UseNumber()
+	err := dec.Decode(&old)
+	row.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("unable to decode %s at %q: %w", id, rev, err)
+	}
+
+	return c.updateDoc(ctx, db, id, false, func(doc map[string]interface{}) error {
+		for k := range doc {
+			if !keepOnRollback(k) {
+				delete(doc, k)
+			}
+		}
+
+		for k, v := range old {
+			if !keepOnRollback(k) {
+				doc[k] = v
+			}
+		}
+
+		return nil
+	})
+}
+
+// keepOnRollback returns true if the field k comes from the current doc instead of the old one.
+func keepOnRollback(k string) bool {
+	switch k {
+	case "_id", "_rev", "_attachments", "_revisions", "_revs_info", "_conflicts":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoomHistory(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WithOptions(map[string]interface{}{"revs_info": true}).WillReturn(kivikmock.DocumentT(t, `{
+		"_id": "ITB-1101",
+		"_rev": "3-c",
+		"_revs_info": [
+			{"rev": "3-c", "status": "available"},
+			{"rev": "2-b", "status": "available"},
+			{"rev": "1-a", "status": "missing"}
+		]
+	}`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	revs, err := cs.(pcconfig.HistoryService).RoomHistory(ctx, "ITB-1101")
+	if err != nil {
+		t.Fatalf("unable to get history: %s", err)
+	}
+
+	expected := []pcconfig.Revision{
+		{Rev: "3-c", Available: true},
+		{Rev: "2-b", Available: true},
+		{Rev: "1-a"},
+	}
+
+	if diff := cmp.Diff(expected, revs); diff != "" {
+		t.Errorf("got incorrect history (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRollbackRoom(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	var saved map[string]interface{}
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WithOptions(map[string]interface{}{"rev": "1-abc"}).WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, `{
+		"_id": "ITB-1101",
+		"_rev": "2-def",
+		"_attachments": {"thumbnail.jpg": {"content_type": "image/jpeg", "stub": true}},
+		"presets": [],
+		"broken": true
+	}`))
+	db.ExpectPut().WithDocID("ITB-1101").WillExecute(func(ctx context.Context, id string, doc interface{}, opts map[string]interface{}) (string, error) {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+
+		return "3-ghi", json.Unmarshal(b, &saved)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	rev, err := cs.(pcconfig.HistoryService).RollbackRoom(ctx, "ITB-1101", "1-abc")
+	if err != nil {
+		t.Fatalf("unable to roll back: %s", err)
+	}
+
+	var expected map[string]interface{}
+	if err := json.Unmarshal([]byte(mockUIConfigDoc), &expected); err != nil {
+		t.Fatalf("unable to parse expected doc: %s", err)
+	}
+
+	// the rev and attachments come from the current doc
+	expected["_rev"] = "2-def"
+	expected["_attachments"] = map[string]interface{}{
+		"thumbnail.jpg": map[string]interface{}{"content_type": "image/jpeg", "stub": true},
+	}
+
+	if rev != "3-ghi" {
+		t.Fatalf("got wrong rev: expected %q, got %q", "3-ghi", rev)
+	}
+
+	if diff := cmp.Diff(expected, saved); diff != "" {
+		t.Errorf("saved incorrect doc (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,118 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+func (c *configService) PCMappings(ctx context.Context) ([]pcconfig.PCMapping, error) {
+	var mappings []pcconfig.PCMapping
+
+	err := c.allDocs(ctx, c.pcMappingDB, func(id string, rows *kivik.Rows) error {
+		var raw json.RawMessage
+		if err := rows.ScanDoc(&raw); err != nil {
+			return fmt.Errorf("unable to scan pc mapping %q: %w", id, err)
+		}
+
+		mapping, err := c.decodePCMapping(id, raw)
+		if err != nil {
+			return fmt.Errorf("unable to scan pc mapping %q: %w", id, err)
+		}
+
+		mappings = append(mappings, mapping.toPCMapping(id))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+func (c *configService) Rooms(ctx context.Context) ([]pcconfig.Room, error) {
+	var rooms []pcconfig.Room
+
+	err := c.allDocs(ctx, c.uiConfigDB, func(id string, rows *kivik.Rows) error {
+		var raw json.RawMessage
+		if err := rows.ScanDoc(&raw); err != nil {
+			return fmt.Errorf("unable to scan ui config %q: %w", id, err)
+		}
+
+		config, err := c.decodeUIConfig(id, raw)
+		if err != nil {
+			return fmt.Errorf("unable to scan ui config %q: %w", id, err)
+		}
+
+		rooms = append(rooms, config.toRoom(id))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pcconfig.TemplateNames(rooms...)) == 0 {
+		return rooms, nil
+	}
+
+	templates, err := c.Templates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get templates: %w", err)
+	}
+
+	for i := range rooms {
+		// cameras that can't be resolved are left for validation to report
+		rooms[i], _ = pcconfig.ResolveRoomTemplates(rooms[i], templates)
+	}
+
+	return rooms, nil
+}
+
+// allDocs calls fn with every (non-design) doc in db.
+func (c *configService) allDocs(ctx context.Context, db string, fn func(string, *kivik.Rows) error) error {
+	rows, err := c.client.DB(ctx, db).AllDocs(ctx, kivik.Options{"include_docs": true})
+	if err != nil {
+		return fmt.Errorf("unable to get all docs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if strings.HasPrefix(rows.ID(), "_design/") {
+			continue
+		}
+
+		if err := fn(rows.ID(), rows); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("unable to iterate docs: %w", err)
+	}
+
+	return nil
+}
+
+// DecodePCMapping decodes a pc-mapping document with the given id.
+func DecodePCMapping(id string, data []byte) (pcconfig.PCMapping, error) {
+	var mapping pcMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return pcconfig.PCMapping{}, fmt.Errorf("unable to decode pc mapping: %w", err)
+	}
+
+	return mapping.toPCMapping(id), nil
+}
+
+// DecodeRoom decodes a ui-configuration document with the given id.
+func DecodeRoom(id string, data []byte) (pcconfig.Room, error) {
+	var config uiConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return pcconfig.Room{}, fmt.Errorf("unable to decode ui config: %w", err)
+	}
+
+	return config.toRoom(id), nil
+}
@@ -0,0 +1,95 @@
+package couch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPCMappings(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(db)
+	db.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ITB-1101-CP1", Doc: []byte(`{"_id": "ITB-1101-CP1", "uiConfig": "ITB-1101", "controlGroup": "Group 1"}`)}).
+		AddRow(&driver.Row{ID: "_design/views", Doc: []byte(`{"_id": "_design/views", "views": {}}`)}).
+		AddRow(&driver.Row{ID: "ITB-1101-CP2", Doc: []byte(`{"_id": "ITB-1101-CP2", "uiConfig": "ITB-1101", "controlGroup": "Group 2"}`)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	mappings, err := cs.(pcconfig.ConfigLister).PCMappings(ctx)
+	if err != nil {
+		t.Fatalf("unable to get pc mappings: %s", err)
+	}
+
+	expected := []pcconfig.PCMapping{
+		{Hostname: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1"},
+		{Hostname: "ITB-1101-CP2", Room: "ITB-1101", ControlGroup: "Group 2"},
+	}
+
+	if diff := cmp.Diff(expected, mappings); diff != "" {
+		t.Errorf("got incorrect mappings (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRooms(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ITB-1101", Doc: []byte(mockUIConfigDoc)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	rooms, err := cs.(pcconfig.ConfigLister).Rooms(ctx)
+	if err != nil {
+		t.Fatalf("unable to get rooms: %s", err)
+	}
+
+	expected := []pcconfig.Room{
+		{
+			ID: "ITB-1101",
+			ControlGroups: []pcconfig.ControlGroup{
+				{
+					Name: "Camera",
+					Cameras: []pcconfig.Camera{
+						{
+							DisplayName: "mock cam",
+							Stream:      "https://stream",
+							Presets: []pcconfig.CameraPreset{
+								{
+									DisplayName: "mock preset 1",
+									SetPreset:   "https://mock preset 1",
+									SavePreset:  "https://save mock preset 1",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, rooms); diff != "" {
+		t.Errorf("got incorrect rooms (-want, +got):\n%s", diff)
+	}
+}
@@ -1,16 +1,61 @@
 package couch
 
-import "github.com/go-kivik/couchdb/v3"
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kivik/couchdb/v3"
+	"go.uber.org/zap"
+)
 
 const (
 	_defaultUIConfigDB  = "ui-configuration"
 	_defaultPCMappingDB = "pc-mapping"
+	_defaultTemplateDB  = "camera-templates"
+	_defaultScheduleDB  = "scheduled-changes"
+	_defaultCheckInDB   = "pc-checkins"
 )
 
 type options struct {
 	authFunc    interface{}
 	uiConfigDB  string
 	pcMappingDB string
+	templateDB  string
+	scheduleDB  string
+	checkInDB   string
+	validation  ValidationMode
+	log         *zap.Logger
+
+	requestTimeout  time.Duration
+	retries         int
+	retryBackoff    time.Duration
+	breakerFailures int
+	breakerCooldown time.Duration
+	tls             *tls.Config
+	transportConfig TransportConfig
+
+	// authTransport, if set, wraps the transport to authenticate each request. url is couchdb's address.
+	authTransport func(base http.RoundTripper, url string) http.RoundTripper
+}
+
+func newOptions(opts []Option) options {
+	options := options{
+		uiConfigDB:   _defaultUIConfigDB,
+		pcMappingDB:  _defaultPCMappingDB,
+		templateDB:   _defaultTemplateDB,
+		scheduleDB:   _defaultScheduleDB,
+		checkInDB:    _defaultCheckInDB,
+		log:          zap.NewNop(),
+		retryBackoff: 100 * time.Millisecond,
+	}
+
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	return options
 }
 
 // Option configures how we create the DataService.
@@ -29,3 +74,122 @@ func WithBasicAuth(username, password string) Option {
 		o.authFunc = couchdb.BasicAuth(username, password)
 	})
 }
+
+// WithBasicAuthFrom authenticates each request with basic auth, using the current credentials from creds.
+// Only used by New.
+func WithBasicAuthFrom(creds CredentialSource) Option {
+	return optionFunc(func(o *options) {
+		o.authTransport = func(base http.RoundTripper, _ string) http.RoundTripper {
+			return &basicAuthTransport{base: base, creds: creds}
+		}
+	})
+}
+
+// WithCookieAuth authenticates with a CouchDB session cookie, using the credentials from creds.
+// The session is renewed before it expires, when it's rejected, and when the credentials change.
+// Only used by New.
+func WithCookieAuth(creds CredentialSource) Option {
+	return optionFunc(func(o *options) {
+		o.authTransport = func(base http.RoundTripper, url string) http.RoundTripper {
+			return &sessionTransport{
+				base:       base,
+				creds:      creds,
+				sessionURL: strings.TrimSuffix(url, "/") + "/_session",
+				now:        time.Now,
+			}
+		}
+	})
+}
+
+// WithJWTFile authenticates each request with the JSON Web Token in path as a bearer token. The file
+// is read again whenever it changes, so that whatever issues the token can refresh it. Only used by New.
+func WithJWTFile(path string) Option {
+	return optionFunc(func(o *options) {
+		o.authTransport = func(base http.RoundTripper, _ string) http.RoundTripper {
+			return &bearerTransport{base: base, token: &watchedFile{path: path}}
+		}
+	})
+}
+
+// WithProxyAuth authenticates as username with roles using CouchDB's proxy authentication. secret is
+// CouchDB's couch_httpd_auth/secret; if it's empty, the token header isn't sent.
+func WithProxyAuth(username, secret string, roles []string) Option {
+	return optionFunc(func(o *options) {
+		o.authFunc = couchdb.ProxyAuth(username, secret, roles)
+	})
+}
+
+// WithTemplateDB sets the database camera templates are stored in.
+func WithTemplateDB(db string) Option {
+	return optionFunc(func(o *options) {
+		o.templateDB = db
+	})
+}
+
+// WithScheduleDB sets the database scheduled change sets are stored in.
+func WithScheduleDB(db string) Option {
+	return optionFunc(func(o *options) {
+		o.scheduleDB = db
+	})
+}
+
+// WithCheckInDB sets the database PC check ins are stored in.
+func WithCheckInDB(db string) Option {
+	return optionFunc(func(o *options) {
+		o.checkInDB = db
+	})
+}
+
+// WithValidation sets how documents that don't match their schema are handled. Defaults to ValidationOff.
+func WithValidation(mode ValidationMode) Option {
+	return optionFunc(func(o *options) {
+		o.validation = mode
+	})
+}
+
+// WithLogger sets the logger problems with documents are logged to.
+func WithLogger(log *zap.Logger) Option {
+	return optionFunc(func(o *options) {
+		o.log = log
+	})
+}
+
+// WithRequestTimeout limits how long each request to CouchDB can take, including reading its
+// response. Each retry gets its own timeout. Only used by New.
+func WithRequestTimeout(d time.Duration) Option {
+	return optionFunc(func(o *options) {
+		o.requestTimeout = d
+	})
+}
+
+// WithRetries retries reads that fail because of a network error or a 429, 502, 503, or 504 up to
+// retries times. The wait between retries starts around backoff and doubles each time. Only used by New.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return optionFunc(func(o *options) {
+		o.retries = retries
+		o.retryBackoff = backoff
+	})
+}
+
+// WithCircuitBreaker stops sending requests to CouchDB for cooldown after failures requests in
+// a row fail, returning ErrCircuitOpen instead. Only used by New.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return optionFunc(func(o *options) {
+		o.breakerFailures = failures
+		o.breakerCooldown = cooldown
+	})
+}
+
+// WithTLSConfig sets the TLS config used to connect to CouchDB, like one from LoadTLSConfig. Only used by New.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return optionFunc(func(o *options) {
+		o.tls = cfg
+	})
+}
+
+// WithTransportConfig tunes the connections made to CouchDB. Only used by New.
+func WithTransportConfig(tc TransportConfig) Option {
+	return optionFunc(func(o *options) {
+		o.transportConfig = tc
+	})
+}
@@ -0,0 +1,185 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+// _maxUpdateAttempts is how many times a document update is retried after a conflict.
+const _maxUpdateAttempts = 3
+
+func (c *configService) SetCameraPreset(ctx context.Context, room, controlGroup, camera string, slot int, preset pcconfig.CameraPreset) error {
+	return c.updateUIConfig(ctx, room, func(doc map[string]interface{}) error {
+		cam, err := findCamera(doc, controlGroup, camera)
+		if err != nil {
+			return err
+		}
+
+		presets, _ := cam["presets"].([]interface{})
+		slots := len(presets)
+
+		if template, _ := cam["template"].(string); template != "" {
+			// presets on a templated camera override the template's slot by slot, so only what is different
+			// from the template's preset is stored. the preset is a position of this camera, so it isn't
+			// written to the template, which other cameras use too.
+			tmpl, err := c.templatePresets(ctx, room, controlGroup, cam)
+			if err != nil {
+				return err
+			}
+
+			if slot < len(tmpl) {
+				preset = pcconfig.PresetOverride(preset, tmpl[slot])
+			}
+
+			if len(tmpl) > slots {
+				slots = len(tmpl)
+			}
+		}
+
+		if slot < 0 || slot > slots {
+			return fmt.Errorf("invalid preset slot %d", slot)
+		}
+
+		// empty presets follow the template
+		for len(presets) <= slot {
+			presets = append(presets, map[string]interface{}{})
+		}
+
+		existing, ok := presets[slot].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("preset %d is not an object", slot)
+		}
+
+		// merge into the existing preset so we don't lose any fields we don't know about
+		updated, err := toMap(preset)
+		if err != nil {
+			return err
+		}
+
+		for k, v := range updated {
+			existing[k] = v
+		}
+
+		presets[slot] = existing
+		cam["presets"] = presets
+		return nil
+	})
+}
+
+// templatePresets returns the presets the raw camera cam gets from its template, without its own.
+func (c *configService) templatePresets(ctx context.Context, room, controlGroup string, cam map[string]interface{}) ([]pcconfig.CameraPreset, error) {
+	b, err := json.Marshal(cam)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal camera: %w", err)
+	}
+
+	var camera pcconfig.Camera
+	if err := json.Unmarshal(b, &camera); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal camera: %w", err)
+	}
+
+	camera.Presets = nil
+
+	resolved, err := c.resolve(ctx, pcconfig.Room{ID: room, ControlGroups: []pcconfig.ControlGroup{{Name: controlGroup, Cameras: []pcconfig.Camera{camera}}}})
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved.ControlGroups[0].Cameras[0].Presets, nil
+}
+
+// updateUIConfig gets the ui config doc for room, calls update with it, and
+// saves the result, retrying if someone else updated the doc at the same time.
+func (c *configService) updateUIConfig(ctx context.Context, room string, update func(map[string]interface{}) error) error {
+	_, err := c.updateDoc(ctx, c.uiConfigDB, room, false, update)
+	return err
+}
+
+// updateDoc gets the doc id from db, calls update with it, and saves the result,
+// retrying if someone else updated the doc at the same time. It returns the doc's new rev.
+// If create is true and the doc doesn't exist, update is called with an empty doc.
+func (c *configService) updateDoc(ctx context.Context, dbName, id string, create bool, update func(map[string]interface{}) error) (string, error) {
+	db := c.client.DB(ctx, dbName)
+
+	var err error
+	for i := 0; i < _maxUpdateAttempts; i++ {
+		var doc map[string]interface{}
+
+		row := db.Get(ctx, id)
+		switch {
+		case create && kivik.StatusCode(row.Err) == http.StatusNotFound:
+			doc = make(map[string]interface{})
+		case row.Err != nil:
+			return "", fmt.Errorf("unable to get %s: %w", id, row.Err)
+		default:
+			dec := json.NewDecoder(row.Body)
+			dec.UseNumber()
+			err = dec.Decode(&doc)
+			row.Body.Close()
+			if err != nil {
+				return "", fmt.Errorf("unable to decode %s: %w", id, err)
+			}
+		}
+
+		if err := update(doc); err != nil {
+			return "", err
+		}
+
+		var rev string
+		rev, err = db.Put(ctx, id, doc)
+		switch kivik.StatusCode(err) {
+		case 0:
+			return rev, nil
+		case http.StatusConflict:
+			// try again
+		default:
+			return "", fmt.Errorf("unable to put %s: %w", id, err)
+		}
+	}
+
+	return "", fmt.Errorf("unable to put %s: %w", id, err)
+}
+
+// findCamera returns the camera named camera in controlGroup from a raw ui config doc.
+func findCamera(doc map[string]interface{}, controlGroup, camera string) (map[string]interface{}, error) {
+	groups, _ := doc["presets"].([]interface{})
+	for _, g := range groups {
+		group, ok := g.(map[string]interface{})
+		if !ok || group["name"] != controlGroup {
+			continue
+		}
+
+		cams, _ := group["cameras"].([]interface{})
+		for _, c := range cams {
+			cam, ok := c.(map[string]interface{})
+			if ok && cam["displayName"] == camera {
+				return cam, nil
+			}
+		}
+
+		return nil, fmt.Errorf("no camera %q in control group %q", camera, controlGroup)
+	}
+
+	return nil, errors.New("no matching control group found")
+}
+
+// toMap converts v to a generic map using its json representation.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal: %w", err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,235 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+const mockUIConfigDoc = `{
+	"_id": "ITB-1101",
+	"_rev": "1-abc",
+	"api": ["localhost"],
+	"presets": [
+		{
+			"name": "Camera",
+			"icon": "tv",
+			"cameras": [
+				{
+					"displayName": "mock cam",
+					"stream": "https://stream",
+					"presets": [
+						{
+							"displayName": "mock preset 1",
+							"setPreset": "https://mock preset 1",
+							"savePreset": "https://save mock preset 1"
+						}
+					]
+				}
+			]
+		}
+	]
+}`
+
+func TestSetCameraPreset(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	var saved map[string]interface{}
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+	db.ExpectPut().WithDocID("ITB-1101").WillExecute(func(ctx context.Context, id string, doc interface{}, opts map[string]interface{}) (string, error) {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+
+		return "2-abc", json.Unmarshal(b, &saved)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	err = cs.(pcconfig.CameraPresetService).SetCameraPreset(ctx, "ITB-1101", "Camera", "mock cam", 0, pcconfig.CameraPreset{
+		DisplayName: "Podium",
+		SetPreset:   "https://mock preset 1",
+		SavePreset:  "https://save mock preset 1",
+	})
+	if err != nil {
+		t.Fatalf("unable to set camera preset: %s", err)
+	}
+
+	var expected map[string]interface{}
+	if err := json.Unmarshal([]byte(mockUIConfigDoc), &expected); err != nil {
+		t.Fatalf("unable to parse expected doc: %s", err)
+	}
+
+	expected["presets"].([]interface{})[0].(map[string]interface{})["cameras"].([]interface{})[0].(map[string]interface{})["presets"].([]interface{})[0].(map[string]interface{})["displayName"] = "Podium"
+
+	if diff := cmp.Diff(expected, saved); diff != "" {
+		t.Errorf("saved incorrect doc (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSetCameraPresetNewSlot(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	var saved uiConfig
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+	db.ExpectPut().WithDocID("ITB-1101").WillExecute(func(ctx context.Context, id string, doc interface{}, opts map[string]interface{}) (string, error) {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+
+		return "2-abc", json.Unmarshal(b, &saved)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	preset := pcconfig.CameraPreset{DisplayName: "Whiteboard", Preset: "2"}
+	if err := cs.(pcconfig.CameraPresetService).SetCameraPreset(ctx, "ITB-1101", "Camera", "mock cam", 1, preset); err != nil {
+		t.Fatalf("unable to set camera preset: %s", err)
+	}
+
+	presets := saved.ControlGroups[0].Cameras[0].Presets
+	if len(presets) != 2 {
+		t.Fatalf("expected 2 presets, got %d", len(presets))
+	}
+
+	if diff := cmp.Diff(preset, presets[1]); diff != "" {
+		t.Errorf("saved incorrect preset (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSetCameraPresetInvalidSlot(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	err = cs.(pcconfig.CameraPresetService).SetCameraPreset(ctx, "ITB-1101", "Camera", "mock cam", 5, pcconfig.CameraPreset{})
+	if err == nil {
+		t.Fatalf("expected an invalid slot error")
+	}
+}
+
+func TestSetCameraPresetConflict(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	conflict := &kivik.Error{
+		HTTPStatus: http.StatusConflict,
+		Err:        errors.New("conflict"),
+	}
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+	db.ExpectPut().WithDocID("ITB-1101").WillReturnError(conflict)
+	db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+	db.ExpectPut().WithDocID("ITB-1101").WillReturn("3-abc")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	err = cs.(pcconfig.CameraPresetService).SetCameraPreset(ctx, "ITB-1101", "Camera", "mock cam", 0, pcconfig.CameraPreset{DisplayName: "Podium"})
+	if err != nil {
+		t.Fatalf("expected conflict to be retried, got %s", err)
+	}
+}
+
+func TestSetCameraPresetTemplate(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	var saved uiConfig
+
+	uiDB := mock.NewDB()
+	templateDB := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(uiDB)
+	uiDB.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, `{
+		"_id": "ITB-1101",
+		"presets": [{"name": "Group 1", "cameras": [{"displayName": "Front", "template": "ptz", "address": "10.0.0.5"}]}]
+	}`))
+	mock.ExpectDB().WithName(_defaultTemplateDB).WillReturn(templateDB)
+	templateDB.ExpectGet().WithDocID("ptz").WillReturn(kivikmock.DocumentT(t, `{
+		"_id": "ptz",
+		"camera": {
+			"stream": "rtsp://{{cameraAddress}}/main",
+			"presets": [
+				{"displayName": "Podium", "setPreset": "http://{{cameraAddress}}/preset/1"},
+				{"displayName": "Board", "setPreset": "http://{{cameraAddress}}/preset/2"}
+			]
+		}
+	}`))
+	uiDB.ExpectPut().WithDocID("ITB-1101").WillExecute(func(ctx context.Context, id string, doc interface{}, opts map[string]interface{}) (string, error) {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+
+		return "2-abc", json.Unmarshal(b, &saved)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	err = cs.(pcconfig.CameraPresetService).SetCameraPreset(ctx, "ITB-1101", "Group 1", "Front", 1, pcconfig.CameraPreset{
+		DisplayName: "Whiteboard",
+		SetPreset:   "http://10.0.0.5/preset/2",
+	})
+	if err != nil {
+		t.Fatalf("unable to set camera preset: %s", err)
+	}
+
+	// only the new name is stored, so the rest of the presets keep following the template
+	expected := []pcconfig.CameraPreset{
+		{},
+		{DisplayName: "Whiteboard"},
+	}
+
+	if diff := cmp.Diff(expected, saved.ControlGroups[0].Cameras[0].Presets); diff != "" {
+		t.Errorf("saved incorrect presets (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,232 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+type changeSet struct {
+	Effective time.Time            `json:"effective"`
+	Rooms     []pcconfig.Room      `json:"rooms,omitempty"`
+	Mappings  []pcconfig.PCMapping `json:"mappings,omitempty"`
+	Canary    *canary              `json:"canary,omitempty"`
+	Applied   bool                 `json:"applied"`
+	AppliedAt *time.Time           `json:"appliedAt,omitempty"`
+
+	// CanaryFetches are kept outside of Canary so that they aren't lost when the change set is replaced.
+	CanaryFetches map[string]time.Time `json:"canaryFetches,omitempty"`
+}
+
+type canary struct {
+	Hostnames []string `json:"hostnames,omitempty"`
+	Percent   int      `json:"percent,omitempty"`
+}
+
+func (cs changeSet) toChangeSet(id string) pcconfig.ChangeSet {
+	set := pcconfig.ChangeSet{
+		ID:        id,
+		Effective: cs.Effective,
+		Rooms:     cs.Rooms,
+		Mappings:  cs.Mappings,
+		Applied:   cs.Applied,
+		AppliedAt: cs.AppliedAt,
+	}
+
+	if cs.Canary != nil {
+		set.Canary = &pcconfig.Canary{
+			Hostnames: cs.Canary.Hostnames,
+			Percent:   cs.Canary.Percent,
+			Fetched:   cs.CanaryFetches,
+		}
+	}
+
+	return set
+}
+
+func (c *configService) ChangeSets(ctx context.Context) ([]pcconfig.ChangeSet, error) {
+	sets := []pcconfig.ChangeSet{}
+
+	err := c.allDocs(ctx, c.scheduleDB, func(id string, rows *kivik.Rows) error {
+		var doc changeSet
+		if err := rows.ScanDoc(&doc); err != nil {
+			return fmt.Errorf("unable to scan change set %q: %w", id, err)
+		}
+
+		sets = append(sets, doc.toChangeSet(id))
+		return nil
+	})
+	if err != nil && kivik.StatusCode(err) != http.StatusNotFound {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+func (c *configService) PutChangeSet(ctx context.Context, cs pcconfig.ChangeSet) error {
+	doc := changeSet{
+		Effective: cs.Effective,
+		Rooms:     cs.Rooms,
+		Mappings:  cs.Mappings,
+		Applied:   cs.Applied,
+		AppliedAt: cs.AppliedAt,
+	}
+
+	if cs.Canary != nil {
+		doc.Canary = &canary{
+			Hostnames: cs.Canary.Hostnames,
+			Percent:   cs.Canary.Percent,
+		}
+	}
+
+	updated, err := toMap(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.updateDoc(ctx, c.scheduleDB, cs.ID, true, func(doc map[string]interface{}) error {
+		for k := range doc {
+			if k != "_id" && k != "_rev" && k != "canaryFetches" {
+				delete(doc, k)
+			}
+		}
+
+		for k, v := range updated {
+			doc[k] = v
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func (c *configService) DeleteChangeSet(ctx context.Context, id string) error {
+	db := c.client.DB(ctx, c.scheduleDB)
+
+	_, rev, err := db.GetMeta(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to get change set rev: %w", err)
+	}
+
+	if _, err := db.Delete(ctx, id, rev); err != nil {
+		return fmt.Errorf("unable to delete change set: %w", err)
+	}
+
+	return nil
+}
+
+func (c *configService) RecordCanaryFetch(ctx context.Context, id, hostname string, at time.Time) error {
+	_, err := c.updateDoc(ctx, c.scheduleDB, id, false, func(doc map[string]interface{}) error {
+		fetches, ok := doc["canaryFetches"].(map[string]interface{})
+		if !ok {
+			fetches = make(map[string]interface{})
+		}
+
+		if _, ok := fetches[hostname]; !ok {
+			fetches[hostname] = at.UTC().Format(time.RFC3339)
+		}
+
+		doc["canaryFetches"] = fetches
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to record canary fetch: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyChangeSet writes each room and mapping in the change set to the datastore, and then marks it
+// as applied. Applying a change set more than once has the same result, so if it fails part way
+// through it can just be applied again.
+func (c *configService) ApplyChangeSet(ctx context.Context, id string) error {
+	var set changeSet
+	if err := c.client.DB(ctx, c.scheduleDB).Get(ctx, id).ScanDoc(&set); err != nil {
+		return fmt.Errorf("unable to get/scan change set: %w", err)
+	}
+
+	for _, room := range set.Rooms {
+		if err := c.updateUIConfig(ctx, room.ID, func(doc map[string]interface{}) error {
+			return replaceControlGroups(doc, room.ControlGroups)
+		}); err != nil {
+			return fmt.Errorf("unable to update room %q: %w", room.ID, err)
+		}
+	}
+
+	for _, m := range set.Mappings {
+		m := m
+		if _, err := c.updateDoc(ctx, c.pcMappingDB, m.Hostname, true, func(doc map[string]interface{}) error {
+			doc["uiConfig"] = m.Room
+			doc["controlGroup"] = m.ControlGroup
+			delete(doc, "overrides")
+
+			if m.Overrides != nil {
+				overrides, err := toMap(m.Overrides)
+				if err != nil {
+					return err
+				}
+
+				doc["overrides"] = overrides
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to update pc mapping %q: %w", m.Hostname, err)
+		}
+	}
+
+	_, err := c.updateDoc(ctx, c.scheduleDB, id, false, func(doc map[string]interface{}) error {
+		doc["applied"] = true
+		doc["appliedAt"] = time.Now().UTC().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to mark change set applied: %w", err)
+	}
+
+	return nil
+}
+
+// replaceControlGroups replaces the cameras in each of the control groups in a raw ui config
+// doc with the ones in cgs, adding control groups that don't exist yet.
+func replaceControlGroups(doc map[string]interface{}, cgs []pcconfig.ControlGroup) error {
+	groups, _ := doc["presets"].([]interface{})
+
+	for _, cg := range cgs {
+		b, err := json.Marshal(cg.Cameras)
+		if err != nil {
+			return fmt.Errorf("unable to marshal cameras: %w", err)
+		}
+
+		var cameras []interface{}
+		if err := json.Unmarshal(b, &cameras); err != nil {
+			return fmt.Errorf("unable to unmarshal cameras: %w", err)
+		}
+
+		found := false
+		for _, g := range groups {
+			group, ok := g.(map[string]interface{})
+			if ok && group["name"] == cg.Name {
+				group["cameras"] = cameras
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			groups = append(groups, map[string]interface{}{
+				"name":    cg.Name,
+				"cameras": cameras,
+			})
+		}
+	}
+
+	doc["presets"] = groups
+	return nil
+}
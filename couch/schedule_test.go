@@ -0,0 +1,125 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+const mockChangeSetDoc = `{
+	"_id": "fall",
+	"_rev": "1-abc",
+	"effective": "2026-08-24T06:00:00Z",
+	"rooms": [
+		{
+			"id": "ITB-1101",
+			"controlGroups": [
+				{"name": "Camera", "cameras": [{"displayName": "new cam", "stream": "https://new-stream"}]},
+				{"name": "New Group", "cameras": []}
+			]
+		}
+	],
+	"mappings": [
+		{"hostname": "ITB-1101-CP9", "room": "ITB-1101", "controlGroup": "New Group"}
+	],
+	"applied": false
+}`
+
+func TestApplyChangeSet(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	saved := make(map[string]map[string]interface{})
+	save := func(ctx context.Context, id string, doc interface{}, opts map[string]interface{}) (string, error) {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return "", err
+		}
+
+		var m map[string]interface{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return "", err
+		}
+
+		saved[id] = m
+		return "2-abc", nil
+	}
+
+	scheduleDB := mock.NewDB()
+	uiDB := mock.NewDB()
+	mappingDB := mock.NewDB()
+
+	mock.ExpectDB().WithName(_defaultScheduleDB).WillReturn(scheduleDB)
+	scheduleDB.ExpectGet().WithDocID("fall").WillReturn(kivikmock.DocumentT(t, mockChangeSetDoc))
+
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(uiDB)
+	uiDB.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockUIConfigDoc))
+	uiDB.ExpectPut().WithDocID("ITB-1101").WillExecute(save)
+
+	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(mappingDB)
+	mappingDB.ExpectGet().WithDocID("ITB-1101-CP9").WillReturnError(&kivik.Error{HTTPStatus: http.StatusNotFound, Err: errors.New("missing")})
+	mappingDB.ExpectPut().WithDocID("ITB-1101-CP9").WillExecute(save)
+
+	mock.ExpectDB().WithName(_defaultScheduleDB).WillReturn(scheduleDB)
+	scheduleDB.ExpectGet().WithDocID("fall").WillReturn(kivikmock.DocumentT(t, mockChangeSetDoc))
+	scheduleDB.ExpectPut().WithDocID("fall").WillExecute(save)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if err := cs.(pcconfig.ScheduleService).ApplyChangeSet(ctx, "fall"); err != nil {
+		t.Fatalf("unable to apply change set: %s", err)
+	}
+
+	room, err := DecodeRoom("ITB-1101", mustMarshal(t, saved["ITB-1101"]))
+	if err != nil {
+		t.Fatalf("unable to decode saved room: %s", err)
+	}
+
+	expected := pcconfig.Room{
+		ID: "ITB-1101",
+		ControlGroups: []pcconfig.ControlGroup{
+			{Name: "Camera", Cameras: []pcconfig.Camera{{DisplayName: "new cam", Stream: "https://new-stream"}}},
+			{Name: "New Group", Cameras: []pcconfig.Camera{}},
+		},
+	}
+
+	if diff := cmp.Diff(expected, room); diff != "" {
+		t.Errorf("saved incorrect room (-want, +got):\n%s", diff)
+	}
+
+	// fields pc-config doesn't know about are kept
+	if saved["ITB-1101"]["presets"].([]interface{})[0].(map[string]interface{})["icon"] != "tv" {
+		t.Errorf("expected unknown fields to be kept, got %v", saved["ITB-1101"])
+	}
+
+	switch {
+	case saved["ITB-1101-CP9"]["uiConfig"] != "ITB-1101" || saved["ITB-1101-CP9"]["controlGroup"] != "New Group":
+		t.Errorf("saved incorrect mapping: %v", saved["ITB-1101-CP9"])
+	case saved["fall"]["applied"] != true:
+		t.Errorf("expected change set to be marked applied, got %v", saved["fall"])
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unable to marshal: %s", err)
+	}
+
+	return b
+}
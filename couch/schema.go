@@ -0,0 +1,195 @@
+package couch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/byuoitav/pc-config/jsonschema"
+	"go.uber.org/zap"
+)
+
+// ValidationMode is what happens to documents that don't match their schema when they are read.
+type ValidationMode int
+
+const (
+	// ValidationOff doesn't validate documents.
+	ValidationOff ValidationMode = iota
+
+	// ValidationLenient logs the problems with documents and skips cameras that are invalid.
+	ValidationLenient
+
+	// ValidationStrict returns an error for documents that are invalid.
+	ValidationStrict
+)
+
+// ParseValidationMode parses off, lenient, or strict.
+func ParseValidationMode(s string) (ValidationMode, error) {
+	switch s {
+	case "off", "":
+		return ValidationOff, nil
+	case "lenient":
+		return ValidationLenient, nil
+	case "strict":
+		return ValidationStrict, nil
+	}
+
+	return ValidationOff, fmt.Errorf("unknown validation mode %q", s)
+}
+
+var (
+	_pcMappingSchema = jsonschema.ForDecoding(pcMapping{})
+	_uiConfigSchema  = jsonschema.ForDecoding(uiConfig{})
+
+	// _cameraPathRegex matches the path of a camera in a ui config doc, and anything in it.
+	_cameraPathRegex = regexp.MustCompile(`^presets\[(\d+)\]\.cameras\[(\d+)\]`)
+)
+
+// Schemas returns the JSON Schema of the documents in each database, by the database's default name.
+func Schemas() map[string]*jsonschema.Schema {
+	return map[string]*jsonschema.Schema{
+		_defaultPCMappingDB: _pcMappingSchema,
+		_defaultUIConfigDB:  _uiConfigSchema,
+	}
+}
+
+// InvalidDocumentError is returned when strict validation is on and a document doesn't match its schema.
+type InvalidDocumentError struct {
+	DB     string
+	ID     string
+	Errors []jsonschema.ValidationError
+}
+
+func (e *InvalidDocumentError) Error() string {
+	msg := fmt.Sprintf("%s/%s doesn't match its schema: %s", e.DB, e.ID, e.Errors[0])
+	if len(e.Errors) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Errors)-1)
+	}
+
+	return msg
+}
+
+// decodePCMapping validates and decodes the pc mapping id.
+func (c *configService) decodePCMapping(id string, data json.RawMessage) (pcMapping, error) {
+	var mapping pcMapping
+
+	errs, err := c.validate(_pcMappingSchema, c.pcMappingDB, id, data)
+	if err != nil {
+		return mapping, err
+	}
+
+	if len(errs) > 0 {
+		c.log.Warn("invalid pc mapping", zap.String("id", id), zap.Errors("problems", toErrors(errs)))
+	}
+
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return mapping, fmt.Errorf("unable to decode: %w", err)
+	}
+
+	return mapping, nil
+}
+
+// decodeUIConfig validates and decodes the ui config id. With lenient validation, invalid cameras are removed.
+func (c *configService) decodeUIConfig(id string, data json.RawMessage) (uiConfig, error) {
+	var config uiConfig
+
+	errs, err := c.validate(_uiConfigSchema, c.uiConfigDB, id, data)
+	if err != nil {
+		return config, err
+	}
+
+	if len(errs) > 0 {
+		c.log.Warn("invalid ui config", zap.String("id", id), zap.Errors("problems", toErrors(errs)))
+
+		if data, err = withoutInvalidCameras(data, errs); err != nil {
+			return config, fmt.Errorf("unable to remove invalid cameras: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("unable to decode: %w", err)
+	}
+
+	return config, nil
+}
+
+// validate validates data against schema. In strict mode, an InvalidDocumentError is returned if
+// there are any problems; otherwise, the problems are returned.
+func (c *configService) validate(schema *jsonschema.Schema, db, id string, data json.RawMessage) ([]jsonschema.ValidationError, error) {
+	if c.validation == ValidationOff {
+		return nil, nil
+	}
+
+	errs, err := schema.ValidateJSON(data)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("unable to validate: %w", err)
+	case len(errs) > 0 && c.validation == ValidationStrict:
+		return nil, &InvalidDocumentError{DB: db, ID: id, Errors: errs}
+	}
+
+	return errs, nil
+}
+
+// withoutInvalidCameras removes each camera from the ui config doc data that has one of errs.
+func withoutInvalidCameras(data json.RawMessage, errs []jsonschema.ValidationError) (json.RawMessage, error) {
+	invalid := make(map[int][]int)
+	for _, e := range errs {
+		m := _cameraPathRegex.FindStringSubmatch(e.Path)
+		if m == nil {
+			continue
+		}
+
+		cg, _ := strconv.Atoi(m[1])
+		cam, _ := strconv.Atoi(m[2])
+		invalid[cg] = append(invalid[cg], cam)
+	}
+
+	if len(invalid) == 0 {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("unable to decode: %w", err)
+	}
+
+	groups, _ := doc["presets"].([]interface{})
+	for i, cams := range invalid {
+		group, ok := groups[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		cameras, _ := group["cameras"].([]interface{})
+
+		// remove from the end so that the indexes of the rest don't change
+		sort.Sort(sort.Reverse(sort.IntSlice(cams)))
+		for j, cam := range cams {
+			if j > 0 && cam == cams[j-1] {
+				continue
+			}
+
+			cameras = append(cameras[:cam], cameras[cam+1:]...)
+		}
+
+		group["cameras"] = cameras
+	}
+
+	return json.Marshal(doc)
+}
+
+func toErrors(errs []jsonschema.ValidationError) []error {
+	converted := make([]error, len(errs))
+	for i := range errs {
+		converted[i] = errs[i]
+	}
+
+	return converted
+}
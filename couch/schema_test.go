@@ -0,0 +1,85 @@
+package couch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const mockInvalidUIConfigDoc = `{
+	"_id": "ITB-1101",
+	"presets": [
+		{
+			"name": "Camera",
+			"cameras": [
+				{"displayName": "good cam", "stream": "https://good-stream", "presets": []},
+				{"displayName": "bad cam", "stream": 5, "presets": []},
+				{"stream": "https://nameless-stream", "presets": []}
+			]
+		}
+	]
+}`
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     ValidationMode
+		expected []pcconfig.Camera
+		invalid  bool
+	}{
+		{
+			name:    "Strict",
+			mode:    ValidationStrict,
+			invalid: true,
+		},
+		{
+			name: "Lenient",
+			mode: ValidationLenient,
+			expected: []pcconfig.Camera{
+				{DisplayName: "good cam", Stream: "https://good-stream", Presets: []pcconfig.CameraPreset{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := kivikmock.NewT(t)
+
+			db := mock.NewDB()
+			mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+			db.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, mockInvalidUIConfigDoc))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			cs, err := NewWithClient(ctx, client, WithValidation(tt.mode))
+			if err != nil {
+				t.Fatalf("unable to create config service: %s", err)
+			}
+
+			cams, err := cs.Cameras(ctx, "ITB-1101", "Camera")
+
+			var invalid *InvalidDocumentError
+			switch {
+			case tt.invalid && !errors.As(err, &invalid):
+				t.Fatalf("expected an InvalidDocumentError, got %v", err)
+			case tt.invalid:
+				if len(invalid.Errors) != 2 {
+					t.Errorf("expected 2 problems, got %v", invalid.Errors)
+				}
+			case err != nil:
+				t.Fatalf("unable to get cameras: %s", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, cams, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("got incorrect cameras (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package couch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+// SnapshotVersion is the version of the snapshot format written by WriteSnapshot. ReadSnapshot
+// only reads snapshots of this version.
+const SnapshotVersion = 1
+
+// Snapshot is a copy of the documents pc-config reads from CouchDB, so that it can run without CouchDB.
+// Each map is from a doc's id to the doc.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	PCMappings map[string]json.RawMessage `json:"pcMappings"`
+	UIConfigs  map[string]json.RawMessage `json:"uiConfigs"`
+	Templates  map[string]json.RawMessage `json:"templates"`
+}
+
+// Snapshot copies every pc mapping, ui config, and camera template.
+func (c *configService) Snapshot(ctx context.Context) (*Snapshot, error) {
+	snap := &Snapshot{
+		Version:    SnapshotVersion,
+		CreatedAt:  time.Now().UTC(),
+		PCMappings: make(map[string]json.RawMessage),
+		UIConfigs:  make(map[string]json.RawMessage),
+		Templates:  make(map[string]json.RawMessage),
+	}
+
+	copyDocs := func(db string, docs map[string]json.RawMessage) func(string, *kivik.Rows) error {
+		return func(id string, rows *kivik.Rows) error {
+			var raw json.RawMessage
+			if err := rows.ScanDoc(&raw); err != nil {
+				return fmt.Errorf("unable to scan %s doc %q: %w", db, id, err)
+			}
+
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return fmt.Errorf("unable to compact %s doc %q: %w", db, id, err)
+			}
+
+			docs[id] = compact.Bytes()
+			return nil
+		}
+	}
+
+	if err := c.allDocs(ctx, c.pcMappingDB, copyDocs(c.pcMappingDB, snap.PCMappings)); err != nil {
+		return nil, fmt.Errorf("unable to copy pc mappings: %w", err)
+	}
+
+	if err := c.allDocs(ctx, c.uiConfigDB, copyDocs(c.uiConfigDB, snap.UIConfigs)); err != nil {
+		return nil, fmt.Errorf("unable to copy ui configs: %w", err)
+	}
+
+	// like Templates, a missing template database means there aren't any
+	err := c.allDocs(ctx, c.templateDB, copyDocs(c.templateDB, snap.Templates))
+	if err != nil && kivik.StatusCode(err) != http.StatusNotFound {
+		return nil, fmt.Errorf("unable to copy templates: %w", err)
+	}
+
+	return snap, nil
+}
+
+// WriteSnapshot writes snap to w as gzipped JSON.
+func WriteSnapshot(w io.Writer, snap *Snapshot) error {
+	gz := gzip.NewWriter(w)
+
+	if err := json.NewEncoder(gz).Encode(snap); err != nil {
+		return fmt.Errorf("unable to encode snapshot: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("unable to compress snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	var snap Snapshot
+	if err := json.NewDecoder(gz).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("unable to decode snapshot: %w", err)
+	}
+
+	if snap.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d: only version %d is supported", snap.Version, SnapshotVersion)
+	}
+
+	return &snap, nil
+}
+
+// snapshotService is a read only ConfigService backed by a Snapshot.
+type snapshotService struct {
+	snap *Snapshot
+
+	// decoder decodes and validates docs; it never makes requests
+	decoder   *configService
+	templates map[string]pcconfig.Camera
+}
+
+// NewFromSnapshot creates a read only ConfigService that serves the config in snap. Only the
+// validation and logging options are used.
+func NewFromSnapshot(snap *Snapshot, opts ...Option) (pcconfig.ConfigService, error) {
+	options := newOptions(opts)
+
+	s := &snapshotService{
+		snap: snap,
+		decoder: &configService{
+			uiConfigDB:  options.uiConfigDB,
+			pcMappingDB: options.pcMappingDB,
+			templateDB:  options.templateDB,
+			validation:  options.validation,
+			log:         options.log,
+		},
+		templates: make(map[string]pcconfig.Camera, len(snap.Templates)),
+	}
+
+	for id, raw := range snap.Templates {
+		tmpl, err := DecodeTemplate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode template %q: %w", id, err)
+		}
+
+		s.templates[id] = tmpl
+	}
+
+	return s, nil
+}
+
+func (s *snapshotService) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
+	mapping, _, err := s.mapping(hostname)
+	if err != nil {
+		return "", "", err
+	}
+
+	return mapping.UIConfig, mapping.ControlGroup, nil
+}
+
+func (s *snapshotService) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	mapping, id, err := s.mapping(hostname)
+	if err != nil {
+		return pcconfig.PCOverrides{}, "", err
+	}
+
+	return mapping.Overrides, id, nil
+}
+
+// mapping gets the longest pc mapping that hostname starts with, and its id.
+func (s *snapshotService) mapping(hostname string) (pcMapping, string, error) {
+	for _, id := range pcconfig.MappingCandidates(hostname) {
+		raw, ok := s.snap.PCMappings[id]
+		if !ok {
+			continue
+		}
+
+		mapping, err := s.decoder.decodePCMapping(id, raw)
+		if err != nil {
+			return pcMapping{}, "", fmt.Errorf("unable to get/scan pc mapping: %w", err)
+		}
+
+		return mapping, id, nil
+	}
+
+	return pcMapping{}, "", fmt.Errorf("unable to get/scan pc mapping: %w", &kivik.Error{
+		HTTPStatus: http.StatusNotFound,
+		Message:    fmt.Sprintf("no pc mapping for %q", hostname),
+	})
+}
+
+func (s *snapshotService) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
+	r, err := s.Room(ctx, room)
+	if err != nil {
+		return []pcconfig.Camera{}, err
+	}
+
+	for _, cg := range r.ControlGroups {
+		if cg.Name == controlGroup {
+			return cg.Cameras, nil
+		}
+	}
+
+	return []pcconfig.Camera{}, errors.New("no matching control group found")
+}
+
+// Room returns every control group in room, with their templates resolved.
+func (s *snapshotService) Room(ctx context.Context, room string) (pcconfig.Room, error) {
+	raw, ok := s.snap.UIConfigs[room]
+	if !ok {
+		return pcconfig.Room{}, fmt.Errorf("unable to get/scan ui config: %w", &kivik.Error{
+			HTTPStatus: http.StatusNotFound,
+			Message:    fmt.Sprintf("no ui config for %q", room),
+		})
+	}
+
+	config, err := s.decoder.decodeUIConfig(room, raw)
+	if err != nil {
+		return pcconfig.Room{}, fmt.Errorf("unable to get/scan ui config: %w", err)
+	}
+
+	return resolveRoom(config.toRoom(room), s.templates)
+}
+
+func (s *snapshotService) PCMappings(ctx context.Context) ([]pcconfig.PCMapping, error) {
+	mappings := make([]pcconfig.PCMapping, 0, len(s.snap.PCMappings))
+
+	for _, id := range sortedIDs(s.snap.PCMappings) {
+		mapping, err := s.decoder.decodePCMapping(id, s.snap.PCMappings[id])
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan pc mapping %q: %w", id, err)
+		}
+
+		mappings = append(mappings, mapping.toPCMapping(id))
+	}
+
+	return mappings, nil
+}
+
+func (s *snapshotService) Rooms(ctx context.Context) ([]pcconfig.Room, error) {
+	rooms := make([]pcconfig.Room, 0, len(s.snap.UIConfigs))
+
+	for _, id := range sortedIDs(s.snap.UIConfigs) {
+		config, err := s.decoder.decodeUIConfig(id, s.snap.UIConfigs[id])
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan ui config %q: %w", id, err)
+		}
+
+		// cameras that can't be resolved are left for validation to report
+		room, _ := pcconfig.ResolveRoomTemplates(config.toRoom(id), s.templates)
+		rooms = append(rooms, room)
+	}
+
+	return rooms, nil
+}
+
+// Templates returns every camera template, keyed by name.
+func (s *snapshotService) Templates(ctx context.Context) (map[string]pcconfig.Camera, error) {
+	templates := make(map[string]pcconfig.Camera, len(s.templates))
+	for id, tmpl := range s.templates {
+		templates[id] = tmpl
+	}
+
+	return templates, nil
+}
+
+func (s *snapshotService) PCMappingsFor(ctx context.Context, hostnames []string) (map[string]pcconfig.PCMapping, error) {
+	found := make(map[string]pcconfig.PCMapping)
+
+	for _, hostname := range hostnames {
+		mapping, id, err := s.mapping(hostname)
+		if err != nil {
+			continue
+		}
+
+		found[hostname] = mapping.toPCMapping(id)
+	}
+
+	return found, nil
+}
+
+func (s *snapshotService) RoomsFor(ctx context.Context, rooms []string) (map[string]pcconfig.Room, error) {
+	found := make(map[string]pcconfig.Room)
+
+	for _, id := range rooms {
+		room, err := s.Room(ctx, id)
+		if err != nil {
+			continue
+		}
+
+		found[id] = room
+	}
+
+	return found, nil
+}
+
+func sortedIDs(docs map[string]json.RawMessage) []string {
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,136 @@
+package couch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSnapshot(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	mappingDB := mock.NewDB()
+	uiDB := mock.NewDB()
+	templateDB := mock.NewDB()
+
+	mock.ExpectDB().WithName(_defaultPCMappingDB).WillReturn(mappingDB)
+	mappingDB.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ITB-1101-CP", Doc: []byte(`{"_id": "ITB-1101-CP", "uiConfig": "ITB-1101", "controlGroup": "Group 1", "overrides": {"cameras": [{"camera": "Back", "hide": true}]}}`)}).
+		AddRow(&driver.Row{ID: "_design/views", Doc: []byte(`{"_id": "_design/views", "views": {}}`)}))
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(uiDB)
+	uiDB.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ITB-1101", Doc: []byte(`{
+			"_id": "ITB-1101",
+			"presets": [{"name": "Group 1", "cameras": [{"displayName": "Front", "template": "ptz", "address": "10.0.0.5"}, {"displayName": "Back"}]}]
+		}`)}))
+	mock.ExpectDB().WithName(_defaultTemplateDB).WillReturn(templateDB)
+	templateDB.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ptz", Doc: []byte(mockTemplateDoc)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	snap, err := cs.(*configService).Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to take snapshot: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, snap); err != nil {
+		t.Fatalf("unable to write snapshot: %s", err)
+	}
+
+	read, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("unable to read snapshot: %s", err)
+	}
+
+	if diff := cmp.Diff(snap, read); diff != "" {
+		t.Fatalf("snapshot changed after writing it (-want, +got):\n%s", diff)
+	}
+
+	served, err := NewFromSnapshot(read)
+	if err != nil {
+		t.Fatalf("unable to create config service from snapshot: %s", err)
+	}
+
+	room, cg, err := served.RoomAndControlGroup(ctx, "ITB-1101-CP1")
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get room and control group: %s", err)
+	case room != "ITB-1101" || cg != "Group 1":
+		t.Fatalf("got wrong room and control group: %q, %q", room, cg)
+	}
+
+	overrides, id, err := served.(pcconfig.OverrideService).Overrides(ctx, "ITB-1101-CP1")
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get overrides: %s", err)
+	case id != "ITB-1101-CP" || len(overrides.Cameras) != 1:
+		t.Fatalf("got wrong overrides from %q: %+v", id, overrides)
+	}
+
+	cams, err := served.Cameras(ctx, room, cg)
+	if err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	expected := []pcconfig.Camera{
+		{
+			DisplayName: "Front",
+			PanTiltStop: "http://control/ITB-1101/10.0.0.5/stop",
+			Stream:      "rtsp://10.0.0.5/main",
+		},
+		{DisplayName: "Back"},
+	}
+
+	if diff := cmp.Diff(expected, cams); diff != "" {
+		t.Errorf("got wrong cameras (-want, +got):\n%s", diff)
+	}
+
+	mappings, err := served.(pcconfig.ConfigLister).PCMappings(ctx)
+	switch {
+	case err != nil:
+		t.Fatalf("unable to list pc mappings: %s", err)
+	case len(mappings) != 1:
+		t.Fatalf("expected 1 pc mapping, got %d", len(mappings))
+	}
+
+	if _, _, err := served.RoomAndControlGroup(ctx, "JFSB-CP1"); kivik.StatusCode(err) != http.StatusNotFound {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestReadSnapshotVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, &Snapshot{Version: SnapshotVersion + 1}); err != nil {
+		t.Fatalf("unable to write snapshot: %s", err)
+	}
+
+	if _, err := ReadSnapshot(&buf); err == nil {
+		t.Fatalf("expected an error for a newer snapshot version")
+	}
+
+	buf.Reset()
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(`not json`))
+	gz.Close()
+
+	if _, err := ReadSnapshot(&buf); err == nil {
+		t.Fatalf("expected an error for an invalid snapshot")
+	}
+}
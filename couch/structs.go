@@ -3,8 +3,10 @@ package couch
 import pcconfig "github.com/byuoitav/pc-config"
 
 type pcMapping struct {
-	UIConfig     string `json:"uiConfig"`
-	ControlGroup string `json:"controlGroup"`
+	UIConfig     string `json:"uiConfig" jsonschema:"required"`
+	ControlGroup string `json:"controlGroup" jsonschema:"required"`
+
+	Overrides pcconfig.PCOverrides `json:"overrides"`
 }
 
 type uiConfig struct {
@@ -13,3 +15,38 @@ type uiConfig struct {
 		Cameras []pcconfig.Camera `json:"cameras"`
 	} `json:"presets"`
 }
+
+type cameraTemplate struct {
+	Camera pcconfig.Camera `json:"camera"`
+}
+
+func (m pcMapping) toPCMapping(hostname string) pcconfig.PCMapping {
+	mapping := pcconfig.PCMapping{
+		Hostname:     hostname,
+		Room:         m.UIConfig,
+		ControlGroup: m.ControlGroup,
+	}
+
+	if !m.Overrides.Empty() {
+		overrides := m.Overrides
+		mapping.Overrides = &overrides
+	}
+
+	return mapping
+}
+
+func (u uiConfig) toRoom(id string) pcconfig.Room {
+	room := pcconfig.Room{
+		ID:            id,
+		ControlGroups: make([]pcconfig.ControlGroup, 0, len(u.ControlGroups)),
+	}
+
+	for _, cg := range u.ControlGroups {
+		room.ControlGroups = append(room.ControlGroups, pcconfig.ControlGroup{
+			Name:    cg.ID,
+			Cameras: cg.Cameras,
+		})
+	}
+
+	return room
+}
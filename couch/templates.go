@@ -0,0 +1,78 @@
+package couch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+)
+
+// resolve resolves the templates of every camera in room, getting only the templates that are used.
+func (c *configService) resolve(ctx context.Context, room pcconfig.Room) (pcconfig.Room, error) {
+	names := pcconfig.TemplateNames(room)
+	if len(names) == 0 {
+		return room, nil
+	}
+
+	db := c.client.DB(ctx, c.templateDB)
+	templates := make(map[string]pcconfig.Camera, len(names))
+
+	for _, name := range names {
+		var doc cameraTemplate
+		if err := db.Get(ctx, name).ScanDoc(&doc); err != nil {
+			return pcconfig.Room{}, fmt.Errorf("unable to get/scan template %q: %w", name, err)
+		}
+
+		templates[name] = doc.Camera
+	}
+
+	return resolveRoom(room, templates)
+}
+
+// resolveRoom resolves the templates of every camera in room. A template that doesn't exist is reported like a missing doc.
+func resolveRoom(room pcconfig.Room, templates map[string]pcconfig.Camera) (pcconfig.Room, error) {
+	resolved, err := pcconfig.ResolveRoomTemplates(room, templates)
+	switch {
+	case errors.Is(err, pcconfig.ErrTemplateNotFound):
+		return pcconfig.Room{}, fmt.Errorf("%v: %w", err, &kivik.Error{HTTPStatus: http.StatusNotFound, Message: "missing"})
+	case err != nil:
+		return pcconfig.Room{}, err
+	}
+
+	return resolved, nil
+}
+
+// Templates returns every camera template, keyed by name. If the template
+// database doesn't exist, there aren't any templates.
+func (c *configService) Templates(ctx context.Context) (map[string]pcconfig.Camera, error) {
+	templates := make(map[string]pcconfig.Camera)
+
+	err := c.allDocs(ctx, c.templateDB, func(id string, rows *kivik.Rows) error {
+		var doc cameraTemplate
+		if err := rows.ScanDoc(&doc); err != nil {
+			return fmt.Errorf("unable to scan template %q: %w", id, err)
+		}
+
+		templates[id] = doc.Camera
+		return nil
+	})
+	if err != nil && kivik.StatusCode(err) != http.StatusNotFound {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// DecodeTemplate decodes a camera template document.
+func DecodeTemplate(data []byte) (pcconfig.Camera, error) {
+	var doc cameraTemplate
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return pcconfig.Camera{}, fmt.Errorf("unable to decode template: %w", err)
+	}
+
+	return doc.Camera, nil
+}
@@ -0,0 +1,146 @@
+package couch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+const mockTemplateUIConfigDoc = `{
+	"_id": "ITB-1101",
+	"presets": [
+		{
+			"name": "Group 1",
+			"cameras": [
+				{"displayName": "Front", "template": "ptz", "address": "10.0.0.5"},
+				{"displayName": "Back", "template": "ptz"},
+				{"displayName": "Side", "template": "missing"}
+			]
+		}
+	]
+}`
+
+const mockTemplateDoc = `{
+	"_id": "ptz",
+	"camera": {
+		"panTiltStop": "http://control/{{room}}/{{cameraAddress}}/stop",
+		"stream": "rtsp://{{cameraAddress}}/main"
+	}
+}`
+
+func TestCamerasTemplate(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	uiDB := mock.NewDB()
+	templateDB := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(uiDB)
+	uiDB.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, `{
+		"_id": "ITB-1101",
+		"presets": [{"name": "Group 1", "cameras": [{"displayName": "Front", "template": "ptz", "address": "10.0.0.5"}]}]
+	}`))
+	mock.ExpectDB().WithName(_defaultTemplateDB).WillReturn(templateDB)
+	templateDB.ExpectGet().WithDocID("ptz").WillReturn(kivikmock.DocumentT(t, mockTemplateDoc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	cameras, err := cs.Cameras(ctx, "ITB-1101", "Group 1")
+	if err != nil {
+		t.Fatalf("failed to get cameras: %s", err)
+	}
+
+	expected := []pcconfig.Camera{
+		{
+			DisplayName: "Front",
+			PanTiltStop: "http://control/ITB-1101/10.0.0.5/stop",
+			Stream:      "rtsp://10.0.0.5/main",
+		},
+	}
+
+	if diff := cmp.Diff(expected, cameras); diff != "" {
+		t.Errorf("resolved template incorrectly (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCamerasTemplateUnbound(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	uiDB := mock.NewDB()
+	templateDB := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(uiDB)
+	uiDB.ExpectGet().WithDocID("ITB-1101").WillReturn(kivikmock.DocumentT(t, `{
+		"_id": "ITB-1101",
+		"presets": [{"name": "Group 1", "cameras": [{"displayName": "Back", "template": "ptz"}]}]
+	}`))
+	mock.ExpectDB().WithName(_defaultTemplateDB).WillReturn(templateDB)
+	templateDB.ExpectGet().WithDocID("ptz").WillReturn(kivikmock.DocumentT(t, mockTemplateDoc))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	var unbound pcconfig.UnboundVariablesError
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Group 1"); !errors.As(err, &unbound) {
+		t.Fatalf("expected an UnboundVariablesError, got %v", err)
+	}
+}
+
+func TestRoomsTemplates(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	uiDB := mock.NewDB()
+	templateDB := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(uiDB)
+	uiDB.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ITB-1101", Doc: []byte(mockTemplateUIConfigDoc)}))
+	mock.ExpectDB().WithName(_defaultTemplateDB).WillReturn(templateDB)
+	templateDB.ExpectAllDocs().WillReturn(kivikmock.NewRows().
+		AddRow(&driver.Row{ID: "ptz", Doc: []byte(mockTemplateDoc)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	rooms, err := cs.(pcconfig.ConfigLister).Rooms(ctx)
+	if err != nil {
+		t.Fatalf("unable to get rooms: %s", err)
+	}
+
+	expected := []pcconfig.Camera{
+		{
+			DisplayName: "Front",
+			PanTiltStop: "http://control/ITB-1101/10.0.0.5/stop",
+			Stream:      "rtsp://10.0.0.5/main",
+		},
+		{
+			DisplayName: "Back",
+			Template:    "ptz",
+			PanTiltStop: "http://control/ITB-1101/{{cameraAddress}}/stop",
+			Stream:      "rtsp://{{cameraAddress}}/main",
+		},
+		{DisplayName: "Side", Template: "missing"},
+	}
+
+	if diff := cmp.Diff(expected, rooms[0].ControlGroups[0].Cameras); diff != "" {
+		t.Errorf("resolved templates incorrectly (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,60 @@
+package couch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-kivik/kivik/v3"
+)
+
+// PutThumbnail stores jpeg as an attachment on the room's ui config doc.
+func (c *configService) PutThumbnail(ctx context.Context, room, key string, jpeg []byte) error {
+	db := c.client.DB(ctx, c.uiConfigDB)
+
+	var err error
+	for i := 0; i < _maxUpdateAttempts; i++ {
+		var rev string
+
+		_, rev, err = db.GetMeta(ctx, room)
+		if err != nil {
+			return fmt.Errorf("unable to get ui config rev: %w", err)
+		}
+
+		_, err = db.PutAttachment(ctx, room, rev, &kivik.Attachment{
+			Filename:    key,
+			ContentType: "image/jpeg",
+			Content:     ioutil.NopCloser(bytes.NewReader(jpeg)),
+		})
+		switch kivik.StatusCode(err) {
+		case 0:
+			return nil
+		case http.StatusConflict:
+			// try again
+		default:
+			return fmt.Errorf("unable to put thumbnail: %w", err)
+		}
+	}
+
+	return fmt.Errorf("unable to put thumbnail: %w", err)
+}
+
+// Thumbnail gets a thumbnail attachment from the room's ui config doc.
+func (c *configService) Thumbnail(ctx context.Context, room, key string) ([]byte, error) {
+	db := c.client.DB(ctx, c.uiConfigDB)
+
+	att, err := db.GetAttachment(ctx, room, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get thumbnail: %w", err)
+	}
+	defer att.Content.Close()
+
+	jpeg, err := ioutil.ReadAll(att.Content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read thumbnail: %w", err)
+	}
+
+	return jpeg, nil
+}
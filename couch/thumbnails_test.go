@@ -0,0 +1,75 @@
+package couch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/go-kivik/kivik/v3/driver"
+	"github.com/go-kivik/kivikmock/v3"
+)
+
+var mockJPEG = []byte{0xff, 0xd8, 0xff, 0xe0}
+
+func TestPutThumbnail(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	conflict := &kivik.Error{
+		HTTPStatus: http.StatusConflict,
+		Err:        errors.New("conflict"),
+	}
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGetMeta().WillReturn(100, "1-abc")
+	db.ExpectPutAttachment().WithDocID("ITB-1101").WithRev("1-abc").WillReturnError(conflict)
+	db.ExpectGetMeta().WillReturn(100, "2-abc")
+	db.ExpectPutAttachment().WithDocID("ITB-1101").WithRev("2-abc").WillReturn("3-abc")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if err := cs.(pcconfig.ThumbnailStore).PutThumbnail(ctx, "ITB-1101", "thumbnail.jpg", mockJPEG); err != nil {
+		t.Fatalf("unable to put thumbnail: %s", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	client, mock := kivikmock.NewT(t)
+
+	db := mock.NewDB()
+	mock.ExpectDB().WithName(_defaultUIConfigDB).WillReturn(db)
+	db.ExpectGetAttachment().WithDocID("ITB-1101").WithFilename("thumbnail.jpg").WillReturn(&driver.Attachment{
+		Filename:    "thumbnail.jpg",
+		ContentType: "image/jpeg",
+		Content:     ioutil.NopCloser(bytes.NewReader(mockJPEG)),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := NewWithClient(ctx, client)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	jpeg, err := cs.(pcconfig.ThumbnailStore).Thumbnail(ctx, "ITB-1101", "thumbnail.jpg")
+	if err != nil {
+		t.Fatalf("unable to get thumbnail: %s", err)
+	}
+
+	if !bytes.Equal(jpeg, mockJPEG) {
+		t.Fatalf("got wrong thumbnail: % x", jpeg)
+	}
+}
@@ -0,0 +1,366 @@
+package couch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned for requests that aren't sent because CouchDB has been failing.
+var ErrCircuitOpen = errors.New("couchdb is unavailable: circuit breaker is open")
+
+// _maxBackoff is the longest to wait between retries.
+const _maxBackoff = 5 * time.Second
+
+// TransportConfig tunes the connections made to CouchDB. Zero values use net/http's defaults.
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+}
+
+// LoadTLSConfig builds the TLS config to connect to CouchDB with. caFile is a PEM bundle of the
+// CAs to trust instead of the system's, and certFile and keyFile are a client certificate to
+// present. Any of them can be empty.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle: %w", err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+
+	switch {
+	case certFile != "" && keyFile != "":
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	case certFile != "" || keyFile != "":
+		return nil, errors.New("both a client certificate and key are required")
+	}
+
+	return cfg, nil
+}
+
+// transport builds the http transport requests to CouchDB at url are sent with.
+func (o options) transport(url string) http.RoundTripper {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if o.tls != nil {
+		base.TLSClientConfig = o.tls.Clone()
+	}
+
+	tc := o.transportConfig
+	if tc.MaxIdleConns > 0 {
+		base.MaxIdleConns = tc.MaxIdleConns
+	}
+
+	if tc.MaxIdleConnsPerHost > 0 {
+		base.MaxIdleConnsPerHost = tc.MaxIdleConnsPerHost
+	}
+
+	if tc.MaxConnsPerHost > 0 {
+		base.MaxConnsPerHost = tc.MaxConnsPerHost
+	}
+
+	if tc.IdleConnTimeout > 0 {
+		base.IdleConnTimeout = tc.IdleConnTimeout
+	}
+
+	if tc.TLSHandshakeTimeout > 0 {
+		base.TLSHandshakeTimeout = tc.TLSHandshakeTimeout
+	}
+
+	if tc.DialTimeout > 0 {
+		dialer := &net.Dialer{Timeout: tc.DialTimeout, KeepAlive: 30 * time.Second}
+		base.DialContext = dialer.DialContext
+	}
+
+	// authentication happens on each retry, so that new credentials are used
+	var rt http.RoundTripper = base
+	if o.authTransport != nil {
+		rt = o.authTransport(base, url)
+	}
+
+	t := &resilientTransport{
+		base:     rt,
+		timeout:  o.requestTimeout,
+		retries:  o.retries,
+		backoff:  o.retryBackoff,
+		log:      o.log,
+		sleepFor: sleep,
+	}
+
+	if o.breakerFailures > 0 {
+		t.breaker = &breaker{
+			failures: o.breakerFailures,
+			cooldown: o.breakerCooldown,
+			now:      time.Now,
+		}
+	}
+
+	return t
+}
+
+// resilientTransport times out, retries, and stops sending requests to CouchDB while it is down.
+type resilientTransport struct {
+	base http.RoundTripper
+
+	// timeout is how long each attempt can take, including reading the body. Zero means no timeout.
+	timeout time.Duration
+
+	// retries is how many times reads are retried if they fail, waiting longer each time starting at backoff.
+	retries int
+	backoff time.Duration
+
+	// breaker, if set, fails requests fast while CouchDB is down.
+	breaker *breaker
+
+	log *zap.Logger
+
+	sleepFor func(ctx context.Context, d time.Duration) error
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retries := 0
+	if retryable(req) {
+		retries = t.retries
+	}
+
+	// the body has to be sent again on each retry
+	if retries > 0 && req.Body != nil && req.GetBody == nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read request body: %w", err)
+		}
+
+		// round trippers can't change the request they're given
+		req = req.Clone(req.Context())
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("unable to reset request body: %w", err)
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+		if attempt >= retries || !shouldRetry(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		if resp != nil {
+			// drain it so that the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t.log.Warn("retrying couchdb request", zap.String("method", req.Method), zap.String("path", req.URL.Path), zap.Int("attempt", attempt+1), zap.Error(retryReason(resp, err)))
+
+		if err := t.sleepFor(req.Context(), backoff(t.backoff, attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends req once, through the circuit breaker.
+func (t *resilientTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.breaker != nil {
+		if !t.breaker.allow() {
+			return nil, ErrCircuitOpen
+		}
+	}
+
+	parent := req.Context()
+
+	cancel := context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), t.timeout)
+		req = req.WithContext(ctx)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+
+	switch {
+	case t.breaker == nil:
+	case err != nil && parent.Err() != nil:
+		// the caller giving up (or running out of time) doesn't mean couchdb is down,
+		// but the per-attempt timeout firing does
+		t.breaker.release()
+	default:
+		t.breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the timeout covers reading the body, so it's only canceled once the body is closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable returns true if req can be sent again without changing anything. Reads that are
+// POSTed because of their size, like _all_docs with keys, are retryable.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(req.URL.Path, "/_all_docs") || strings.HasSuffix(req.URL.Path, "/_bulk_get")
+	}
+
+	return false
+}
+
+// shouldRetry returns true if the attempt that returned resp and err failed in a way that might not happen again.
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	switch {
+	case ctx.Err() != nil:
+		return false
+	case errors.Is(err, ErrCircuitOpen):
+		return false
+	case err != nil:
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+func retryReason(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("got status %d", resp.StatusCode)
+}
+
+// backoff returns how long to wait before retry attempt+1: base doubled each attempt, with jitter.
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt)
+	if d <= 0 || d > _maxBackoff {
+		d = _maxBackoff
+	}
+
+	// somewhere between half and all of d, so that retries from different requests spread out
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// breaker opens after failures consecutive failures, and stays open for cooldown. Then, a single
+// request is let through; if it succeeds the breaker closes, and otherwise it opens again.
+type breaker struct {
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	failed    int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow returns true if a request can be sent.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.failed < b.failures:
+		return true
+	case b.now().Before(b.openUntil), b.probing:
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// release is called when a request that was allowed finished without showing whether CouchDB is up.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// record records whether a request that was allowed succeeded.
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failed = 0
+		return
+	}
+
+	b.failed++
+	if b.failed >= b.failures {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,309 @@
+package couch
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// flakyCouch serves mockUIConfigDoc, after failing with status the first fail requests.
+func flakyCouch(t *testing.T, fail int32, status int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) <= fail {
+			w.WriteHeader(status)
+			return
+		}
+
+		serveUIConfig(w)
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// serveUIConfig responds like couchdb does to a GET of mockUIConfigDoc.
+func serveUIConfig(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", `"1-abc"`)
+	_, _ = w.Write([]byte(mockUIConfigDoc))
+}
+
+func TestRetries(t *testing.T) {
+	srv, hits := flakyCouch(t, 2, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := New(ctx, srv.URL, WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	cams, err := cs.Cameras(ctx, "ITB-1101", "Camera")
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get cameras: %s", err)
+	case len(cams) != 1:
+		t.Fatalf("expected 1 camera, got %d", len(cams))
+	case atomic.LoadInt32(hits) != 3:
+		t.Fatalf("expected 3 requests, got %d", atomic.LoadInt32(hits))
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	srv, hits := flakyCouch(t, 5, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := New(ctx, srv.URL, WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if atomic.LoadInt32(hits) != 3 {
+		t.Fatalf("expected 3 requests, got %d", atomic.LoadInt32(hits))
+	}
+}
+
+func TestRetryOnlyReads(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, r.Method+" "+r.URL.Path+" "+string(body))
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: options{retries: 1, log: zap.NewNop()}.transport("")}
+	client.Transport.(*resilientTransport).sleepFor = func(context.Context, time.Duration) error { return nil }
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPut, "/ui-configuration/ITB-1101"},
+		{http.MethodPost, "/pc-mapping/_all_docs"},
+	} {
+		r, err := http.NewRequest(req.method, srv.URL+req.path, strings.NewReader(`{"keys": ["ITB"]}`))
+		if err != nil {
+			t.Fatalf("unable to build request: %s", err)
+		}
+
+		// like kivik's, the body can't be read again
+		r.GetBody = nil
+
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatalf("unable to send request: %s", err)
+		}
+
+		resp.Body.Close()
+	}
+
+	expected := []string{
+		`PUT /ui-configuration/ITB-1101 {"keys": ["ITB"]}`,
+		`POST /pc-mapping/_all_docs {"keys": ["ITB"]}`,
+		`POST /pc-mapping/_all_docs {"keys": ["ITB"]}`,
+	}
+
+	if strings.Join(bodies, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("got wrong requests:\n%s", strings.Join(bodies, "\n"))
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cs, err := New(ctx, srv.URL, WithRequestTimeout(20*time.Millisecond), WithRetries(1, time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	start := time.Now()
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("request didn't time out, took %s", time.Since(start))
+	}
+
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Fatalf("expected 2 requests, got %d", atomic.LoadInt32(&hits))
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	srv, hits := flakyCouch(t, 3, http.StatusInternalServerError)
+
+	now := time.Now()
+	rt := options{breakerFailures: 3, breakerCooldown: time.Minute, log: zap.NewNop()}.transport("").(*resilientTransport)
+	rt.breaker.now = func() time.Time { return now }
+
+	client := &http.Client{Transport: rt}
+	get := func() error {
+		resp, err := client.Get(srv.URL + "/ui-configuration/ITB-1101")
+		if err != nil {
+			return err
+		}
+
+		resp.Body.Close()
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("unable to send request %d: %s", i, err)
+		}
+	}
+
+	// open, so couchdb isn't asked
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	if atomic.LoadInt32(hits) != 3 {
+		t.Fatalf("expected 3 requests, got %d", atomic.LoadInt32(hits))
+	}
+
+	// after the cooldown, a request is let through, and it working closes the breaker
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("expected the breaker to be closed, got %s", err)
+		}
+	}
+
+	if atomic.LoadInt32(hits) != 5 {
+		t.Fatalf("expected 5 requests, got %d", atomic.LoadInt32(hits))
+	}
+}
+
+func TestCircuitBreakerDeadlines(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	rt := options{breakerFailures: 1, breakerCooldown: time.Minute, requestTimeout: time.Minute, log: zap.NewNop()}.transport("").(*resilientTransport)
+	client := &http.Client{Transport: rt}
+
+	get := func(timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/ui-configuration/ITB-1101", nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		resp.Body.Close()
+		return nil
+	}
+
+	// the caller's deadline isn't a failure
+	for i := 0; i < 3; i++ {
+		if err := get(20 * time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the caller's deadline to be exceeded, got %v", err)
+		}
+	}
+
+	// but the per-attempt timeout is
+	rt.timeout = 20 * time.Millisecond
+	if err := get(time.Second); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the request to time out, got %v", err)
+	}
+
+	if err := get(time.Second); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveUIConfig(w)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// the test server's certificate isn't trusted by default
+	cs, err := New(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err == nil {
+		t.Fatalf("expected an error for an untrusted certificate")
+	}
+
+	dir, err := ioutil.TempDir("", "pc-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatalf("unable to write CA bundle: %s", err)
+	}
+
+	cfg, err := LoadTLSConfig(caFile, "", "")
+	if err != nil {
+		t.Fatalf("unable to load tls config: %s", err)
+	}
+
+	cs, err = New(ctx, srv.URL, WithTLSConfig(cfg))
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if _, err := cs.Cameras(ctx, "ITB-1101", "Camera"); err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	if _, err := LoadTLSConfig(filepath.Join(os.TempDir(), "missing.pem"), "", ""); err == nil {
+		t.Fatalf("expected an error for a missing CA bundle")
+	}
+
+	if _, err := LoadTLSConfig("", "cert.pem", ""); err == nil {
+		t.Fatalf("expected an error for a certificate without a key")
+	}
+}
@@ -1,6 +1,9 @@
 package pcconfig
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // ConfigService talks the a datastore to get configuration information.
 type ConfigService interface {
@@ -11,13 +14,185 @@ type ConfigService interface {
 	Cameras(ctx context.Context, room, controlGroup string) ([]Camera, error)
 }
 
+// ConfigLister lists all of the configuration in a datastore.
+type ConfigLister interface {
+	// PCMappings returns every PC mapping
+	PCMappings(ctx context.Context) ([]PCMapping, error)
+
+	// Rooms returns the configuration for every room
+	Rooms(ctx context.Context) ([]Room, error)
+}
+
+// CameraPresetService updates the presets stored for a camera.
+type CameraPresetService interface {
+	// SetCameraPreset replaces the preset at slot on the given camera.
+	// If slot is the number of presets the camera has, the preset is added.
+	SetCameraPreset(ctx context.Context, room, controlGroup, camera string, slot int, preset CameraPreset) error
+}
+
+// TemplateService gets camera templates.
+type TemplateService interface {
+	// Templates returns every camera template, keyed by name.
+	Templates(ctx context.Context) (map[string]Camera, error)
+}
+
+// ThumbnailStore stores JPEG thumbnails of camera presets.
+type ThumbnailStore interface {
+	// PutThumbnail stores jpeg for room under key.
+	PutThumbnail(ctx context.Context, room, key string, jpeg []byte) error
+
+	// Thumbnail returns the thumbnail for room stored under key.
+	Thumbnail(ctx context.Context, room, key string) ([]byte, error)
+}
+
+// RoomService gets the config for a whole room.
+type RoomService interface {
+	// Room returns every control group in room.
+	Room(ctx context.Context, room string) (Room, error)
+}
+
+// BatchService looks up the config of many PCs with as few requests to the datastore as possible.
+type BatchService interface {
+	// PCMappingsFor returns the mapping of each hostname that has one, by hostname. Like
+	// RoomAndControlGroup, the longest mapping that hostname starts with is used. Mappings that
+	// can't be read are left out, like rooms are by RoomsFor.
+	PCMappingsFor(ctx context.Context, hostnames []string) (map[string]PCMapping, error)
+
+	// RoomsFor returns each of rooms that exists, by id. Rooms that can't be built aren't returned,
+	// so that looking them up on their own returns the reason.
+	RoomsFor(ctx context.Context, rooms []string) (map[string]Room, error)
+}
+
+// OverrideService gets the overrides for individual PCs.
+type OverrideService interface {
+	// Overrides returns the overrides for hostname, and the id of the pc mapping they are stored on.
+	Overrides(ctx context.Context, hostname string) (PCOverrides, string, error)
+}
+
+// HistoryService gets and restores previous versions of rooms and pc mappings.
+type HistoryService interface {
+	// RoomHistory returns every revision of room's config, newest first.
+	RoomHistory(ctx context.Context, room string) ([]Revision, error)
+
+	// RoomAt returns room's config at rev. If rev is empty, the current config is returned.
+	RoomAt(ctx context.Context, room, rev string) (Room, error)
+
+	// RollbackRoom replaces room's config with its config at rev, and returns the new revision.
+	RollbackRoom(ctx context.Context, room, rev string) (string, error)
+
+	// MappingHistory returns every revision of the pc mapping with the id hostname, newest first.
+	MappingHistory(ctx context.Context, hostname string) ([]Revision, error)
+
+	// MappingAt returns the pc mapping at rev. If rev is empty, the current mapping is returned.
+	MappingAt(ctx context.Context, hostname, rev string) (PCMapping, error)
+
+	// RollbackMapping replaces the pc mapping with the mapping at rev, and returns the new revision.
+	RollbackMapping(ctx context.Context, hostname, rev string) (string, error)
+}
+
+// Revision is a single version of a config document.
+type Revision struct {
+	Rev string `json:"rev"`
+
+	// Available is false once the revision's contents have been removed from the datastore.
+	Available bool `json:"available"`
+}
+
+// ScheduleService stores change sets that take effect in the future.
+type ScheduleService interface {
+	// ChangeSets returns every change set, including ones that have been applied.
+	ChangeSets(ctx context.Context) ([]ChangeSet, error)
+
+	// PutChangeSet creates or replaces a change set.
+	PutChangeSet(ctx context.Context, cs ChangeSet) error
+
+	// DeleteChangeSet deletes a change set.
+	DeleteChangeSet(ctx context.Context, id string) error
+
+	// ApplyChangeSet writes a change set's rooms and mappings to the datastore and marks it applied.
+	ApplyChangeSet(ctx context.Context, id string) error
+
+	// RecordCanaryFetch records that hostname fetched the canary change set id at.
+	RecordCanaryFetch(ctx context.Context, id, hostname string, at time.Time) error
+}
+
+// ChangeSet is a group of changes that take effect at the same time.
+type ChangeSet struct {
+	ID        string    `json:"id"`
+	Effective time.Time `json:"effective"`
+
+	// Rooms have the control groups to change in each room. Each control group
+	// replaces the one with the same name, or is added if there isn't one.
+	Rooms []Room `json:"rooms,omitempty"`
+
+	// Mappings replace the pc mappings with the same hostname, or are added if there isn't one.
+	Mappings []PCMapping `json:"mappings,omitempty"`
+
+	// Canary, if set, limits the change set to some PCs until it is applied.
+	Canary *Canary `json:"canary,omitempty"`
+
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// CheckInService stores the latest check in from each PC.
+type CheckInService interface {
+	// CheckIn stores ci, replacing the last check in from the same hostname.
+	CheckIn(ctx context.Context, ci CheckIn) error
+
+	// CheckIns returns the latest check in from every PC.
+	CheckIns(ctx context.Context) ([]CheckIn, error)
+}
+
 // ControlKeyService gets the control key for a room
 type ControlKeyService interface {
 	ControlKey(ctx context.Context, room, controlGroup string) (string, error)
 }
 
+// PCMapping maps a PC's hostname (or a prefix of it) to a room and control group.
+type PCMapping struct {
+	Hostname     string `json:"hostname"`
+	Room         string `json:"room"`
+	ControlGroup string `json:"controlGroup"`
+
+	// Overrides change the config PCs using this mapping get.
+	Overrides *PCOverrides `json:"overrides,omitempty"`
+}
+
+// MinMappingLength is the shortest a hostname is trimmed to when looking for its mapping.
+const MinMappingLength = 3
+
+// MappingCandidates returns the ids of the pc mappings hostname could use, longest first.
+// A PC uses the longest mapping that its hostname starts with.
+func MappingCandidates(hostname string) []string {
+	ids := []string{hostname}
+	for len(hostname) > MinMappingLength {
+		hostname = hostname[:len(hostname)-1]
+		ids = append(ids, hostname)
+	}
+
+	return ids
+}
+
+// Room is the configuration for every control group in a room.
+type Room struct {
+	ID            string         `json:"id"`
+	ControlGroups []ControlGroup `json:"controlGroups"`
+}
+
+type ControlGroup struct {
+	Name    string   `json:"name"`
+	Cameras []Camera `json:"cameras"`
+}
+
 type Camera struct {
-	DisplayName string `json:"displayName"`
+	DisplayName string `json:"displayName" jsonschema:"required"`
+
+	// Template is the name of a camera template this camera is built from. Fields set
+	// on the camera override the template's, and Variables fill in its {{variables}}.
+	// Presets override the template's presets slot by slot.
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
 
 	TiltUp      string `json:"tiltUp"`
 	TiltDown    string `json:"tiltDown"`
@@ -31,10 +206,43 @@ type Camera struct {
 
 	Stream string `json:"stream"`
 
+	// Snapshot returns a JPEG of what the camera currently sees.
+	Snapshot string `json:"snapshot,omitempty"`
+
+	// Address and Protocol identify a camera that pc-config controls itself
+	// (through the camera proxy) instead of through the URLs above.
+	Address  string `json:"address,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+
 	Presets []CameraPreset `json:"presets"`
+
+	// Capabilities describes everything the camera can do. It is emitted
+	// alongside the legacy pan/tilt/zoom fields above.
+	Capabilities []CameraCapability `json:"capabilities,omitempty"`
+
+	// Status is whether pc-config was able to reach the camera the last
+	// time it checked. It is only set on configs sent to PCs.
+	Status string `json:"status,omitempty"`
 }
 
+const (
+	CameraStatusUp   = "up"
+	CameraStatusDown = "down"
+)
+
 type CameraPreset struct {
+	// DisplayName isn't required in stored docs, since presets on a templated camera can get it from the template.
 	DisplayName string `json:"displayName"`
 	SetPreset   string `json:"setPreset"`
+
+	// SavePreset stores the camera's current position in this preset.
+	SavePreset string `json:"savePreset,omitempty"`
+
+	// Thumbnail is a URL for an image of the preset's position. In the
+	// datastore, it is the key of the thumbnail in the ThumbnailStore.
+	Thumbnail string `json:"thumbnail,omitempty"`
+
+	// Preset identifies the preset on the camera itself. Only used
+	// for cameras with a Protocol.
+	Preset string `json:"preset,omitempty"`
 }
@@ -0,0 +1,227 @@
+package pcconfig
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// ChangeType is how something changed between two versions of a room.
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change is a single difference between the cameras in two versions of a room, or between two versions of a pc mapping.
+type Change struct {
+	Type ChangeType `json:"type"`
+
+	ControlGroup string `json:"controlGroup,omitempty"`
+	Camera       string `json:"camera,omitempty"`
+	Preset       string `json:"preset,omitempty"`
+	Field        string `json:"field,omitempty"`
+
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// DiffRooms returns the changes to the cameras and presets between from and to.
+func DiffRooms(from, to Room) []Change {
+	var changes []Change
+
+	toGroups := make(map[string]ControlGroup)
+	for _, cg := range to.ControlGroups {
+		toGroups[cg.Name] = cg
+	}
+
+	fromGroups := make(map[string]bool)
+	for _, cg := range from.ControlGroups {
+		fromGroups[cg.Name] = true
+
+		other, ok := toGroups[cg.Name]
+		if !ok {
+			changes = append(changes, Change{Type: ChangeRemoved, ControlGroup: cg.Name})
+			continue
+		}
+
+		changes = append(changes, diffCameras(cg.Name, cg.Cameras, other.Cameras)...)
+	}
+
+	for _, cg := range to.ControlGroups {
+		if !fromGroups[cg.Name] {
+			changes = append(changes, Change{Type: ChangeAdded, ControlGroup: cg.Name})
+		}
+	}
+
+	return changes
+}
+
+// DiffMappings returns the changes to the fields of a pc mapping between from and to.
+func DiffMappings(from, to PCMapping) []Change {
+	return diffFields(from, to)
+}
+
+func diffCameras(cg string, from, to []Camera) []Change {
+	var changes []Change
+
+	toCams := make(map[string]Camera)
+	for _, cam := range to {
+		toCams[cam.DisplayName] = cam
+	}
+
+	fromCams := make(map[string]bool)
+	for _, cam := range from {
+		fromCams[cam.DisplayName] = true
+
+		other, ok := toCams[cam.DisplayName]
+		if !ok {
+			changes = append(changes, Change{Type: ChangeRemoved, ControlGroup: cg, Camera: cam.DisplayName})
+			continue
+		}
+
+		fromPresets, toPresets := cam.Presets, other.Presets
+		cam.Presets, other.Presets = nil, nil
+
+		for _, c := range diffFields(cam, other) {
+			c.ControlGroup, c.Camera = cg, cam.DisplayName
+			changes = append(changes, c)
+		}
+
+		for _, c := range diffPresets(fromPresets, toPresets) {
+			c.ControlGroup, c.Camera = cg, cam.DisplayName
+			changes = append(changes, c)
+		}
+	}
+
+	for _, cam := range to {
+		if !fromCams[cam.DisplayName] {
+			changes = append(changes, Change{Type: ChangeAdded, ControlGroup: cg, Camera: cam.DisplayName})
+		}
+	}
+
+	return changes
+}
+
+func diffPresets(from, to []CameraPreset) []Change {
+	var changes []Change
+
+	toPresets := make(map[string]CameraPreset)
+	for _, p := range to {
+		toPresets[p.DisplayName] = p
+	}
+
+	fromPresets := make(map[string]bool)
+	var fromOrder, toOrder []string
+
+	for _, p := range from {
+		fromPresets[p.DisplayName] = true
+
+		other, ok := toPresets[p.DisplayName]
+		if !ok {
+			changes = append(changes, Change{Type: ChangeRemoved, Preset: p.DisplayName})
+			continue
+		}
+
+		fromOrder = append(fromOrder, p.DisplayName)
+		for _, c := range diffFields(p, other) {
+			c.Preset = p.DisplayName
+			changes = append(changes, c)
+		}
+	}
+
+	for _, p := range to {
+		if !fromPresets[p.DisplayName] {
+			changes = append(changes, Change{Type: ChangeAdded, Preset: p.DisplayName})
+			continue
+		}
+
+		toOrder = append(toOrder, p.DisplayName)
+	}
+
+	// presets are saved by slot, so their order matters
+	if strings.Join(fromOrder, "\x00") != strings.Join(toOrder, "\x00") {
+		changes = append(changes, Change{
+			Type:  ChangeModified,
+			Field: "presets",
+			From:  strings.Join(fromOrder, ", "),
+			To:    strings.Join(toOrder, ", "),
+		})
+	}
+
+	return changes
+}
+
+// diffFields compares the json fields of from and to.
+func diffFields(from, to interface{}) []Change {
+	fromFields, toFields := flatten(from), flatten(to)
+
+	keys := make(map[string]bool)
+	for k := range fromFields {
+		keys[k] = true
+	}
+
+	for k := range toFields {
+		keys[k] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+
+	sort.Strings(sorted)
+
+	var changes []Change
+	for _, k := range sorted {
+		f, inFrom := fromFields[k]
+		t, inTo := toFields[k]
+
+		switch {
+		case f == t:
+		case !inFrom:
+			changes = append(changes, Change{Type: ChangeAdded, Field: k, To: t})
+		case !inTo:
+			changes = append(changes, Change{Type: ChangeRemoved, Field: k, From: f})
+		default:
+			changes = append(changes, Change{Type: ChangeModified, Field: k, From: f, To: t})
+		}
+	}
+
+	return changes
+}
+
+// flatten returns the non-empty json fields of v. Nested objects use dotted
+// field names, and everything that isn't a string is left as json.
+func flatten(v interface{}) map[string]string {
+	fields := make(map[string]string)
+
+	m, err := toJSONMap(v)
+	if err != nil {
+		return fields
+	}
+
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, val := range m {
+			if emptyJSON(val) {
+				continue
+			}
+
+			switch val := val.(type) {
+			case string:
+				fields[prefix+k] = val
+			case map[string]interface{}:
+				walk(prefix+k+".", val)
+			default:
+				b, _ := json.Marshal(val)
+				fields[prefix+k] = string(b)
+			}
+		}
+	}
+
+	walk("", m)
+	return fields
+}
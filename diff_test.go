@@ -0,0 +1,93 @@
+package pcconfig
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffRooms(t *testing.T) {
+	from := Room{
+		ID: "ITB-1101",
+		ControlGroups: []ControlGroup{
+			{
+				Name: "Group 1",
+				Cameras: []Camera{
+					{
+						DisplayName: "Front",
+						PanTiltStop: "http://front/stop",
+						Stream:      "http://front/stream",
+						Presets: []CameraPreset{
+							{DisplayName: "Podium", SetPreset: "http://front/preset/1"},
+							{DisplayName: "Board", SetPreset: "http://front/preset/2"},
+							{DisplayName: "Door", SetPreset: "http://front/preset/3"},
+						},
+					},
+					{DisplayName: "Back"},
+				},
+			},
+			{Name: "Group 2"},
+		},
+	}
+
+	to := Room{
+		ID: "ITB-1101",
+		ControlGroups: []ControlGroup{
+			{
+				Name: "Group 1",
+				Cameras: []Camera{
+					{
+						DisplayName: "Front",
+						Stream:      "http://front/stream2",
+						Snapshot:    "http://front/snapshot",
+						Presets: []CameraPreset{
+							{DisplayName: "Board", SetPreset: "http://front/preset/2"},
+							{DisplayName: "Podium", SetPreset: "http://front/preset/4"},
+							{DisplayName: "Audience", SetPreset: "http://front/preset/5"},
+						},
+					},
+					{DisplayName: "Side"},
+				},
+			},
+			{Name: "Group 3"},
+		},
+	}
+
+	expected := []Change{
+		{Type: ChangeRemoved, ControlGroup: "Group 1", Camera: "Front", Field: "panTiltStop", From: "http://front/stop"},
+		{Type: ChangeAdded, ControlGroup: "Group 1", Camera: "Front", Field: "snapshot", To: "http://front/snapshot"},
+		{Type: ChangeModified, ControlGroup: "Group 1", Camera: "Front", Field: "stream", From: "http://front/stream", To: "http://front/stream2"},
+		{Type: ChangeModified, ControlGroup: "Group 1", Camera: "Front", Preset: "Podium", Field: "setPreset", From: "http://front/preset/1", To: "http://front/preset/4"},
+		{Type: ChangeRemoved, ControlGroup: "Group 1", Camera: "Front", Preset: "Door"},
+		{Type: ChangeAdded, ControlGroup: "Group 1", Camera: "Front", Preset: "Audience"},
+		{Type: ChangeModified, ControlGroup: "Group 1", Camera: "Front", Field: "presets", From: "Podium, Board", To: "Board, Podium"},
+		{Type: ChangeRemoved, ControlGroup: "Group 1", Camera: "Back"},
+		{Type: ChangeAdded, ControlGroup: "Group 1", Camera: "Side"},
+		{Type: ChangeRemoved, ControlGroup: "Group 2"},
+		{Type: ChangeAdded, ControlGroup: "Group 3"},
+	}
+
+	if diff := cmp.Diff(expected, DiffRooms(from, to)); diff != "" {
+		t.Errorf("got incorrect changes (-want, +got):\n%s", diff)
+	}
+
+	if changes := DiffRooms(from, from); len(changes) != 0 {
+		t.Errorf("expected no changes between the same room, got %+v", changes)
+	}
+}
+
+func TestDiffMappings(t *testing.T) {
+	from := PCMapping{Hostname: "ITB-1101-CP", Room: "ITB-1101", ControlGroup: "Group 1"}
+	to := PCMapping{Hostname: "ITB-1101-CP", Room: "ITB-1101", ControlGroup: "Group 2", Overrides: &PCOverrides{
+		Cameras: []CameraOverride{{Camera: "Back", Hide: true}},
+	}}
+
+	expected := []Change{
+		{Type: ChangeModified, Field: "controlGroup", From: "Group 1", To: "Group 2"},
+		{Type: ChangeAdded, Field: "overrides.cameras", To: `[{"camera":"Back","hide":true}]`},
+	}
+
+	if diff := cmp.Diff(expected, DiffMappings(from, to)); diff != "" {
+		t.Errorf("got incorrect changes (-want, +got):\n%s", diff)
+	}
+}
@@ -3,12 +3,15 @@ module github.com/byuoitav/pc-config
 go 1.14
 
 require (
-	github.com/gin-gonic/gin v1.6.3
+	github.com/gin-gonic/gin v1.7.7 // 1.7 is the first version that lets /admin/... routes coexist with /:hostname
 	github.com/go-kivik/couchdb/v3 v3.1.0
 	github.com/go-kivik/kivik/v3 v3.1.1
 	github.com/go-kivik/kivikmock/v3 v3.1.1
 	github.com/google/go-cmp v0.5.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.14
 	github.com/spf13/pflag v1.0.5
 	go.uber.org/zap v1.15.0
 	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
 )
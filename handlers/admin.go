@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// _authorizedKey is set on the requests RequireToken allows.
+const _authorizedKey = "authorized"
+
+// RequireToken only allows requests that include token as a bearer token.
+func RequireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.String(http.StatusUnauthorized, "invalid token")
+			c.Abort()
+			return
+		}
+
+		c.Set(_authorizedKey, true)
+		c.Next()
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// _bulkWorkers is how many configs BulkConfigs and CheckIns build at once.
+	_bulkWorkers = 16
+
+	// _maxBulkHostnames is the most hostnames BulkConfigs accepts in one request.
+	_maxBulkHostnames = 1000
+)
+
+type bulkRequest struct {
+	Hostnames []string `json:"hostnames"`
+}
+
+type bulkResponse struct {
+	Configs []bulkResult `json:"configs"`
+}
+
+// bulkResult is the config of one hostname, or why it couldn't be built.
+type bulkResult struct {
+	Hostname string             `json:"hostname"`
+	Config   *pcconfig.ConfigV2 `json:"config,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// BulkConfigs builds the v2 config of each hostname in the request. Configs are built concurrently,
+// and each room is only read once. A hostname whose config can't be built gets an error instead.
+// Control keys are only included for admins.
+func (h *Handlers) BulkConfigs(c *gin.Context) {
+	var req bulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.String(http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err))
+		return
+	}
+
+	var hostnames []string
+	seen := make(map[string]bool)
+
+	for _, hostname := range req.Hostnames {
+		if hostname == "" || seen[hostname] {
+			continue
+		}
+
+		seen[hostname] = true
+		hostnames = append(hostnames, hostname)
+	}
+
+	switch {
+	case len(hostnames) == 0:
+		c.String(http.StatusBadRequest, "at least one hostname is required")
+		return
+	case len(hostnames) > _maxBulkHostnames:
+		c.String(http.StatusBadRequest, fmt.Sprintf("at most %d hostnames can be requested at once", _maxBulkHostnames))
+		return
+	}
+
+	at := time.Now()
+	if c.Query("at") != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, c.Query("at")); err != nil {
+			c.String(http.StatusBadRequest, fmt.Sprintf("invalid at: %s", err))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	sets, err := h.changeSets(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cs := newSharedReads(h.ConfigService)
+	if bs, ok := h.ConfigService.(pcconfig.BatchService); ok {
+		cs.prefetch(ctx, bs, hostnames)
+	}
+
+	withKeys := c.GetBool(_authorizedKey)
+
+	results := make([]bulkResult, len(hostnames))
+	inParallel(len(hostnames), func(i int) {
+		results[i] = h.bulkResult(ctx, cs, sets, hostnames[i], at, withKeys)
+	})
+
+	c.JSON(http.StatusOK, bulkResponse{Configs: results})
+}
+
+// bulkResult builds the config for hostname, with its control key if withKey is true.
+// These aren't fetched by the PC, so canary fetches aren't recorded.
+func (h *Handlers) bulkResult(ctx context.Context, cs *sharedReads, sets []pcconfig.ChangeSet, hostname string, at time.Time, withKey bool) bulkResult {
+	result := bulkResult{Hostname: hostname}
+
+	config, _, err := h.configFrom(ctx, cs, sets, hostname, at, true)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if withKey {
+		// ignore this error, just don't set the key
+		if key, err := cs.controlKey(ctx, config.room, config.controlGroup, h.ControlKeyService); err == nil {
+			config.ControlKey = key
+		}
+	}
+
+	version, err := config.Version()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	v2 := config.v2(hostname, version)
+	result.Config = &v2
+	return result
+}
+
+// inParallel calls fn with each index below n, using up to _bulkWorkers goroutines.
+func inParallel(n int, fn func(i int)) {
+	indexes := make(chan int)
+
+	workers := _bulkWorkers
+	if n < workers {
+		workers = n
+	}
+
+	var g errgroup.Group
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for i := range indexes {
+				fn(i)
+			}
+
+			return nil
+		})
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+
+	close(indexes)
+	_ = g.Wait()
+}
+
+// sharedReads is a ConfigService for a single request that only reads the cameras and control key of
+// each control group and the camera templates once, no matter how many PCs share them.
+type sharedReads struct {
+	pcconfig.ConfigService
+
+	// mappings are the prefetched pc mappings, by hostname. It isn't changed once PCs are being looked up.
+	mappings map[string]pcconfig.PCMapping
+
+	mu    sync.Mutex
+	reads map[string]*sharedRead
+}
+
+type sharedRead struct {
+	once sync.Once
+	val  interface{}
+	err  error
+}
+
+func newSharedReads(cs pcconfig.ConfigService) *sharedReads {
+	return &sharedReads{
+		ConfigService: cs,
+		reads:         make(map[string]*sharedRead),
+	}
+}
+
+// prefetch reads the mappings of every hostname, and then every room they are in, in a batch each.
+// It is best effort; anything that isn't prefetched is read when it's needed.
+func (s *sharedReads) prefetch(ctx context.Context, bs pcconfig.BatchService, hostnames []string) {
+	if !s.prefetchMappings(ctx, bs, hostnames) {
+		return
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+
+	for _, mapping := range s.mappings {
+		if !seen[mapping.Room] {
+			seen[mapping.Room] = true
+			ids = append(ids, mapping.Room)
+		}
+	}
+
+	rooms, err := bs.RoomsFor(ctx, ids)
+	if err != nil {
+		return
+	}
+
+	for _, room := range rooms {
+		for _, cg := range room.ControlGroups {
+			s.store(camerasKey(room.ID, cg.Name), cg.Cameras)
+		}
+	}
+}
+
+// prefetchMappings reads the mapping of every hostname in one batch, so that each PC's room, control
+// group, and overrides come from a single lookup. It reports whether they could be read.
+func (s *sharedReads) prefetchMappings(ctx context.Context, bs pcconfig.BatchService, hostnames []string) bool {
+	mappings, err := bs.PCMappingsFor(ctx, hostnames)
+	if err != nil {
+		return false
+	}
+
+	s.mappings = mappings
+	return true
+}
+
+// store saves val as the result of the read with key.
+func (s *sharedReads) store(key string, val interface{}) {
+	r := &sharedRead{val: val}
+	r.once.Do(func() {})
+
+	s.mu.Lock()
+	s.reads[key] = r
+	s.mu.Unlock()
+}
+
+// do calls read the first time it is called with key, and returns what it returned every time.
+// Concurrent callers with the same key wait for the first one to finish.
+func (s *sharedReads) do(key string, read func() (interface{}, error)) (interface{}, error) {
+	s.mu.Lock()
+	r, ok := s.reads[key]
+	if !ok {
+		r = &sharedRead{}
+		s.reads[key] = r
+	}
+	s.mu.Unlock()
+
+	r.once.Do(func() {
+		r.val, r.err = read()
+	})
+
+	return r.val, r.err
+}
+
+func camerasKey(room, controlGroup string) string {
+	return "cameras\x00" + room + "\x00" + controlGroup
+}
+
+// controlKey gets the control key of room and controlGroup from keys, only once for each control group.
+func (s *sharedReads) controlKey(ctx context.Context, room, controlGroup string, keys pcconfig.ControlKeyService) (string, error) {
+	val, err := s.do("key\x00"+room+"\x00"+controlGroup, func() (interface{}, error) {
+		return keys.ControlKey(ctx, room, controlGroup)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return val.(string), nil
+}
+
+func (s *sharedReads) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
+	if mapping, ok := s.mappings[hostname]; ok {
+		return mapping.Room, mapping.ControlGroup, nil
+	}
+
+	return s.ConfigService.RoomAndControlGroup(ctx, hostname)
+}
+
+func (s *sharedReads) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
+	val, err := s.do(camerasKey(room, controlGroup), func() (interface{}, error) {
+		return s.ConfigService.Cameras(ctx, room, controlGroup)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// each caller gets its own copy, since configs are built by modifying it
+	cams := val.([]pcconfig.Camera)
+	return append([]pcconfig.Camera(nil), cams...), nil
+}
+
+func (s *sharedReads) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	overrider, ok := s.ConfigService.(pcconfig.OverrideService)
+	if !ok {
+		return pcconfig.PCOverrides{}, "", nil
+	}
+
+	mapping, ok := s.mappings[hostname]
+	switch {
+	case ok && mapping.Overrides != nil:
+		return *mapping.Overrides, mapping.Hostname, nil
+	case ok:
+		return pcconfig.PCOverrides{}, mapping.Hostname, nil
+	}
+
+	return overrider.Overrides(ctx, hostname)
+}
+
+func (s *sharedReads) Templates(ctx context.Context) (map[string]pcconfig.Camera, error) {
+	t, ok := s.ConfigService.(pcconfig.TemplateService)
+	if !ok {
+		return nil, nil
+	}
+
+	val, err := s.do("templates", func() (interface{}, error) {
+		return t.Templates(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return val.(map[string]pcconfig.Camera), nil
+}
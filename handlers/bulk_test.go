@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+)
+
+type countingConfigService struct {
+	*mockConfigService
+
+	mu    sync.Mutex
+	reads map[[2]string]int
+}
+
+func (m *countingConfigService) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
+	m.mu.Lock()
+	m.reads[[2]string{room, controlGroup}]++
+	m.mu.Unlock()
+
+	return m.mockConfigService.Cameras(ctx, room, controlGroup)
+}
+
+type countingKeyService struct {
+	mu    sync.Mutex
+	calls map[[2]string]int
+}
+
+func (c *countingKeyService) ControlKey(ctx context.Context, room, controlGroup string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls[[2]string{room, controlGroup}]++
+	return "1234", nil
+}
+
+func TestBulkConfigs(t *testing.T) {
+	mappings := map[string][2]string{
+		"ITB-1101-CP4": {"ITB-1101", "Missing"},
+		"JFSB-B100-CP": {"JFSB-B100", "Group 1"},
+	}
+
+	var hostnames []string
+	for _, hostname := range []string{"ITB-1101-CP1", "ITB-1101-CP2", "ITB-1101-CP3"} {
+		mappings[hostname] = [2]string{"ITB-1101", "Group 1"}
+		hostnames = append(hostnames, hostname)
+	}
+
+	for i := 0; i < 50; i++ {
+		hostname := fmt.Sprintf("ITB-1102-CP%d", i)
+		mappings[hostname] = [2]string{"ITB-1102", "Group 1"}
+		hostnames = append(hostnames, hostname)
+	}
+
+	cs := &countingConfigService{
+		mockConfigService: &mockConfigService{
+			mappings: mappings,
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}:  {{DisplayName: "Front"}},
+				{"ITB-1102", "Group 1"}:  {{DisplayName: "Back"}},
+				{"JFSB-B100", "Group 1"}: {{DisplayName: "Stage"}},
+			},
+		},
+		reads: make(map[[2]string]int),
+	}
+
+	keys := &countingKeyService{calls: make(map[[2]string]int)}
+
+	h := &Handlers{
+		ConfigService:     cs,
+		ControlKeyService: keys,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/configs", h.BulkConfigs)
+	r.POST("/admin/configs", RequireToken("token"), h.BulkConfigs)
+
+	hostnames = append(hostnames, "ITB-1101-CP1", "ITB-1101-CP4", "UNKNOWN-CP1", "JFSB-B100-CP")
+	body, err := json.Marshal(bulkRequest{Hostnames: hostnames})
+	if err != nil {
+		t.Fatalf("unable to encode request: %s", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/configs", strings.NewReader(string(body)))
+	req.Header.Set("Authorization", "Bearer token")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp bulkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to decode response: %s", err)
+	}
+
+	// duplicates are dropped, and the rest stay in order
+	if len(resp.Configs) != len(hostnames)-1 {
+		t.Fatalf("expected %d results, got %d", len(hostnames)-1, len(resp.Configs))
+	}
+
+	for i, result := range resp.Configs {
+		want := hostnames[i]
+		if i >= 53 {
+			want = hostnames[i+1]
+		}
+
+		if result.Hostname != want {
+			t.Fatalf("expected result %d to be for %s, got %s", i, want, result.Hostname)
+		}
+
+		switch result.Hostname {
+		case "ITB-1101-CP4", "UNKNOWN-CP1":
+			if result.Error == "" || result.Config != nil {
+				t.Errorf("expected an error for %s, got %+v", result.Hostname, result)
+			}
+		default:
+			if result.Error != "" || result.Config == nil {
+				t.Fatalf("expected a config for %s, got error %q", result.Hostname, result.Error)
+			}
+
+			room := mappings[result.Hostname][0]
+			if result.Config.Room != room || len(result.Config.Cameras) != 1 || result.Config.Version == "" {
+				t.Errorf("unexpected config for %s: %+v", result.Hostname, result.Config)
+			}
+
+			if result.Config.ControlKey == nil || result.Config.ControlKey.Key != "1234" {
+				t.Errorf("expected the control key for %s, got %+v", result.Hostname, result.Config.ControlKey)
+			}
+		}
+	}
+
+	for cg, n := range cs.reads {
+		if n != 1 {
+			t.Errorf("expected %v to be read once, got %d", cg, n)
+		}
+	}
+
+	for cg, n := range keys.calls {
+		if n != 1 {
+			t.Errorf("expected the control key of %v to be fetched once, got %d", cg, n)
+		}
+	}
+
+	if len(keys.calls) != 3 {
+		t.Errorf("expected 3 control keys to be fetched, got %v", keys.calls)
+	}
+
+	// control keys are only sent to admins
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/configs", strings.NewReader(string(body))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	resp = bulkResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to decode response: %s", err)
+	}
+
+	for _, result := range resp.Configs {
+		if result.Config != nil && result.Config.ControlKey != nil {
+			t.Errorf("expected no control key for %s, got %+v", result.Hostname, result.Config.ControlKey)
+		}
+	}
+
+	if len(keys.calls) != 3 {
+		t.Errorf("expected no more control keys to be fetched, got %v", keys.calls)
+	}
+
+	for _, body := range []string{`{"hostnames": []}`, `{"hostnames": [""]}`, `not json`} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/configs", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+type mockBatchService struct {
+	*countingConfigService
+	batches int
+}
+
+func (m *mockBatchService) PCMappingsFor(ctx context.Context, hostnames []string) (map[string]pcconfig.PCMapping, error) {
+	m.batches++
+
+	mappings := make(map[string]pcconfig.PCMapping)
+	for _, hostname := range hostnames {
+		if mapping, ok := m.mappings[hostname]; ok {
+			mappings[hostname] = pcconfig.PCMapping{Hostname: hostname, Room: mapping[0], ControlGroup: mapping[1]}
+		}
+	}
+
+	return mappings, nil
+}
+
+func (m *mockBatchService) RoomsFor(ctx context.Context, rooms []string) (map[string]pcconfig.Room, error) {
+	m.batches++
+
+	found := make(map[string]pcconfig.Room)
+	for _, room := range rooms {
+		for key, cams := range m.cameras {
+			if key[0] == room {
+				r := found[room]
+				r.ID = room
+				r.ControlGroups = append(r.ControlGroups, pcconfig.ControlGroup{Name: key[1], Cameras: cams})
+				found[room] = r
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func TestBulkConfigsBatch(t *testing.T) {
+	cs := &mockBatchService{
+		countingConfigService: &countingConfigService{
+			mockConfigService: &mockConfigService{
+				mappings: map[string][2]string{
+					"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+					"ITB-1101-CP2": {"ITB-1101", "Group 1"},
+					"ITB-1101-CP3": {"ITB-1101", "Missing"},
+				},
+				cameras: map[[2]string][]pcconfig.Camera{
+					{"ITB-1101", "Group 1"}: {{DisplayName: "Front"}},
+				},
+			},
+			reads: make(map[[2]string]int),
+		},
+	}
+
+	h := &Handlers{
+		ConfigService:     cs,
+		ControlKeyService: mockControlKeyService{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/configs", h.BulkConfigs)
+
+	body := `{"hostnames": ["ITB-1101-CP1", "ITB-1101-CP2", "ITB-1101-CP3", "UNKNOWN-CP1"]}`
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/configs", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp bulkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to decode response: %s", err)
+	}
+
+	var errs []string
+	for _, result := range resp.Configs {
+		if result.Error != "" {
+			errs = append(errs, result.Hostname)
+		}
+	}
+
+	if len(resp.Configs) != 4 || len(errs) != 2 || errs[0] != "ITB-1101-CP3" || errs[1] != "UNKNOWN-CP1" {
+		t.Fatalf("expected errors for ITB-1101-CP3 and UNKNOWN-CP1, got %+v", resp.Configs)
+	}
+
+	// the missing control group is the only one that isn't prefetched
+	if cs.batches != 2 || len(cs.reads) != 1 || cs.reads[[2]string{"ITB-1101", "Missing"}] != 1 {
+		t.Fatalf("expected 2 batches and only the missing control group to be read, got %d batches and reads %v", cs.batches, cs.reads)
+	}
+}
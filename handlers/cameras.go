@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/cameras"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// _discoveryWorkers is how many cameras DiscoverPresets asks for their presets at once.
+	_discoveryWorkers = 8
+
+	// _discoveryTimeout is how long DiscoverPresets waits for a single camera.
+	_discoveryTimeout = 5 * time.Second
+)
+
+// CameraProxy rewrites the camera URLs sent to PCs so that they point back
+// at pc-config, which forwards them on to the real camera/control service.
+type CameraProxy struct {
+	// BaseURL is the address PCs use to reach pc-config (e.g. https://pc-config.av.byu.edu).
+	BaseURL string
+
+	// Client is used to forward requests. http.DefaultClient is used if it is nil.
+	Client *http.Client
+
+	// Drivers builds drivers for cameras that have a Protocol, keyed by protocol.
+	Drivers map[string]cameras.NewDriverFunc
+
+	// Limit and Burst control how often PTZ commands can be sent to a single
+	// camera. Stop commands are never limited. If Limit is 0, commands aren't limited.
+	Limit rate.Limit
+	Burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	drivers  map[string]cameras.Driver
+	presets  map[string][]pcconfig.CameraPreset
+}
+
+// Rewrite returns a copy of cameras with every control URL replaced by a proxy URL for hostname.
+func (p *CameraProxy) Rewrite(hostname string, cams []pcconfig.Camera) []pcconfig.Camera {
+	rewritten := make([]pcconfig.Camera, len(cams))
+
+	for i, cam := range cams {
+		rewrite := func(action, orig string) string {
+			if orig == "" && cam.Protocol == "" {
+				return ""
+			}
+
+			return p.url(hostname, cam.DisplayName, action, nil)
+		}
+
+		cam.TiltUp = rewrite(pcconfig.ActionTiltUp, cam.TiltUp)
+		cam.TiltDown = rewrite(pcconfig.ActionTiltDown, cam.TiltDown)
+		cam.PanLeft = rewrite(pcconfig.ActionPanLeft, cam.PanLeft)
+		cam.PanRight = rewrite(pcconfig.ActionPanRight, cam.PanRight)
+		cam.PanTiltStop = rewrite(pcconfig.ActionPanTiltStop, cam.PanTiltStop)
+		cam.ZoomIn = rewrite(pcconfig.ActionZoomIn, cam.ZoomIn)
+		cam.ZoomOut = rewrite(pcconfig.ActionZoomOut, cam.ZoomOut)
+		cam.ZoomStop = rewrite(pcconfig.ActionZoomStop, cam.ZoomStop)
+
+		presets := make([]pcconfig.CameraPreset, len(cam.Presets))
+		for j, preset := range cam.Presets {
+			presets[j] = p.rewritePreset(hostname, cam, j, preset)
+		}
+
+		caps := make([]pcconfig.CameraCapability, len(cam.Capabilities))
+		for j, capability := range cam.Capabilities {
+			actions := make([]pcconfig.CameraAction, len(capability.Actions))
+			for k, action := range capability.Actions {
+				action.URL = rewrite(action.Name, action.URL)
+				actions[k] = action
+			}
+
+			capability.Actions = actions
+			caps[j] = capability
+		}
+
+		cam.Presets = presets
+		cam.Capabilities = caps
+
+		// PCs don't need to know where the camera actually is
+		cam.Address = ""
+		cam.Snapshot = ""
+
+		if cam.Protocol != "" && len(cam.Capabilities) == 0 {
+			// there aren't any urls in the config to build capabilities from
+			cam = pcconfig.NormalizeCapabilities(cam)
+		}
+
+		rewritten[i] = cam
+	}
+
+	return rewritten
+}
+
+// CachedPresets fills in the presets of cameras that have a Protocol but no presets
+// configured, using the presets DiscoverPresets last found on the camera. Cameras aren't contacted.
+func (p *CameraProxy) CachedPresets(cams []pcconfig.Camera) []pcconfig.Camera {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cached := make([]pcconfig.Camera, len(cams))
+	for i, cam := range cams {
+		cached[i] = cam
+		if cam.Protocol == "" || len(cam.Presets) > 0 {
+			continue
+		}
+
+		if presets, ok := p.presets[cameraKey(cam)]; ok {
+			cached[i].Presets = append([]pcconfig.CameraPreset(nil), presets...)
+		}
+	}
+
+	return cached
+}
+
+// RunDiscovery calls DiscoverPresets each interval until ctx is done.
+func (p *CameraProxy) RunDiscovery(ctx context.Context, lister pcconfig.ConfigLister, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// cameras that can't be reached keep the presets they had last time
+		_ = p.DiscoverPresets(ctx, lister)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DiscoverPresets asks every camera in lister that has a Protocol but no presets configured for
+// the presets stored on it, and caches them for CachedPresets. A camera that can't be reached
+// keeps the presets it had before.
+func (p *CameraProxy) DiscoverPresets(ctx context.Context, lister pcconfig.ConfigLister) error {
+	rooms, err := lister.Rooms(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get rooms: %w", err)
+	}
+
+	var cams []pcconfig.Camera
+	seen := make(map[string]bool)
+
+	for _, room := range rooms {
+		for _, cg := range room.ControlGroups {
+			for _, cam := range cg.Cameras {
+				if cam.Protocol == "" || len(cam.Presets) > 0 || seen[cameraKey(cam)] {
+					continue
+				}
+
+				seen[cameraKey(cam)] = true
+				cams = append(cams, cam)
+			}
+		}
+	}
+
+	discovered := make(map[string][]pcconfig.CameraPreset)
+	var mu sync.Mutex
+
+	ch := make(chan pcconfig.Camera)
+	var wg sync.WaitGroup
+
+	for i := 0; i < _discoveryWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for cam := range ch {
+				ctx, cancel := context.WithTimeout(ctx, _discoveryTimeout)
+				presets, err := p.listPresets(ctx, cam)
+				cancel()
+
+				if err != nil {
+					continue
+				}
+
+				mu.Lock()
+				discovered[cameraKey(cam)] = presets
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, cam := range cams {
+		ch <- cam
+	}
+
+	close(ch)
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, presets := range p.presets {
+		if _, ok := discovered[key]; !ok && seen[key] {
+			discovered[key] = presets
+		}
+	}
+
+	p.presets = discovered
+	return nil
+}
+
+// presetsFor returns cam's presets. If it doesn't have any configured or cached, they are listed from the camera.
+func (p *CameraProxy) presetsFor(ctx context.Context, cam pcconfig.Camera) []pcconfig.CameraPreset {
+	cam = p.CachedPresets([]pcconfig.Camera{cam})[0]
+	if cam.Protocol == "" || len(cam.Presets) > 0 {
+		return cam.Presets
+	}
+
+	// a camera that can't list its presets just doesn't get any
+	presets, err := p.listPresets(ctx, cam)
+	if err != nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.presets == nil {
+		p.presets = make(map[string][]pcconfig.CameraPreset)
+	}
+
+	p.presets[cameraKey(cam)] = presets
+	return presets
+}
+
+// listPresets asks cam for the presets stored on it.
+func (p *CameraProxy) listPresets(ctx context.Context, cam pcconfig.Camera) ([]pcconfig.CameraPreset, error) {
+	d, err := p.driver(cam)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := d.(cameras.PresetLister)
+	if !ok {
+		return nil, fmt.Errorf("%s cameras can't list presets: %w", cam.Protocol, cameras.ErrUnsupported)
+	}
+
+	return lister.Presets(ctx)
+}
+
+// rewritePreset replaces the urls on preset, which is in slot on cam.
+func (p *CameraProxy) rewritePreset(hostname string, cam pcconfig.Camera, slot int, preset pcconfig.CameraPreset) pcconfig.CameraPreset {
+	if preset.SetPreset != "" || cam.Protocol != "" {
+		preset.SetPreset = p.url(hostname, cam.DisplayName, pcconfig.ActionSetPreset, url.Values{"preset": {preset.DisplayName}})
+	}
+
+	if preset.SavePreset != "" || cam.Protocol != "" {
+		preset.SavePreset = p.url(hostname, cam.DisplayName, "presets/"+strconv.Itoa(slot), nil)
+	}
+
+	if preset.Thumbnail != "" {
+		preset.Thumbnail = p.url(hostname, cam.DisplayName, "presets/"+strconv.Itoa(slot)+"/thumbnail", nil)
+	}
+
+	return preset
+}
+
+func (p *CameraProxy) url(hostname, camera, action string, query url.Values) string {
+	u := fmt.Sprintf("%s/%s/cameras/%s/%s", strings.TrimSuffix(p.BaseURL, "/"), url.PathEscape(hostname), url.PathEscape(camera), action)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	return u
+}
+
+// allow reports whether a command can be sent to the camera identified by key right now.
+func (p *CameraProxy) allow(key, action string) bool {
+	switch {
+	case p.Limit == 0:
+		return true
+	case action == pcconfig.ActionPanTiltStop || action == pcconfig.ActionZoomStop || action == pcconfig.ActionFocusStop:
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.limiters == nil {
+		p.limiters = make(map[string]*rate.Limiter)
+	}
+
+	l, ok := p.limiters[key]
+	if !ok {
+		l = rate.NewLimiter(p.Limit, p.Burst)
+		p.limiters[key] = l
+	}
+
+	return l.Allow()
+}
+
+// driver returns the driver for cam. Drivers are kept once they are built, so that
+// anything they look up about the camera only has to be looked up once.
+func (p *CameraProxy) driver(cam pcconfig.Camera) (cameras.Driver, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if d, ok := p.drivers[cameraKey(cam)]; ok {
+		return d, nil
+	}
+
+	newDriver, ok := p.Drivers[cam.Protocol]
+	if !ok {
+		return nil, fmt.Errorf("no driver for protocol %q", cam.Protocol)
+	}
+
+	d, err := newDriver(cam.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.drivers == nil {
+		p.drivers = make(map[string]cameras.Driver)
+	}
+
+	p.drivers[cameraKey(cam)] = d
+	return d, nil
+}
+
+// cameraKey identifies the physical camera cam controls, which can be in more than one control group.
+func cameraKey(cam pcconfig.Camera) string {
+	return cam.Protocol + "\x00" + cam.Address
+}
+
+func (p *CameraProxy) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+
+	return http.DefaultClient
+}
+
+// actionURL returns the real URL for action on cam.
+func actionURL(cam pcconfig.Camera, action, preset string) (string, bool) {
+	if action == pcconfig.ActionSetPreset {
+		for _, p := range cam.Presets {
+			if p.DisplayName == preset && p.SetPreset != "" {
+				return p.SetPreset, true
+			}
+		}
+
+		return "", false
+	}
+
+	a, ok := pcconfig.NormalizeCapabilities(cam).Action(action)
+	if !ok || a.URL == "" {
+		return "", false
+	}
+
+	return a.URL, true
+}
+
+// cameraForPC finds the camera named name in the control group hostname is mapped to, using the same
+// view of the datastore as the config hostname is served.
+func (h *Handlers) cameraForPC(ctx context.Context, hostname, name string) (string, string, pcconfig.Camera, int, error) {
+	sets, err := h.changeSets(ctx)
+	if err != nil {
+		return "", "", pcconfig.Camera{}, http.StatusInternalServerError, err
+	}
+
+	cs := h.viewFor(h.readsFor(ctx, hostname), sets, hostname, time.Now())
+
+	room, cg, err := cs.RoomAndControlGroup(ctx, hostname)
+	if err != nil {
+		return "", "", pcconfig.Camera{}, http.StatusInternalServerError, fmt.Errorf("unable to get room/controlGroup: %w", err)
+	}
+
+	cameras, err := cs.Cameras(ctx, room, cg)
+	if err != nil {
+		return "", "", pcconfig.Camera{}, http.StatusInternalServerError, fmt.Errorf("unable to get cameras: %w", err)
+	}
+
+	if overrider, ok := cs.(pcconfig.OverrideService); ok {
+		overrides, _, err := overrider.Overrides(ctx, hostname)
+		if err != nil {
+			return "", "", pcconfig.Camera{}, http.StatusInternalServerError, fmt.Errorf("unable to get overrides: %w", err)
+		}
+
+		if o, ok := overrides.Camera(name); ok && o.Hide {
+			return "", "", pcconfig.Camera{}, http.StatusForbidden, fmt.Errorf("%q is hidden from %s", name, hostname)
+		}
+	}
+
+	for _, cam := range cameras {
+		if cam.DisplayName == name {
+			return room, cg, cam, http.StatusOK, nil
+		}
+	}
+
+	return "", "", pcconfig.Camera{}, http.StatusForbidden, fmt.Errorf("%q is not a camera in %s's control group", name, hostname)
+}
+
+// ControlCamera forwards a camera command from a PC to the camera it controls.
+func (h *Handlers) ControlCamera(c *gin.Context) {
+	if h.CameraProxy == nil {
+		c.String(http.StatusNotFound, "camera proxy is not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	action := c.Param("action")
+
+	room, _, cam, status, err := h.cameraForPC(ctx, c.Param("hostname"), c.Param("camera"))
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	if cam.Protocol != "" {
+		h.driveCamera(ctx, c, room, cam, action)
+		return
+	}
+
+	target, ok := actionURL(cam, action, c.Query("preset"))
+	if !ok {
+		c.String(http.StatusNotFound, fmt.Sprintf("camera %q does not support %q", cam.DisplayName, action))
+		return
+	}
+
+	if !h.CameraProxy.allow(room+"/"+cam.DisplayName, action) {
+		c.String(http.StatusTooManyRequests, "too many camera commands, slow down")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to build request: %s", err))
+		return
+	}
+
+	resp, err := h.CameraProxy.client().Do(req)
+	if err != nil {
+		c.String(http.StatusBadGateway, fmt.Sprintf("unable to make request: %s", err))
+		return
+	}
+	defer resp.Body.Close()
+
+	c.DataFromReader(resp.StatusCode, resp.ContentLength, resp.Header.Get("Content-Type"), resp.Body, nil)
+}
+
+// driveCamera runs action on cam using its driver.
+func (h *Handlers) driveCamera(ctx context.Context, c *gin.Context, room string, cam pcconfig.Camera, action string) {
+	var preset string
+	if action == pcconfig.ActionSetPreset {
+		for _, p := range h.CameraProxy.presetsFor(ctx, cam) {
+			if p.DisplayName == c.Query("preset") {
+				preset = p.Preset
+				break
+			}
+		}
+
+		if preset == "" {
+			c.String(http.StatusNotFound, fmt.Sprintf("camera %q has no preset %q", cam.DisplayName, c.Query("preset")))
+			return
+		}
+	}
+
+	d, err := h.CameraProxy.driver(cam)
+	if err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to build driver: %s", err))
+		return
+	}
+
+	if !h.CameraProxy.allow(room+"/"+cam.DisplayName, action) {
+		c.String(http.StatusTooManyRequests, "too many camera commands, slow down")
+		return
+	}
+
+	err = cameras.Do(ctx, d, action, preset)
+	switch {
+	case errors.Is(err, cameras.ErrUnsupported):
+		c.String(http.StatusNotFound, fmt.Sprintf("camera %q does not support %q", cam.DisplayName, action))
+	case err != nil:
+		c.String(http.StatusBadGateway, fmt.Sprintf("unable to control camera: %s", err))
+	default:
+		c.Status(http.StatusOK)
+	}
+}
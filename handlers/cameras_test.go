@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/cameras"
+	"github.com/gin-gonic/gin"
+)
+
+type mockConfigService struct {
+	mappings map[string][2]string
+	cameras  map[[2]string][]pcconfig.Camera
+}
+
+func (m *mockConfigService) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
+	mapping, ok := m.mappings[hostname]
+	if !ok {
+		return "", "", errors.New("no mapping")
+	}
+
+	return mapping[0], mapping[1], nil
+}
+
+func (m *mockConfigService) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
+	cameras, ok := m.cameras[[2]string{room, controlGroup}]
+	if !ok {
+		return nil, errors.New("no matching control group found")
+	}
+
+	return cameras, nil
+}
+
+func newProxyRouter(t *testing.T, camURL string, burst int) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	h := &Handlers{
+		ConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+				"ITB-1101-CP2": {"ITB-1101", "Group 2"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {
+					{
+						DisplayName: "Front",
+						TiltUp:      camURL + "/tiltUp",
+						PanTiltStop: camURL + "/stop",
+						Presets: []pcconfig.CameraPreset{
+							{DisplayName: "Podium", SetPreset: camURL + "/preset/1"},
+						},
+					},
+				},
+				{"ITB-1101", "Group 2"}: {
+					{DisplayName: "Back", TiltUp: camURL + "/back"},
+				},
+			},
+		},
+		CameraProxy: &CameraProxy{
+			BaseURL: "https://pc-config",
+			Limit:   1,
+			Burst:   burst,
+		},
+	}
+
+	r := gin.New()
+	r.GET("/:hostname/cameras/:camera/:action", h.ControlCamera)
+	return r
+}
+
+func TestRewrite(t *testing.T) {
+	p := &CameraProxy{BaseURL: "https://pc-config/"}
+
+	cams := p.Rewrite("ITB-1101-CP1", []pcconfig.Camera{
+		{
+			DisplayName: "Front Cam",
+			TiltUp:      "http://10.0.0.1/tiltUp?user=admin",
+			Stream:      "http://10.0.0.1/stream",
+			Presets: []pcconfig.CameraPreset{
+				{DisplayName: "Podium", SetPreset: "http://10.0.0.1/preset/1"},
+			},
+		},
+	})
+
+	switch {
+	case cams[0].TiltUp != "https://pc-config/ITB-1101-CP1/cameras/Front%20Cam/tiltUp":
+		t.Fatalf("unexpected tiltUp url: %s", cams[0].TiltUp)
+	case cams[0].TiltDown != "":
+		t.Fatalf("expected tiltDown to stay empty, got %s", cams[0].TiltDown)
+	case cams[0].Stream != "http://10.0.0.1/stream":
+		t.Fatalf("expected stream to be unchanged, got %s", cams[0].Stream)
+	case cams[0].Presets[0].SetPreset != "https://pc-config/ITB-1101-CP1/cameras/Front%20Cam/setPreset?preset=Podium":
+		t.Fatalf("unexpected preset url: %s", cams[0].Presets[0].SetPreset)
+	}
+}
+
+func TestControlCamera(t *testing.T) {
+	var got []string
+	cam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Path)
+	}))
+	defer cam.Close()
+
+	r := newProxyRouter(t, cam.URL, 2)
+
+	for _, path := range []string{
+		"/ITB-1101-CP1/cameras/Front/tiltUp",
+		"/ITB-1101-CP1/cameras/Front/setPreset?preset=Podium",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	if len(got) != 2 || got[0] != "/tiltUp" || got[1] != "/preset/1" {
+		t.Fatalf("camera got unexpected requests: %v", got)
+	}
+}
+
+func TestControlCameraOtherControlGroup(t *testing.T) {
+	r := newProxyRouter(t, "http://camera", 1)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/cameras/Back/tiltUp", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+}
+
+func TestControlCameraRateLimit(t *testing.T) {
+	cam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer cam.Close()
+
+	r := newProxyRouter(t, cam.URL, 1)
+
+	codes := make([]int, 3)
+	for i, path := range []string{
+		"/ITB-1101-CP1/cameras/Front/tiltUp",
+		"/ITB-1101-CP1/cameras/Front/tiltUp",
+		"/ITB-1101-CP1/cameras/Front/panTiltStop",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		codes[i] = w.Code
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests || codes[2] != http.StatusOK {
+		t.Fatalf("unexpected status codes: %v", codes)
+	}
+}
+
+type mockDriver struct {
+	cameras.Driver
+	presets []string
+}
+
+func (m *mockDriver) GoToPreset(ctx context.Context, preset string) error {
+	m.presets = append(m.presets, preset)
+	return nil
+}
+
+func TestControlCameraDriver(t *testing.T) {
+	driver := &mockDriver{}
+
+	h := &Handlers{
+		ConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {
+					{
+						DisplayName: "Front",
+						Address:     "10.0.0.1",
+						Protocol:    "mock",
+						Presets: []pcconfig.CameraPreset{
+							{DisplayName: "Podium", Preset: "2"},
+						},
+					},
+				},
+			},
+		},
+		CameraProxy: &CameraProxy{
+			BaseURL: "https://pc-config",
+			Drivers: map[string]cameras.NewDriverFunc{
+				"mock": func(string) (cameras.Driver, error) {
+					return driver, nil
+				},
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/cameras/:camera/:action", h.ControlCamera)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/cameras/Front/setPreset?preset=Podium", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(driver.presets) != 1 || driver.presets[0] != "2" {
+		t.Fatalf("expected preset 2 to be recalled, got %v", driver.presets)
+	}
+}
+
+type listingDriver struct {
+	cameras.Driver
+	presets []pcconfig.CameraPreset
+	err     error
+	calls   int
+}
+
+func (l *listingDriver) Presets(ctx context.Context) ([]pcconfig.CameraPreset, error) {
+	l.calls++
+	return l.presets, l.err
+}
+
+func TestDiscoverPresets(t *testing.T) {
+	driver := &listingDriver{presets: []pcconfig.CameraPreset{{DisplayName: "Podium", Preset: "1"}}}
+
+	proxy := &CameraProxy{
+		Drivers: map[string]cameras.NewDriverFunc{
+			"mock": func(string) (cameras.Driver, error) {
+				return driver, nil
+			},
+		},
+	}
+
+	cams := []pcconfig.Camera{
+		{DisplayName: "Front", Address: "10.0.0.1", Protocol: "mock"},
+		{DisplayName: "Back", Address: "10.0.0.2", Protocol: "mock", Presets: []pcconfig.CameraPreset{{DisplayName: "Board", Preset: "2"}}},
+	}
+
+	lister := &mockLister{rooms: []pcconfig.Room{
+		{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: cams}}},
+		{ID: "ITB-1102", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: cams[:1]}}},
+	}}
+
+	if cached := proxy.CachedPresets(cams); len(cached[0].Presets) != 0 || driver.calls != 0 {
+		t.Fatalf("expected no presets before discovery, got %+v", cached[0].Presets)
+	}
+
+	if err := proxy.DiscoverPresets(context.Background(), lister); err != nil {
+		t.Fatalf("unable to discover presets: %s", err)
+	}
+
+	// the camera is in two rooms, but it's only asked once
+	cached := proxy.CachedPresets(cams)
+	switch {
+	case driver.calls != 1:
+		t.Fatalf("expected the camera to be asked once, got %d", driver.calls)
+	case len(cached[0].Presets) != 1 || cached[0].Presets[0].Preset != "1":
+		t.Fatalf("got wrong discovered presets: %+v", cached[0].Presets)
+	case len(cached[1].Presets) != 1 || cached[1].Presets[0].Preset != "2":
+		t.Fatalf("configured presets were replaced: %+v", cached[1].Presets)
+	}
+
+	// a camera that can't be reached keeps its presets
+	driver.err = errors.New("unreachable")
+	if err := proxy.DiscoverPresets(context.Background(), lister); err != nil {
+		t.Fatalf("unable to discover presets: %s", err)
+	}
+
+	if cached := proxy.CachedPresets(cams); len(cached[0].Presets) != 1 {
+		t.Fatalf("expected presets to be kept, got %+v", cached[0].Presets)
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+)
+
+// _defaultOverdue is how long a PC can go without checking in before the fleet view reports it.
+const _defaultOverdue = time.Hour
+
+// pcStatus is a PC's latest check in, compared against the config it should be running.
+type pcStatus struct {
+	pcconfig.CheckIn
+
+	// CurrentVersion is the version of the config the PC would get right now.
+	CurrentVersion string `json:"currentVersion,omitempty"`
+
+	Overdue     bool   `json:"overdue"`
+	StaleConfig bool   `json:"staleConfig"`
+	Error       string `json:"error,omitempty"`
+}
+
+// checkInService returns the datastore's CheckInService, or responds with a 404 if it doesn't have one.
+func (h *Handlers) checkInService(c *gin.Context) (pcconfig.CheckInService, bool) {
+	cis, ok := h.ConfigService.(pcconfig.CheckInService)
+	if !ok {
+		c.String(http.StatusNotFound, "check ins are not supported by this datastore")
+	}
+
+	return cis, ok
+}
+
+// CheckIn stores the version of the config a PC applied, along with its app version and uptime.
+func (h *Handlers) CheckIn(c *gin.Context) {
+	cis, ok := h.checkInService(c)
+	if !ok {
+		return
+	}
+
+	var ci pcconfig.CheckIn
+	if err := c.ShouldBindJSON(&ci); err != nil {
+		c.String(http.StatusBadRequest, fmt.Sprintf("invalid check in: %s", err))
+		return
+	}
+
+	ci.Hostname = c.Param("hostname")
+	ci.ConfigVersion = pcconfig.NormalizeVersion(ci.ConfigVersion)
+	ci.Time = time.Now().UTC()
+
+	if ci.ConfigVersion == "" {
+		c.String(http.StatusBadRequest, "configVersion is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := cis.CheckIn(ctx, ci); err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to check in: %s", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CheckIns returns the PCs that haven't checked in within ?overdue= (an hour by default), or whose
+// last check in was for a config other than the one they would get now. If ?all=true, every PC is returned.
+func (h *Handlers) CheckIns(c *gin.Context) {
+	cis, ok := h.checkInService(c)
+	if !ok {
+		return
+	}
+
+	overdue := _defaultOverdue
+	if c.Query("overdue") != "" {
+		var err error
+		if overdue, err = time.ParseDuration(c.Query("overdue")); err != nil {
+			c.String(http.StatusBadRequest, fmt.Sprintf("invalid overdue: %s", err))
+			return
+		}
+	}
+
+	showAll, _ := strconv.ParseBool(c.Query("all"))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	checkIns, err := cis.CheckIns(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to get check ins: %s", err))
+		return
+	}
+
+	now := time.Now()
+
+	sets, err := h.changeSets(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// versions don't include the control key, so it isn't fetched, and each control group is only read once
+	hostnames := make([]string, len(checkIns))
+	for i := range checkIns {
+		hostnames[i] = checkIns[i].Hostname
+	}
+
+	cs := newSharedReads(h.ConfigService)
+	if bs, ok := h.ConfigService.(pcconfig.BatchService); ok {
+		cs.prefetch(ctx, bs, hostnames)
+	}
+
+	all := make([]pcStatus, len(checkIns))
+	inParallel(len(checkIns), func(i int) {
+		all[i] = pcStatus{
+			CheckIn: checkIns[i],
+			Overdue: now.Sub(checkIns[i].Time) > overdue,
+		}
+
+		config, _, err := h.configFrom(ctx, cs, sets, checkIns[i].Hostname, now, true)
+		if err == nil {
+			all[i].CurrentVersion, err = config.Version()
+		}
+
+		switch {
+		case err != nil:
+			all[i].Error = err.Error()
+		case all[i].CurrentVersion != checkIns[i].ConfigVersion:
+			all[i].StaleConfig = true
+		}
+	})
+
+	statuses := []pcStatus{}
+	for _, status := range all {
+		if showAll || status.Overdue || status.StaleConfig || status.Error != "" {
+			statuses = append(statuses, status)
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Hostname < statuses[j].Hostname
+	})
+
+	c.JSON(http.StatusOK, statuses)
+}
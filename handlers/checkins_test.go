@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockCheckInService struct {
+	*mockConfigService
+	checkIns map[string]pcconfig.CheckIn
+}
+
+func (m *mockCheckInService) CheckIn(ctx context.Context, ci pcconfig.CheckIn) error {
+	m.checkIns[ci.Hostname] = ci
+	return nil
+}
+
+func (m *mockCheckInService) CheckIns(ctx context.Context) ([]pcconfig.CheckIn, error) {
+	var checkIns []pcconfig.CheckIn
+	for _, ci := range m.checkIns {
+		checkIns = append(checkIns, ci)
+	}
+
+	return checkIns, nil
+}
+
+type unusedControlKeyService struct {
+	t *testing.T
+}
+
+func (u unusedControlKeyService) ControlKey(ctx context.Context, room, controlGroup string) (string, error) {
+	u.t.Errorf("expected the control key of %s %s not to be fetched", room, controlGroup)
+	return "", errors.New("unused")
+}
+
+func TestCheckIns(t *testing.T) {
+	cs := &mockCheckInService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+				"ITB-1101-CP2": {"ITB-1101", "Group 1"},
+				"ITB-1101-CP3": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {{DisplayName: "Front"}},
+			},
+		},
+		checkIns: make(map[string]pcconfig.CheckIn),
+	}
+
+	h := &Handlers{
+		ConfigService:     cs,
+		ControlKeyService: mockControlKeyService{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+	r.POST("/:hostname/checkin", h.CheckIn)
+	r.GET("/admin/checkins", h.CheckIns)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an etag, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+
+	checkIn := func(hostname, version string) {
+		body := `{"configVersion": ` + version + `, "appVersion": "1.2.3", "uptimeSeconds": 60}`
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/"+hostname+"/checkin", strings.NewReader(body)))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// etags are quoted, so the version is sent as is
+	checkIn("ITB-1101-CP1", etag)
+	checkIn("ITB-1101-CP2", `"old"`)
+	checkIn("ITB-1101-CP3", etag)
+
+	ci := cs.checkIns["ITB-1101-CP3"]
+	ci.Time = ci.Time.Add(-2 * time.Hour)
+	cs.checkIns["ITB-1101-CP3"] = ci
+
+	// versions don't depend on the control key, so it isn't needed to check them
+	h.ControlKeyService = unusedControlKeyService{t}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/checkins", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var statuses []pcStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("unable to parse statuses: %s", err)
+	}
+
+	type problem struct {
+		Hostname    string
+		Overdue     bool
+		StaleConfig bool
+	}
+
+	var got []problem
+	for _, s := range statuses {
+		got = append(got, problem{s.Hostname, s.Overdue, s.StaleConfig})
+	}
+
+	want := []problem{
+		{Hostname: "ITB-1101-CP2", StaleConfig: true},
+		{Hostname: "ITB-1101-CP3", Overdue: true},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong statuses (-want, +got):\n%s", diff)
+	}
+}
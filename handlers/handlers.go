@@ -2,46 +2,293 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/jsonschema"
+	"github.com/byuoitav/pc-config/probe"
+	"github.com/byuoitav/pc-config/schedule"
 	"github.com/gin-gonic/gin"
 )
 
 type Handlers struct {
 	ConfigService     pcconfig.ConfigService
 	ControlKeyService pcconfig.ControlKeyService
+
+	// CameraProxy, if set, hides camera URLs from PCs behind pc-config
+	CameraProxy *CameraProxy
+
+	// Thumbnails stores preset thumbnails. Thumbnails are only served when CameraProxy is set.
+	Thumbnails pcconfig.ThumbnailStore
+
+	// CameraStatus, if set, tracks whether each camera can be reached.
+	CameraStatus *probe.Prober
+
+	// AnnotateCameraStatus adds each camera's status to the config sent to PCs.
+	AnnotateCameraStatus bool
+
+	// Schedule, if set, holds the change sets in memory so they aren't read for every config.
+	Schedule *schedule.Applier
+
+	// DocumentSchemas are the JSON Schemas of the documents in the datastore, published alongside the config schemas.
+	DocumentSchemas map[string]*jsonschema.Schema
+
+	// canaryFetches are the hostnames that have fetched each canary change set, by id, since the change sets were read.
+	canaryMu      sync.Mutex
+	canaryFetches map[string]map[string]bool
+	canaryWG      sync.WaitGroup
+}
+
+// Media types PCs can ask for in the Accept header of the legacy config route.
+const (
+	MediaTypeConfigV1 = "application/vnd.pc-config.v1+json"
+	MediaTypeConfigV2 = "application/vnd.pc-config.v2+json"
+)
+
+// pcConfig is a PC's config, along with how it was found.
+type pcConfig struct {
+	pcconfig.Config
+
+	room         string
+	controlGroup string
+	mapping      string
 }
 
+// ConfigForPC serves the v1 config, unless the PC asks for the v2 config in its Accept header.
 func (h *Handlers) ConfigForPC(c *gin.Context) {
+	c.Header("Vary", "Accept")
+
+	if strings.Contains(c.GetHeader("Accept"), MediaTypeConfigV2) {
+		h.serveConfig(c, pcconfig.ConfigSchemaV2)
+		return
+	}
+
+	h.serveConfig(c, 1)
+}
+
+// ConfigForPCV1 serves the v1 config, which is a pcconfig.Config.
+func (h *Handlers) ConfigForPCV1(c *gin.Context) {
+	h.serveConfig(c, 1)
+}
+
+// ConfigForPCV2 serves the v2 config, which is a pcconfig.ConfigV2.
+func (h *Handlers) ConfigForPCV2(c *gin.Context) {
+	h.serveConfig(c, pcconfig.ConfigSchemaV2)
+}
+
+func (h *Handlers) serveConfig(c *gin.Context, schema int) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	room, cg, err := h.ConfigService.RoomAndControlGroup(ctx, c.Param("hostname"))
+	hostname := c.Param("hostname")
+
+	at := time.Now()
+	if c.Query("at") != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, c.Query("at")); err != nil {
+			c.String(http.StatusBadRequest, fmt.Sprintf("invalid at: %s", err))
+			return
+		}
+	}
+
+	config, status, err := h.config(ctx, hostname, at, c.Query("at") != "")
 	if err != nil {
-		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to get room/controlGroup: %s", err))
+		c.String(status, err.Error())
 		return
 	}
 
-	var config pcconfig.Config
-
-	cameras, err := h.ConfigService.Cameras(ctx, room, cg)
+	// both schemas have the same version, since it's a version of what the PC applies
+	version, err := config.Version()
 	if err != nil {
-		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to get cameras: %s", err))
+		c.String(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	config.Cameras = cameras
+	var body interface{} = config.Config
+	contentType := "application/json; charset=utf-8"
+	if schema == pcconfig.ConfigSchemaV2 {
+		body, contentType = config.v2(hostname, version), MediaTypeConfigV2
+	}
 
-	key, err := h.ControlKeyService.ControlKey(ctx, room, cg)
+	data, err := json.Marshal(body)
 	if err != nil {
-		// ignore this error, just don't set the key
-		c.JSON(http.StatusOK, config)
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to marshal config: %s", err))
+		return
+	}
+
+	// the version leaves out things like the control key, so the tag also covers the body that is sent.
+	// PCs report the tag when they check in, so it starts with the version.
+	etag := fmt.Sprintf(`"%s-%x"`, version, sha1.Sum(data))
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	config.ControlKey = key
-	c.JSON(http.StatusOK, config)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// v2 returns c as a v2 config. version is the version of the v1 config.
+func (c pcConfig) v2(hostname, version string) pcconfig.ConfigV2 {
+	v2 := pcconfig.ConfigV2{
+		SchemaVersion: pcconfig.ConfigSchemaV2,
+		Version:       version,
+		Hostname:      hostname,
+		Room:          c.room,
+		ControlGroup:  c.controlGroup,
+		Mapping:       c.mapping,
+		Cameras:       c.Cameras,
+		Overrides:     c.Overrides,
+	}
+
+	if c.ControlKey != "" {
+		v2.ControlKey = &pcconfig.ControlKey{Key: c.ControlKey}
+	}
+
+	return v2
+}
+
+// config builds the config for hostname at at, including its control key. If preview is true, canary fetches aren't recorded.
+// If an error is returned, status is the http status code that should be sent with it.
+func (h *Handlers) config(ctx context.Context, hostname string, at time.Time, preview bool) (pcConfig, int, error) {
+	sets, err := h.changeSets(ctx)
+	if err != nil {
+		return pcConfig{}, http.StatusInternalServerError, err
+	}
+
+	config, status, err := h.configFrom(ctx, h.readsFor(ctx, hostname), sets, hostname, at, preview)
+	if err != nil {
+		return config, status, err
+	}
+
+	// ignore this error, just don't set the key
+	if key, err := h.ControlKeyService.ControlKey(ctx, config.room, config.controlGroup); err == nil {
+		config.ControlKey = key
+	}
+
+	return config, http.StatusOK, nil
+}
+
+// readsFor returns the datastore with hostname's pc mapping already read, if it can be, so that
+// its room, control group, and overrides don't each have to look it up.
+func (h *Handlers) readsFor(ctx context.Context, hostname string) pcconfig.ConfigService {
+	bs, ok := h.ConfigService.(pcconfig.BatchService)
+	if !ok {
+		return h.ConfigService
+	}
+
+	cs := newSharedReads(h.ConfigService)
+	cs.prefetchMappings(ctx, bs, []string{hostname})
+	return cs
+}
+
+// changeSets returns every change set, or nil if the datastore doesn't support them. They are
+// only read from the datastore if Schedule hasn't loaded them yet.
+func (h *Handlers) changeSets(ctx context.Context) ([]pcconfig.ChangeSet, error) {
+	ss, ok := h.ConfigService.(pcconfig.ScheduleService)
+	if !ok {
+		return nil, nil
+	}
+
+	if h.Schedule != nil {
+		if sets, ok := h.Schedule.ChangeSets(); ok {
+			return sets, nil
+		}
+	}
+
+	sets, err := ss.ChangeSets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get change sets: %w", err)
+	}
+
+	return sets, nil
+}
+
+// viewFor returns cs as hostname sees it at at. Change sets are served as soon as they take effect,
+// even if they haven't been applied yet.
+func (h *Handlers) viewFor(cs pcconfig.ConfigService, sets []pcconfig.ChangeSet, hostname string, at time.Time) pcconfig.ConfigService {
+	if _, ok := h.ConfigService.(pcconfig.ScheduleService); !ok {
+		return cs
+	}
+
+	return schedule.At(cs, schedule.ForHostname(sets, hostname), at)
+}
+
+// configFrom builds the config for hostname from cs, layering sets on top of it. The control key isn't set.
+func (h *Handlers) configFrom(ctx context.Context, cs pcconfig.ConfigService, sets []pcconfig.ChangeSet, hostname string, at time.Time, preview bool) (pcConfig, int, error) {
+	var config pcConfig
+
+	cs = h.viewFor(cs, sets, hostname, at)
+
+	room, cg, err := cs.RoomAndControlGroup(ctx, hostname)
+	if err != nil {
+		return config, http.StatusInternalServerError, fmt.Errorf("unable to get room/controlGroup: %w", err)
+	}
+
+	config.room, config.controlGroup = room, cg
+
+	cameras, err := cs.Cameras(ctx, room, cg)
+	if err != nil {
+		return config, http.StatusInternalServerError, fmt.Errorf("unable to get cameras: %w", err)
+	}
+
+	for i := range cameras {
+		cameras[i] = pcconfig.NormalizeCapabilities(cameras[i])
+	}
+
+	if h.CameraStatus != nil && h.AnnotateCameraStatus {
+		cameras = h.CameraStatus.Annotate(room, cg, cameras)
+	}
+
+	if h.CameraProxy != nil {
+		cameras = h.CameraProxy.CachedPresets(cameras)
+		cameras = h.CameraProxy.Rewrite(hostname, cameras)
+	} else {
+		cameras = withoutThumbnails(cameras)
+	}
+
+	// overrides go last so that proxy urls and preset slots still refer to the control group's cameras
+	if overrider, ok := cs.(pcconfig.OverrideService); ok {
+		overrides, source, err := overrider.Overrides(ctx, hostname)
+		if err != nil {
+			return config, http.StatusInternalServerError, fmt.Errorf("unable to get overrides: %w", err)
+		}
+
+		config.mapping = source
+
+		if !overrides.Empty() {
+			cameras = pcconfig.ApplyOverrides(cameras, overrides)
+			config.Overrides = &pcconfig.AppliedOverrides{
+				Source:  source,
+				Cameras: overrides.Cameras,
+			}
+		}
+	}
+
+	config.Cameras = cameras
+
+	if ss, ok := h.ConfigService.(pcconfig.ScheduleService); ok && !preview {
+		h.recordCanaryFetches(ss, sets, hostname, at, config)
+	}
+
+	return config, http.StatusOK, nil
+}
+
+// datastoreStatus returns the http status code to send with an error from the datastore. Datastores
+// return errors that carry a 404 status code for things (like a rev or thumbnail) that don't exist.
+func datastoreStatus(err error) int {
+	var coder interface{ StatusCode() int }
+	if errors.As(err, &coder) && coder.StatusCode() == http.StatusNotFound {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
 }
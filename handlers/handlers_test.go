@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockOverrideService struct {
+	*mockConfigService
+	overrides pcconfig.PCOverrides
+}
+
+func (m *mockOverrideService) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	return m.overrides, "ITB-1101-CP", nil
+}
+
+func TestConfigForPCOverrides(t *testing.T) {
+	cs := &mockOverrideService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {
+					{
+						DisplayName: "Front",
+						TiltUp:      "https://front/tiltUp",
+						PanTiltStop: "https://front/stop",
+						Stream:      "https://front/stream",
+						Presets: []pcconfig.CameraPreset{
+							{DisplayName: "Podium", SetPreset: "https://front/preset/1"},
+							{DisplayName: "Board", SetPreset: "https://front/preset/2"},
+						},
+					},
+					{DisplayName: "Back", TiltUp: "https://back/tiltUp", PanTiltStop: "https://back/stop"},
+				},
+			},
+		},
+		overrides: pcconfig.PCOverrides{
+			Cameras: []pcconfig.CameraOverride{
+				{Camera: "Front", DisplayName: "Lectern", Stream: "https://front/stream-low", PresetOrder: []string{"Board"}},
+				{Camera: "Back", Hide: true},
+			},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService:     cs,
+		ControlKeyService: mockControlKeyService{},
+		CameraProxy:       &CameraProxy{BaseURL: "https://pc-config"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+	r.GET("/:hostname/cameras/:camera/:action", h.ControlCamera)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config", nil))
+
+	var config pcconfig.Config
+	if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+		t.Fatalf("unable to parse config: %s", err)
+	}
+
+	switch {
+	case len(config.Cameras) != 1:
+		t.Fatalf("expected hidden camera to be removed, got %+v", config.Cameras)
+	case config.Cameras[0].DisplayName != "Lectern" || config.Cameras[0].Stream != "https://front/stream-low":
+		t.Fatalf("expected camera to be renamed with a new stream, got %+v", config.Cameras[0])
+	case config.Cameras[0].TiltUp != "https://pc-config/ITB-1101-CP1/cameras/Front/tiltUp":
+		t.Fatalf("expected proxy urls to use the original camera name, got %q", config.Cameras[0].TiltUp)
+	case config.Cameras[0].Presets[0].DisplayName != "Board":
+		t.Fatalf("expected presets to be reordered, got %+v", config.Cameras[0].Presets)
+	case config.Overrides == nil || config.Overrides.Source != "ITB-1101-CP":
+		t.Fatalf("expected override source in config, got %+v", config.Overrides)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/cameras/Back/tiltUp", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a hidden camera, got %d", w.Code)
+	}
+}
+
+type mockKeyService struct {
+	key string
+}
+
+func (m *mockKeyService) ControlKey(ctx context.Context, room, controlGroup string) (string, error) {
+	return m.key, nil
+}
+
+func TestConfigForPCVersions(t *testing.T) {
+	h := &Handlers{
+		ConfigService: &mockOverrideService{
+			mockConfigService: &mockConfigService{
+				mappings: map[string][2]string{
+					"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+				},
+				cameras: map[[2]string][]pcconfig.Camera{
+					{"ITB-1101", "Group 1"}: {{DisplayName: "Front"}},
+				},
+			},
+		},
+		ControlKeyService: &mockKeyService{key: "1234"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+	r.GET("/v1/pcs/:hostname/config", h.ConfigForPCV1)
+	r.GET("/v2/pcs/:hostname/config", h.ConfigForPCV2)
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+
+		return w
+	}
+
+	legacy := get("/ITB-1101-CP1/config", "")
+	v1 := get("/v1/pcs/ITB-1101-CP1/config", "")
+	if legacy.Body.String() != v1.Body.String() {
+		t.Errorf("expected legacy route to serve the v1 config, got %s", legacy.Body.String())
+	}
+
+	expected := pcconfig.ConfigV2{
+		SchemaVersion: pcconfig.ConfigSchemaV2,
+		Version:       pcconfig.NormalizeVersion(v1.Header().Get("ETag")),
+		Hostname:      "ITB-1101-CP1",
+		Room:          "ITB-1101",
+		ControlGroup:  "Group 1",
+		Mapping:       "ITB-1101-CP",
+		Cameras:       []pcconfig.Camera{{DisplayName: "Front", Presets: []pcconfig.CameraPreset{}}},
+		ControlKey:    &pcconfig.ControlKey{Key: "1234"},
+	}
+
+	for _, w := range []*httptest.ResponseRecorder{
+		get("/v2/pcs/ITB-1101-CP1/config", ""),
+		get("/ITB-1101-CP1/config", MediaTypeConfigV2),
+	} {
+		if ct := w.Header().Get("Content-Type"); ct != MediaTypeConfigV2 {
+			t.Errorf("expected content type %q, got %q", MediaTypeConfigV2, ct)
+		}
+
+		var config pcconfig.ConfigV2
+		if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+			t.Fatalf("unable to parse config: %s", err)
+		}
+
+		if diff := cmp.Diff(expected, config); diff != "" {
+			t.Errorf("got incorrect v2 config (-want, +got):\n%s", diff)
+		}
+
+		// the v2 body has its own tag, which is still the same version when a PC checks in with it
+		switch etag := w.Header().Get("ETag"); {
+		case etag == v1.Header().Get("ETag"):
+			t.Errorf("expected the v2 config to have a different etag than the v1 config")
+		case pcconfig.NormalizeVersion(etag) != expected.Version:
+			t.Errorf("expected etag %s to be version %s", etag, expected.Version)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/pcs/ITB-1101-CP1/config", nil)
+	req.Header.Set("If-None-Match", v1.Header().Get("ETag"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the v1 etag not to match the v2 config, got %d", w.Code)
+	}
+}
+
+func TestConfigForPCKeyRotation(t *testing.T) {
+	keys := &mockKeyService{key: "1234"}
+	h := &Handlers{
+		ConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {{DisplayName: "Front"}},
+			},
+		},
+		ControlKeyService: keys,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an etag, got %d", first.Code)
+	}
+
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+
+	keys.key = "5678"
+
+	w := get(etag)
+	switch {
+	case w.Code != http.StatusOK:
+		t.Fatalf("expected 200 after the key was rotated, got %d", w.Code)
+	case !strings.Contains(w.Body.String(), `"5678"`):
+		t.Fatalf("expected the new key, got %s", w.Body.String())
+	case pcconfig.NormalizeVersion(w.Header().Get("ETag")) != pcconfig.NormalizeVersion(etag):
+		t.Errorf("expected the version to stay the same, got %s and %s", etag, w.Header().Get("ETag"))
+	}
+}
+
+type lookupCounter struct {
+	*mockBatchService
+	lookups int
+}
+
+func (l *lookupCounter) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
+	l.lookups++
+	return l.mockBatchService.RoomAndControlGroup(ctx, hostname)
+}
+
+func (l *lookupCounter) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	l.lookups++
+	return pcconfig.PCOverrides{}, hostname, nil
+}
+
+func TestConfigForPCOneLookup(t *testing.T) {
+	cs := &lookupCounter{
+		mockBatchService: &mockBatchService{
+			countingConfigService: &countingConfigService{
+				mockConfigService: &mockConfigService{
+					mappings: map[string][2]string{
+						"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+					},
+					cameras: map[[2]string][]pcconfig.Camera{
+						{"ITB-1101", "Group 1"}: {{DisplayName: "Front"}},
+					},
+				},
+				reads: make(map[[2]string]int),
+			},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService:     cs,
+		ControlKeyService: mockControlKeyService{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config", nil))
+	switch {
+	case w.Code != http.StatusOK:
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	case cs.batches != 1 || cs.lookups != 0:
+		t.Fatalf("expected the mapping to be read once, got %d batches and %d lookups", cs.batches, cs.lookups)
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+)
+
+type rollbackRequest struct {
+	Rev string `json:"rev"`
+}
+
+type rollbackResponse struct {
+	Rev string `json:"rev"`
+}
+
+// historyService returns the datastore's HistoryService, or responds with a 404 if it doesn't have one.
+func (h *Handlers) historyService(c *gin.Context) (pcconfig.HistoryService, bool) {
+	hs, ok := h.ConfigService.(pcconfig.HistoryService)
+	if !ok {
+		c.String(http.StatusNotFound, "history is not supported by this datastore")
+	}
+
+	return hs, ok
+}
+
+// RoomHistory returns the revisions of a room's config.
+func (h *Handlers) RoomHistory(c *gin.Context) {
+	hs, ok := h.historyService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	revs, err := hs.RoomHistory(ctx, c.Param("room"))
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to get history: %s", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, revs)
+}
+
+// RoomDiff returns the changes to a room's cameras between the revisions from and to.
+// If to isn't given, from is compared to the current config.
+func (h *Handlers) RoomDiff(c *gin.Context) {
+	hs, ok := h.historyService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if c.Query("from") == "" {
+		c.String(http.StatusBadRequest, "from is required")
+		return
+	}
+
+	from, err := hs.RoomAt(ctx, c.Param("room"), c.Query("from"))
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to get from revision: %s", err))
+		return
+	}
+
+	to, err := hs.RoomAt(ctx, c.Param("room"), c.Query("to"))
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to get to revision: %s", err))
+		return
+	}
+
+	changes := pcconfig.DiffRooms(from, to)
+	if changes == nil {
+		changes = []pcconfig.Change{}
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// RollbackRoom replaces a room's config with a previous revision of it.
+func (h *Handlers) RollbackRoom(c *gin.Context) {
+	hs, ok := h.historyService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var req rollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Rev == "" {
+		c.String(http.StatusBadRequest, "body must include the rev to roll back to")
+		return
+	}
+
+	rev, err := hs.RollbackRoom(ctx, c.Param("room"), req.Rev)
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to roll back: %s", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, rollbackResponse{Rev: rev})
+}
+
+// MappingHistory returns the revisions of a pc mapping.
+func (h *Handlers) MappingHistory(c *gin.Context) {
+	hs, ok := h.historyService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	revs, err := hs.MappingHistory(ctx, c.Param("hostname"))
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to get history: %s", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, revs)
+}
+
+// MappingDiff returns the changes to a pc mapping between the revisions from and to.
+// If to isn't given, from is compared to the current mapping.
+func (h *Handlers) MappingDiff(c *gin.Context) {
+	hs, ok := h.historyService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if c.Query("from") == "" {
+		c.String(http.StatusBadRequest, "from is required")
+		return
+	}
+
+	from, err := hs.MappingAt(ctx, c.Param("hostname"), c.Query("from"))
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to get from revision: %s", err))
+		return
+	}
+
+	to, err := hs.MappingAt(ctx, c.Param("hostname"), c.Query("to"))
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to get to revision: %s", err))
+		return
+	}
+
+	changes := pcconfig.DiffMappings(from, to)
+	if changes == nil {
+		changes = []pcconfig.Change{}
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// RollbackMapping replaces a pc mapping with a previous revision of it.
+func (h *Handlers) RollbackMapping(c *gin.Context) {
+	hs, ok := h.historyService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var req rollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Rev == "" {
+		c.String(http.StatusBadRequest, "body must include the rev to roll back to")
+		return
+	}
+
+	rev, err := hs.RollbackMapping(ctx, c.Param("hostname"), req.Rev)
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to roll back: %s", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, rollbackResponse{Rev: rev})
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+	"github.com/go-kivik/kivik/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockHistoryService struct {
+	*mockConfigService
+
+	rooms      map[string]pcconfig.Room
+	mappings   map[string]pcconfig.PCMapping
+	rolledBack string
+}
+
+func (m *mockHistoryService) RoomHistory(ctx context.Context, room string) ([]pcconfig.Revision, error) {
+	return []pcconfig.Revision{{Rev: "2-b", Available: true}, {Rev: "1-a", Available: true}}, nil
+}
+
+func (m *mockHistoryService) RoomAt(ctx context.Context, room, rev string) (pcconfig.Room, error) {
+	if rev == "" {
+		rev = "2-b"
+	}
+
+	r, ok := m.rooms[rev]
+	if !ok {
+		return pcconfig.Room{}, fmt.Errorf("unable to get %s at %q: %w", room, rev, &kivik.Error{HTTPStatus: http.StatusNotFound, Err: errors.New("missing")})
+	}
+
+	return r, nil
+}
+
+func (m *mockHistoryService) RollbackRoom(ctx context.Context, room, rev string) (string, error) {
+	if _, ok := m.rooms[rev]; !ok {
+		return "", fmt.Errorf("unable to get %s at %q: %w", room, rev, &kivik.Error{HTTPStatus: http.StatusNotFound, Err: errors.New("missing")})
+	}
+
+	m.rolledBack = rev
+	return "3-c", nil
+}
+
+func (m *mockHistoryService) MappingHistory(ctx context.Context, hostname string) ([]pcconfig.Revision, error) {
+	return nil, nil
+}
+
+func (m *mockHistoryService) MappingAt(ctx context.Context, hostname, rev string) (pcconfig.PCMapping, error) {
+	if rev == "" {
+		rev = "2-b"
+	}
+
+	mapping, ok := m.mappings[rev]
+	if !ok {
+		return pcconfig.PCMapping{}, fmt.Errorf("unable to get %s at %q: %w", hostname, rev, &kivik.Error{HTTPStatus: http.StatusNotFound, Err: errors.New("missing")})
+	}
+
+	return mapping, nil
+}
+
+func (m *mockHistoryService) RollbackMapping(ctx context.Context, hostname, rev string) (string, error) {
+	return "", nil
+}
+
+func TestRoomDiffAndRollback(t *testing.T) {
+	hs := &mockHistoryService{
+		mockConfigService: &mockConfigService{},
+		rooms: map[string]pcconfig.Room{
+			"1-a": {ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front", Stream: "http://front/1"}}}}},
+			"2-b": {ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front", Stream: "http://front/2"}}}}},
+		},
+	}
+
+	h := &Handlers{ConfigService: hs}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/rooms/:room/diff", h.RoomDiff)
+	r.POST("/admin/rooms/:room/rollback", h.RollbackRoom)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/rooms/ITB-1101/diff?from=1-a", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var changes []pcconfig.Change
+	if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("unable to parse changes: %s", err)
+	}
+
+	expected := []pcconfig.Change{
+		{Type: pcconfig.ChangeModified, ControlGroup: "Group 1", Camera: "Front", Field: "stream", From: "http://front/1", To: "http://front/2"},
+	}
+
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Errorf("got incorrect changes (-want, +got):\n%s", diff)
+	}
+
+	for _, path := range []string{"/admin/rooms/ITB-1101/diff?from=9-z", "/admin/rooms/ITB-1101/diff?from=1-a&to=9-z"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 for an unknown rev, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/rooms/ITB-1101/rollback", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a rev, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/rooms/ITB-1101/rollback", strings.NewReader(`{"rev": "1-a"}`)))
+	switch {
+	case w.Code != http.StatusOK:
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	case hs.rolledBack != "1-a":
+		t.Fatalf("expected room to be rolled back to 1-a, got %q", hs.rolledBack)
+	case strings.TrimSpace(w.Body.String()) != `{"rev":"3-c"}`:
+		t.Fatalf("got wrong response: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/rooms/ITB-1101/rollback", strings.NewReader(`{"rev": "9-z"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown rev, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMappingDiff(t *testing.T) {
+	hs := &mockHistoryService{
+		mockConfigService: &mockConfigService{},
+		mappings: map[string]pcconfig.PCMapping{
+			"1-a": {Hostname: "ITB-1101-CP", Room: "ITB-1101", ControlGroup: "Group 1"},
+			"2-b": {Hostname: "ITB-1101-CP", Room: "ITB-1101", ControlGroup: "Group 2"},
+		},
+	}
+
+	h := &Handlers{ConfigService: hs}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/admin/mappings/:hostname/diff", h.MappingDiff)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/mappings/ITB-1101-CP/diff?from=1-a", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var changes []pcconfig.Change
+	if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+		t.Fatalf("unable to parse changes: %s", err)
+	}
+
+	expected := []pcconfig.Change{
+		{Type: pcconfig.ChangeModified, Field: "controlGroup", From: "Group 1", To: "Group 2"},
+	}
+
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Errorf("got incorrect changes (-want, +got):\n%s", diff)
+	}
+
+	for path, code := range map[string]int{
+		"/admin/mappings/ITB-1101-CP/diff":          http.StatusBadRequest,
+		"/admin/mappings/ITB-1101-CP/diff?from=9-z": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d: %s", path, code, w.Code, w.Body.String())
+		}
+	}
+}
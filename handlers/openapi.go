@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/openapi"
+	"github.com/byuoitav/pc-config/probe"
+	"github.com/byuoitav/pc-config/validate"
+	"github.com/gin-gonic/gin"
+)
+
+// PCPrefixes are the paths the routes PCs use are registered under, oldest first.
+var PCPrefixes = []string{"/:hostname", "/v1/pcs/:hostname", "/v2/pcs/:hostname"}
+
+// Spec returns the OpenAPI document describing every route pc-config serves.
+// It has to be updated whenever a route is added to cmd/pc-config.
+func Spec() *openapi.Document {
+	atQuery := map[string]string{"at": "RFC3339 time to preview the config at, including scheduled changes"}
+	adminRoomQuery := map[string]string{
+		"at":         atQuery["at"],
+		"controlKey": "if true, include the control key of each control group",
+	}
+
+	routes := []openapi.Route{
+		{Method: http.MethodGet, Path: "/:hostname", Summary: "Health check, when hostname is healthz", Tag: "pc", ContentType: "text/plain"},
+		{Method: http.MethodGet, Path: "/openapi.json", Summary: "This document", Response: map[string]interface{}{}},
+		{Method: http.MethodGet, Path: "/schemas", Summary: "List the names of the JSON Schemas", Tag: "schemas", Response: []string{}},
+		{Method: http.MethodGet, Path: "/schemas/:name", Summary: "Get a JSON Schema, like config or ui-configuration", Tag: "schemas", Response: map[string]interface{}{}},
+		{Method: http.MethodGet, Path: "/metrics", Summary: "Camera reachability in the prometheus text format", Tag: "status", ContentType: "text/plain"},
+
+		{Method: http.MethodGet, Path: "/:hostname/config", Summary: "Get a PC's config. Sends the v2 config if the Accept header includes " + MediaTypeConfigV2, Tag: "pc", Query: atQuery, Response: pcconfig.Config{}},
+		{Method: http.MethodGet, Path: "/v1/pcs/:hostname/config", Summary: "Get a PC's config", Tag: "pc", Query: atQuery, Response: pcconfig.Config{}},
+		{Method: http.MethodGet, Path: "/v2/pcs/:hostname/config", Summary: "Get a PC's expanded config", Tag: "pc", Query: atQuery, Response: pcconfig.ConfigV2{}, ContentType: MediaTypeConfigV2},
+
+		{Method: http.MethodPost, Path: "/configs", Summary: "Get the expanded configs of many PCs at once, with an error for each PC whose config can't be built, without control keys", Tag: "pc", Query: atQuery, Request: bulkRequest{}, Response: bulkResponse{}},
+
+		{Method: http.MethodGet, Path: "/rooms/:room/config", Summary: "Get the config of every control group in a room, without control keys", Tag: "rooms", Query: atQuery, Response: pcconfig.RoomConfig{}},
+		{Method: http.MethodGet, Path: "/rooms/:room/controlgroups/:controlGroup/config", Summary: "Get the config of a control group, without its control key", Tag: "rooms", Query: atQuery, Response: pcconfig.ControlGroupConfig{}},
+	}
+
+	for _, prefix := range PCPrefixes {
+		routes = append(routes,
+			openapi.Route{Method: http.MethodPost, Path: prefix + "/checkin", Summary: "Report the config version a PC applied", Tag: "pc", Request: pcconfig.CheckIn{}, Status: http.StatusNoContent},
+			openapi.Route{Method: http.MethodGet, Path: prefix + "/cameras/:camera/:action", Summary: "Control a camera in the PC's control group", Tag: "pc", Query: map[string]string{"preset": "name of the preset to go to, for the setPreset action"}},
+			openapi.Route{Method: http.MethodPut, Path: prefix + "/cameras/:camera/presets/:slot", Summary: "Save a camera's current position as a preset", Tag: "pc", Request: savePresetRequest{}, Response: pcconfig.CameraPreset{}},
+			openapi.Route{Method: http.MethodGet, Path: prefix + "/cameras/:camera/presets/:slot/thumbnail", Summary: "Get the thumbnail of a preset", Tag: "pc", ContentType: "image/jpeg"},
+			openapi.Route{Method: http.MethodPost, Path: prefix + "/cameras/:camera/presets/:slot/thumbnail", Summary: "Capture the thumbnail of a preset from the camera", Tag: "pc", Status: http.StatusNoContent},
+		)
+	}
+
+	admin := []openapi.Route{
+		{Method: http.MethodPut, Path: "/admin/rooms/:room/controlgroups/:controlGroup/cameras/:camera/presets/:slot/thumbnail", Summary: "Upload the thumbnail of a preset", RequestContentType: "image/jpeg", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/admin/validate", Summary: "Validate every room and pc mapping", Response: validate.Report{}},
+		{Method: http.MethodGet, Path: "/admin/report", Summary: "Compare pc mappings against rooms", Response: validate.ConsistencyReport{}},
+		{Method: http.MethodGet, Path: "/admin/cameras/status", Summary: "Get the reachability of every camera", Query: map[string]string{"room": "only return cameras in this room"}, Response: []probe.Status{}},
+		{Method: http.MethodGet, Path: "/admin/rooms/:room/history", Summary: "Get the revisions of a room", Response: []pcconfig.Revision{}},
+		{Method: http.MethodGet, Path: "/admin/rooms/:room/diff", Summary: "Compare two revisions of a room", Query: map[string]string{"from": "revision to compare from", "to": "revision to compare to. defaults to the current config"}, Response: []pcconfig.Change{}},
+		{Method: http.MethodPost, Path: "/admin/rooms/:room/rollback", Summary: "Restore a previous revision of a room", Request: rollbackRequest{}, Response: rollbackResponse{}},
+		{Method: http.MethodGet, Path: "/admin/mappings/:hostname/history", Summary: "Get the revisions of a pc mapping", Response: []pcconfig.Revision{}},
+		{Method: http.MethodGet, Path: "/admin/mappings/:hostname/diff", Summary: "Compare two revisions of a pc mapping", Query: map[string]string{"from": "revision to compare from", "to": "revision to compare to. defaults to the current mapping"}, Response: []pcconfig.Change{}},
+		{Method: http.MethodPost, Path: "/admin/mappings/:hostname/rollback", Summary: "Restore a previous revision of a pc mapping", Request: rollbackRequest{}, Response: rollbackResponse{}},
+		{Method: http.MethodGet, Path: "/admin/changesets", Summary: "Get every change set", Response: []pcconfig.ChangeSet{}},
+		{Method: http.MethodPut, Path: "/admin/changesets/:id", Summary: "Create or replace a change set", Request: pcconfig.ChangeSet{}, Response: pcconfig.ChangeSet{}},
+		{Method: http.MethodDelete, Path: "/admin/changesets/:id", Summary: "Delete a change set", Status: http.StatusNoContent},
+		{Method: http.MethodPost, Path: "/admin/changesets/:id/apply", Summary: "Apply a change set now", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/admin/changesets/:id/rollout", Summary: "Get the progress of a canary change set", Response: rolloutStatus{}},
+		{Method: http.MethodPost, Path: "/admin/changesets/:id/promote", Summary: "Apply a canary change set to every PC", Status: http.StatusNoContent},
+		{Method: http.MethodPost, Path: "/admin/changesets/:id/abort", Summary: "Delete a canary change set", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/admin/checkins", Summary: "Get PCs that are overdue to check in or running stale configs", Query: map[string]string{"overdue": "how long a PC can go without checking in. defaults to 1h", "all": "if true, return every PC"}, Response: []pcStatus{}},
+		{Method: http.MethodPost, Path: "/admin/configs", Summary: "Get the expanded configs of many PCs at once, with their control keys and an error for each PC whose config can't be built", Query: atQuery, Request: bulkRequest{}, Response: bulkResponse{}},
+		{Method: http.MethodGet, Path: "/admin/rooms/:room/config", Summary: "Get the config of every control group in a room", Query: adminRoomQuery, Response: pcconfig.RoomConfig{}},
+		{Method: http.MethodGet, Path: "/admin/rooms/:room/controlgroups/:controlGroup/config", Summary: "Get the config of a control group", Query: adminRoomQuery, Response: pcconfig.ControlGroupConfig{}},
+	}
+
+	for _, r := range admin {
+		r.Tag = "admin"
+		r.Secured = true
+		routes = append(routes, r)
+	}
+
+	return openapi.New(openapi.Info{
+		Title:       "pc-config",
+		Description: "Serves camera configuration to classroom PCs.",
+		Version:     "2",
+	}, routes)
+}
+
+// OpenAPI serves the OpenAPI document describing pc-config.
+func (h *Handlers) OpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, Spec())
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite openapi.json with the current spec")
+
+// _specFile is the copy of the spec checked in for PC developers.
+const _specFile = "../openapi.json"
+
+func TestSpecFile(t *testing.T) {
+	spec, err := json.MarshalIndent(Spec(), "", "  ")
+	if err != nil {
+		t.Fatalf("unable to marshal spec: %s", err)
+	}
+
+	spec = append(spec, '\n')
+
+	if *update {
+		if err := ioutil.WriteFile(_specFile, spec, 0644); err != nil {
+			t.Fatalf("unable to write spec: %s", err)
+		}
+	}
+
+	checkedIn, err := ioutil.ReadFile(_specFile)
+	if err != nil {
+		t.Fatalf("unable to read spec: %s", err)
+	}
+
+	if !bytes.Equal(spec, checkedIn) {
+		t.Errorf("%s is out of date. run go test ./handlers -run TestSpecFile -update to regenerate it", _specFile)
+	}
+}
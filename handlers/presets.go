@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/cameras"
+	"github.com/gin-gonic/gin"
+)
+
+type savePresetRequest struct {
+	DisplayName string `json:"displayName"`
+}
+
+// SaveCameraPreset stores a camera's current position in one of its preset
+// slots, and optionally renames the preset. PCs can only save presets for
+// cameras in their own control group.
+func (h *Handlers) SaveCameraPreset(c *gin.Context) {
+	presetService, ok := h.ConfigService.(pcconfig.CameraPresetService)
+	if h.CameraProxy == nil || !ok {
+		c.String(http.StatusNotFound, "saving presets is not enabled")
+		return
+	}
+
+	var req savePresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.String(http.StatusBadRequest, fmt.Sprintf("invalid request: %s", err))
+		return
+	}
+
+	slot, err := strconv.Atoi(c.Param("slot"))
+	if err != nil || slot < 0 {
+		c.String(http.StatusBadRequest, fmt.Sprintf("invalid preset slot %q", c.Param("slot")))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	hostname := c.Param("hostname")
+
+	room, cg, cam, status, err := h.cameraForPC(ctx, hostname, c.Param("camera"))
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	// presets the camera reports are served when none are configured, so they are the slots the PC sees
+	discovered := len(cam.Presets) == 0
+	cam = h.CameraProxy.CachedPresets([]pcconfig.Camera{cam})[0]
+	discovered = discovered && len(cam.Presets) > 0
+
+	var preset pcconfig.CameraPreset
+	switch {
+	case slot < len(cam.Presets):
+		preset = cam.Presets[slot]
+	case slot == len(cam.Presets) && cam.Protocol != "":
+		// new presets on cameras we control are numbered by their slot
+		preset = pcconfig.CameraPreset{Preset: strconv.Itoa(slot + 1)}
+	default:
+		c.String(http.StatusNotFound, fmt.Sprintf("camera %q has no preset slot %d", cam.DisplayName, slot))
+		return
+	}
+
+	if err := h.CameraProxy.savePreset(ctx, cam, preset); err != nil {
+		c.String(http.StatusBadGateway, fmt.Sprintf("unable to save preset: %s", err))
+		return
+	}
+
+	if h.Thumbnails != nil && cam.Snapshot != "" {
+		// a missing thumbnail isn't worth failing the save over
+		if jpeg, err := h.CameraProxy.snapshot(ctx, cam); err == nil {
+			key := thumbnailKey(cg, cam.DisplayName, slot)
+			if err := h.Thumbnails.PutThumbnail(ctx, room, key, jpeg); err == nil {
+				preset.Thumbnail = key
+			}
+		}
+	}
+
+	if req.DisplayName != "" {
+		preset.DisplayName = req.DisplayName
+	}
+
+	if preset.DisplayName == "" {
+		preset.DisplayName = fmt.Sprintf("Preset %d", slot+1)
+	}
+
+	presets := []pcconfig.CameraPreset{preset}
+	start := slot
+	if discovered {
+		// configured presets replace the discovered ones, so the others are stored too to keep them
+		presets, start = append([]pcconfig.CameraPreset(nil), cam.Presets...), 0
+		if slot < len(presets) {
+			presets[slot] = preset
+		} else {
+			presets = append(presets, preset)
+		}
+	}
+
+	for i, p := range presets {
+		if err := presetService.SetCameraPreset(ctx, room, cg, cam.DisplayName, start+i, p); err != nil {
+			c.String(http.StatusInternalServerError, fmt.Sprintf("unable to update preset: %s", err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, h.CameraProxy.rewritePreset(hostname, cam, slot, preset))
+}
+
+// savePreset stores cam's current position in preset.
+func (p *CameraProxy) savePreset(ctx context.Context, cam pcconfig.Camera, preset pcconfig.CameraPreset) error {
+	if cam.Protocol != "" {
+		d, err := p.driver(cam)
+		if err != nil {
+			return fmt.Errorf("unable to build driver: %w", err)
+		}
+
+		saver, ok := d.(cameras.PresetSaver)
+		if !ok {
+			return fmt.Errorf("%s cameras can't save presets: %w", cam.Protocol, cameras.ErrUnsupported)
+		}
+
+		return saver.SavePreset(ctx, preset.Preset)
+	}
+
+	if preset.SavePreset == "" {
+		return fmt.Errorf("preset has no save url: %w", cameras.ErrUnsupported)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, preset.SavePreset, nil)
+	if err != nil {
+		return fmt.Errorf("unable to build request: %w", err)
+	}
+
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("camera returned a %v", resp.StatusCode)
+	}
+
+	return nil
+}
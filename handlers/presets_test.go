@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/cameras"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockPresetService struct {
+	*mockConfigService
+
+	slot   int
+	preset pcconfig.CameraPreset
+
+	// stored is every preset stored, by slot
+	stored []pcconfig.CameraPreset
+}
+
+func (m *mockPresetService) SetCameraPreset(ctx context.Context, room, controlGroup, camera string, slot int, preset pcconfig.CameraPreset) error {
+	m.slot = slot
+	m.preset = preset
+
+	if slot == len(m.stored) {
+		m.stored = append(m.stored, preset)
+	} else {
+		m.stored[slot] = preset
+	}
+
+	return nil
+}
+
+func TestSaveCameraPreset(t *testing.T) {
+	var saved []string
+	cam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		saved = append(saved, r.URL.Path)
+	}))
+	defer cam.Close()
+
+	ps := &mockPresetService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {
+					{
+						DisplayName: "Front",
+						Presets: []pcconfig.CameraPreset{
+							{DisplayName: "Preset 1", SetPreset: cam.URL + "/preset/1", SavePreset: cam.URL + "/save/1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService: ps,
+		CameraProxy:   &CameraProxy{BaseURL: "https://pc-config"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/:hostname/cameras/:camera/presets/:slot", h.SaveCameraPreset)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/ITB-1101-CP1/cameras/Front/presets/0", strings.NewReader(`{"displayName": "Podium"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(saved) != 1 || saved[0] != "/save/1" {
+		t.Fatalf("expected camera to save preset, got %v", saved)
+	}
+
+	if ps.slot != 0 || ps.preset.DisplayName != "Podium" || ps.preset.SetPreset != cam.URL+"/preset/1" {
+		t.Fatalf("stored incorrect preset in slot %d: %+v", ps.slot, ps.preset)
+	}
+
+	var resp pcconfig.CameraPreset
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unable to parse response: %s", err)
+	}
+
+	if resp.SetPreset != "https://pc-config/ITB-1101-CP1/cameras/Front/setPreset?preset=Podium" {
+		t.Fatalf("expected response to use proxy urls, got %+v", resp)
+	}
+}
+
+func TestSaveCameraPresetNoSaveURL(t *testing.T) {
+	ps := &mockPresetService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {
+					{
+						DisplayName: "Front",
+						Presets: []pcconfig.CameraPreset{
+							{DisplayName: "Preset 1", SetPreset: "http://camera/preset/1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService: ps,
+		CameraProxy:   &CameraProxy{BaseURL: "https://pc-config"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/:hostname/cameras/:camera/presets/:slot", h.SaveCameraPreset)
+
+	for path, code := range map[string]int{
+		"/ITB-1101-CP1/cameras/Front/presets/0": http.StatusBadGateway,
+		"/ITB-1101-CP1/cameras/Front/presets/1": http.StatusNotFound,
+		"/ITB-1101-CP1/cameras/Back/presets/0":  http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{}`)))
+		if w.Code != code {
+			t.Fatalf("%s: expected %d, got %d: %s", path, code, w.Code, w.Body.String())
+		}
+	}
+
+	if ps.preset.DisplayName != "" {
+		t.Fatalf("expected nothing to be stored, got %+v", ps.preset)
+	}
+}
+
+type savingDriver struct {
+	*listingDriver
+	saved []string
+}
+
+func (s *savingDriver) SavePreset(ctx context.Context, preset string) error {
+	s.saved = append(s.saved, preset)
+	return nil
+}
+
+func TestSaveCameraPresetDiscovered(t *testing.T) {
+	driver := &savingDriver{listingDriver: &listingDriver{presets: []pcconfig.CameraPreset{
+		{DisplayName: "Podium", Preset: "1"},
+		{DisplayName: "Board", Preset: "2"},
+	}}}
+
+	cams := []pcconfig.Camera{{DisplayName: "Front", Address: "10.0.0.1", Protocol: "mock"}}
+	ps := &mockPresetService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: cams,
+			},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService: ps,
+		CameraProxy: &CameraProxy{
+			BaseURL: "https://pc-config",
+			Drivers: map[string]cameras.NewDriverFunc{
+				"mock": func(string) (cameras.Driver, error) {
+					return driver, nil
+				},
+			},
+		},
+	}
+
+	lister := &mockLister{rooms: []pcconfig.Room{
+		{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: cams}}},
+	}}
+
+	if err := h.CameraProxy.DiscoverPresets(context.Background(), lister); err != nil {
+		t.Fatalf("unable to discover presets: %s", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/:hostname/cameras/:camera/presets/:slot", h.SaveCameraPreset)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/ITB-1101-CP1/cameras/Front/presets/1", strings.NewReader(`{"displayName": "Whiteboard"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(driver.saved) != 1 || driver.saved[0] != "2" {
+		t.Fatalf("expected the camera to save preset 2, got %v", driver.saved)
+	}
+
+	// the discovered presets are stored too, since stored presets replace them
+	expected := []pcconfig.CameraPreset{
+		{DisplayName: "Podium", Preset: "1"},
+		{DisplayName: "Whiteboard", Preset: "2"},
+	}
+
+	if diff := cmp.Diff(expected, ps.stored); diff != "" {
+		t.Errorf("stored wrong presets (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/schedule"
+	"github.com/gin-gonic/gin"
+)
+
+// RoomConfig returns the config of every control group in a room. Control keys are included if ?controlKey=true
+// and the request was allowed by RequireToken.
+func (h *Handlers) RoomConfig(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	room, status, err := h.room(ctx, c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	config := pcconfig.RoomConfig{
+		Room:          room.ID,
+		ControlGroups: make([]pcconfig.ControlGroupConfig, 0, len(room.ControlGroups)),
+	}
+
+	for _, cg := range room.ControlGroups {
+		config.ControlGroups = append(config.ControlGroups, h.controlGroupConfig(ctx, c, room.ID, cg))
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// ControlGroupConfig returns the config of a single control group in a room. The control key is included if
+// ?controlKey=true and the request was allowed by RequireToken.
+func (h *Handlers) ControlGroupConfig(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	room, status, err := h.room(ctx, c)
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	for _, cg := range room.ControlGroups {
+		if cg.Name == c.Param("controlGroup") {
+			c.JSON(http.StatusOK, h.controlGroupConfig(ctx, c, room.ID, cg))
+			return
+		}
+	}
+
+	c.String(http.StatusNotFound, fmt.Sprintf("room %q has no control group %q", room.ID, c.Param("controlGroup")))
+}
+
+// room gets the room in the request, including the change sets that are effective at ?at= (or now)
+// and have been released to every PC. If an error is returned, status is the http status code that
+// should be sent with it.
+func (h *Handlers) room(ctx context.Context, c *gin.Context) (pcconfig.Room, int, error) {
+	if _, ok := h.ConfigService.(pcconfig.RoomService); !ok {
+		return pcconfig.Room{}, http.StatusNotFound, fmt.Errorf("room configs are not supported by this datastore")
+	}
+
+	at := time.Now()
+	if c.Query("at") != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, c.Query("at")); err != nil {
+			return pcconfig.Room{}, http.StatusBadRequest, fmt.Errorf("invalid at: %w", err)
+		}
+	}
+
+	sets, err := h.changeSets(ctx)
+	if err != nil {
+		return pcconfig.Room{}, http.StatusInternalServerError, err
+	}
+
+	room, err := schedule.At(h.ConfigService, schedule.WithoutCanaries(sets), at).Room(ctx, c.Param("room"))
+	if err != nil {
+		return pcconfig.Room{}, http.StatusInternalServerError, fmt.Errorf("unable to get room: %w", err)
+	}
+
+	return room, http.StatusOK, nil
+}
+
+// controlGroupConfig builds the config for cg in room. Cameras are sent as they are in the datastore, since they
+// aren't going to a PC that the camera proxy could send them through.
+func (h *Handlers) controlGroupConfig(ctx context.Context, c *gin.Context, room string, cg pcconfig.ControlGroup) pcconfig.ControlGroupConfig {
+	cameras := make([]pcconfig.Camera, len(cg.Cameras))
+	for i := range cg.Cameras {
+		cameras[i] = pcconfig.NormalizeCapabilities(cg.Cameras[i])
+	}
+
+	if h.CameraStatus != nil && h.AnnotateCameraStatus {
+		cameras = h.CameraStatus.Annotate(room, cg.Name, cameras)
+	}
+
+	config := pcconfig.ControlGroupConfig{
+		Name:    cg.Name,
+		Cameras: withoutThumbnails(cameras),
+	}
+
+	// anyone with a control key can control the room, so they are only sent to admins
+	if withKey, _ := strconv.ParseBool(c.Query("controlKey")); withKey && c.GetBool(_authorizedKey) {
+		// ignore this error, just don't set the key
+		config.ControlKey, _ = h.ControlKeyService.ControlKey(ctx, room, cg.Name)
+	}
+
+	return config
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockRoomService struct {
+	*mockScheduleService
+	rooms map[string]pcconfig.Room
+}
+
+func (m *mockRoomService) Room(ctx context.Context, room string) (pcconfig.Room, error) {
+	r, ok := m.rooms[room]
+	if !ok {
+		return pcconfig.Room{}, errors.New("no room")
+	}
+
+	return r, nil
+}
+
+func TestRoomConfig(t *testing.T) {
+	staged := func(name string) []pcconfig.Room {
+		return []pcconfig.Room{
+			{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 2", Cameras: []pcconfig.Camera{{DisplayName: name}}}}},
+		}
+	}
+
+	h := &Handlers{
+		ConfigService: &mockRoomService{
+			mockScheduleService: &mockScheduleService{
+				mockConfigService: &mockConfigService{},
+				sets: []pcconfig.ChangeSet{
+					{ID: "released", Effective: time.Now().Add(-time.Hour), Rooms: staged("Released")},
+					{ID: "canary", Effective: time.Now().Add(-time.Minute), Rooms: staged("Canary"), Canary: &pcconfig.Canary{Percent: 100}},
+				},
+			},
+			rooms: map[string]pcconfig.Room{
+				"ITB-1101": {
+					ID: "ITB-1101",
+					ControlGroups: []pcconfig.ControlGroup{
+						{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front"}}},
+						{Name: "Group 2", Cameras: []pcconfig.Camera{{DisplayName: "Back"}}},
+					},
+				},
+			},
+		},
+		ControlKeyService: &mockKeyService{key: "1234"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/rooms/:room/config", h.RoomConfig)
+	r.GET("/rooms/:room/controlgroups/:controlGroup/config", h.ControlGroupConfig)
+	r.GET("/admin/rooms/:room/config", RequireToken("token"), h.RoomConfig)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/rooms/ITB-1101/config?controlKey=true", nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var config pcconfig.RoomConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+		t.Fatalf("unable to parse config: %s", err)
+	}
+
+	expected := pcconfig.RoomConfig{
+		Room: "ITB-1101",
+		ControlGroups: []pcconfig.ControlGroupConfig{
+			{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front", Presets: []pcconfig.CameraPreset{}}}, ControlKey: "1234"},
+			{Name: "Group 2", Cameras: []pcconfig.Camera{{DisplayName: "Released", Presets: []pcconfig.CameraPreset{}}}, ControlKey: "1234"},
+		},
+	}
+
+	if diff := cmp.Diff(expected, config); diff != "" {
+		t.Errorf("got incorrect room config (-want, +got):\n%s", diff)
+	}
+
+	// control keys are only sent to admins
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rooms/ITB-1101/controlgroups/Group%201/config?controlKey=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var cg pcconfig.ControlGroupConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &cg); err != nil {
+		t.Fatalf("unable to parse config: %s", err)
+	}
+
+	if cg.Name != "Group 1" || cg.ControlKey != "" {
+		t.Errorf("expected Group 1 without a control key, got %+v", cg)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rooms/ITB-1101/controlgroups/Group%203/config", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing control group, got %d", w.Code)
+	}
+}
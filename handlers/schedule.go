@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/schedule"
+	"github.com/byuoitav/pc-config/validate"
+	"github.com/gin-gonic/gin"
+)
+
+// scheduleService returns the datastore's ScheduleService, or responds with a 404 if it doesn't have one.
+func (h *Handlers) scheduleService(c *gin.Context) (pcconfig.ScheduleService, bool) {
+	ss, ok := h.ConfigService.(pcconfig.ScheduleService)
+	if !ok {
+		c.String(http.StatusNotFound, "scheduled changes are not supported by this datastore")
+	}
+
+	return ss, ok
+}
+
+// reloadSchedule makes Schedule pick up a change to the change sets now, rather than at its next refresh.
+func (h *Handlers) reloadSchedule(ctx context.Context) {
+	if h.Schedule != nil {
+		// it reloads them on its own every interval anyway
+		_ = h.Schedule.Reload(ctx)
+	}
+}
+
+// ChangeSets returns every change set.
+func (h *Handlers) ChangeSets(c *gin.Context) {
+	ss, ok := h.scheduleService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	sets, err := ss.ChangeSets(ctx)
+	if err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to get change sets: %s", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, sets)
+}
+
+// PutChangeSet creates or replaces a change set. Change sets whose rooms have errors are rejected.
+func (h *Handlers) PutChangeSet(c *gin.Context) {
+	ss, ok := h.scheduleService(c)
+	if !ok {
+		return
+	}
+
+	var set pcconfig.ChangeSet
+	if err := c.ShouldBindJSON(&set); err != nil {
+		c.String(http.StatusBadRequest, fmt.Sprintf("invalid change set: %s", err))
+		return
+	}
+
+	set.ID = c.Param("id")
+	set.Applied = false
+	set.AppliedAt = nil
+
+	if set.Canary != nil {
+		set.Canary.Fetched = nil
+		if set.Canary.Percent < 0 || set.Canary.Percent > 100 {
+			c.String(http.StatusBadRequest, "canary percent must be between 0 and 100")
+			return
+		}
+	}
+
+	if set.Effective.IsZero() {
+		c.String(http.StatusBadRequest, "change set must have an effective time")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	// rooms are staged as they are given, but are checked with their templates resolved, like they will be served
+	rooms, err := h.resolveRooms(ctx, set.Rooms)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var problems []validate.Problem
+	for _, room := range rooms {
+		problems = append(problems, validate.Room(room)...)
+	}
+
+	for _, m := range set.Mappings {
+		if m.Hostname == "" || m.Room == "" || m.ControlGroup == "" {
+			problems = append(problems, validate.Problem{
+				Severity: validate.SeverityError,
+				PC:       m.Hostname,
+				Message:  "mapping must have a hostname, room, and control group",
+			})
+		}
+	}
+
+	if report := validate.NewReport(len(set.Rooms), len(set.Mappings), problems); !report.Valid() {
+		c.JSON(http.StatusBadRequest, report)
+		return
+	}
+
+	if err := ss.PutChangeSet(ctx, set); err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to put change set: %s", err))
+		return
+	}
+
+	h.reloadSchedule(ctx)
+
+	c.JSON(http.StatusOK, set)
+}
+
+// resolveRooms returns rooms with the datastore's templates resolved. Cameras that can't be resolved
+// keep their template, for validation to report.
+func (h *Handlers) resolveRooms(ctx context.Context, rooms []pcconfig.Room) ([]pcconfig.Room, error) {
+	t, ok := h.ConfigService.(pcconfig.TemplateService)
+	if !ok || len(pcconfig.TemplateNames(rooms...)) == 0 {
+		return rooms, nil
+	}
+
+	templates, err := t.Templates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get templates: %w", err)
+	}
+
+	resolved := make([]pcconfig.Room, len(rooms))
+	for i, room := range rooms {
+		resolved[i], _ = pcconfig.ResolveRoomTemplates(room, templates)
+	}
+
+	return resolved, nil
+}
+
+// DeleteChangeSet deletes a change set.
+func (h *Handlers) DeleteChangeSet(c *gin.Context) {
+	ss, ok := h.scheduleService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := ss.DeleteChangeSet(ctx, c.Param("id")); err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to delete change set: %s", err))
+		return
+	}
+
+	h.reloadSchedule(ctx)
+
+	c.Status(http.StatusNoContent)
+}
+
+// ApplyChangeSet applies a change set right away, regardless of when it takes effect.
+func (h *Handlers) ApplyChangeSet(c *gin.Context) {
+	ss, ok := h.scheduleService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := ss.ApplyChangeSet(ctx, c.Param("id")); err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to apply change set: %s", err))
+		return
+	}
+
+	h.reloadSchedule(ctx)
+
+	c.Status(http.StatusNoContent)
+}
+
+// rolloutStatus is the progress of a canary change set.
+type rolloutStatus struct {
+	ID        string          `json:"id"`
+	Effective time.Time       `json:"effective"`
+	Canary    pcconfig.Canary `json:"canary"`
+	Fetched   []canaryFetch   `json:"fetched"`
+	Waiting   []string        `json:"waiting"`
+}
+
+type canaryFetch struct {
+	Hostname string    `json:"hostname"`
+	At       time.Time `json:"at"`
+}
+
+// Rollout returns which PCs have fetched a canary change set, and which of the
+// PCs named in the canary haven't yet.
+func (h *Handlers) Rollout(c *gin.Context) {
+	ss, ok := h.scheduleService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	set, status, err := canaryChangeSet(ctx, ss, c.Param("id"))
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	rollout := rolloutStatus{
+		ID:        set.ID,
+		Effective: set.Effective,
+		Canary:    *set.Canary,
+		Fetched:   []canaryFetch{},
+		Waiting:   []string{},
+	}
+
+	for hostname, at := range set.Canary.Fetched {
+		rollout.Fetched = append(rollout.Fetched, canaryFetch{Hostname: hostname, At: at})
+	}
+
+	sort.Slice(rollout.Fetched, func(i, j int) bool {
+		return rollout.Fetched[i].At.Before(rollout.Fetched[j].At)
+	})
+
+	for _, hostname := range set.Canary.Hostnames {
+		if _, ok := set.Canary.Fetched[hostname]; !ok {
+			rollout.Waiting = append(rollout.Waiting, hostname)
+		}
+	}
+
+	c.JSON(http.StatusOK, rollout)
+}
+
+// PromoteRollout applies a canary change set, so that every PC gets it.
+func (h *Handlers) PromoteRollout(c *gin.Context) {
+	ss, ok := h.scheduleService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if _, status, err := canaryChangeSet(ctx, ss, c.Param("id")); err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	if err := ss.ApplyChangeSet(ctx, c.Param("id")); err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to promote rollout: %s", err))
+		return
+	}
+
+	h.reloadSchedule(ctx)
+
+	c.Status(http.StatusNoContent)
+}
+
+// AbortRollout deletes a canary change set, so that the canary PCs go back to the current config.
+func (h *Handlers) AbortRollout(c *gin.Context) {
+	ss, ok := h.scheduleService(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, status, err := canaryChangeSet(ctx, ss, c.Param("id")); err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	if err := ss.DeleteChangeSet(ctx, c.Param("id")); err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to abort rollout: %s", err))
+		return
+	}
+
+	h.reloadSchedule(ctx)
+
+	c.Status(http.StatusNoContent)
+}
+
+// canaryChangeSet finds the unapplied canary change set id.
+func canaryChangeSet(ctx context.Context, ss pcconfig.ScheduleService, id string) (pcconfig.ChangeSet, int, error) {
+	sets, err := ss.ChangeSets(ctx)
+	if err != nil {
+		return pcconfig.ChangeSet{}, http.StatusInternalServerError, fmt.Errorf("unable to get change sets: %w", err)
+	}
+
+	for _, set := range sets {
+		switch {
+		case set.ID != id:
+		case set.Canary == nil:
+			return pcconfig.ChangeSet{}, http.StatusBadRequest, fmt.Errorf("change set %q is not a canary", id)
+		case set.Applied:
+			return pcconfig.ChangeSet{}, http.StatusConflict, fmt.Errorf("change set %q has already been applied", id)
+		default:
+			return set, http.StatusOK, nil
+		}
+	}
+
+	return pcconfig.ChangeSet{}, http.StatusNotFound, fmt.Errorf("no change set %q", id)
+}
+
+// recordCanaryFetches records that hostname fetched each canary change set in sets that targets it, is effective at
+// at, and changed config. Fetches are recorded in the background, so that PCs don't wait on them.
+func (h *Handlers) recordCanaryFetches(ss pcconfig.ScheduleService, sets []pcconfig.ChangeSet, hostname string, at time.Time, config pcConfig) {
+	h.pruneCanaryFetches(sets)
+
+	for _, set := range schedule.ForHostname(sets, hostname) {
+		if set.Canary == nil || set.Applied || set.Effective.After(at) || !changes(set, config) {
+			continue
+		}
+
+		if _, ok := set.Canary.Fetched[hostname]; ok {
+			continue
+		}
+
+		// sets are only reloaded every so often, so remember which fetches have been recorded since
+		h.canaryMu.Lock()
+		if h.canaryFetches[set.ID][hostname] {
+			h.canaryMu.Unlock()
+			continue
+		}
+
+		if h.canaryFetches == nil {
+			h.canaryFetches = make(map[string]map[string]bool)
+		}
+
+		if h.canaryFetches[set.ID] == nil {
+			h.canaryFetches[set.ID] = make(map[string]bool)
+		}
+
+		h.canaryFetches[set.ID][hostname] = true
+		h.canaryMu.Unlock()
+
+		h.canaryWG.Add(1)
+		go func(id string) {
+			defer h.canaryWG.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			// the PC still gets the canary config if this fails, and it's tried again on its next fetch
+			if err := ss.RecordCanaryFetch(ctx, id, hostname, at); err != nil {
+				h.canaryMu.Lock()
+				delete(h.canaryFetches[id], hostname)
+				h.canaryMu.Unlock()
+			}
+		}(set.ID)
+	}
+}
+
+// pruneCanaryFetches forgets the recorded fetches that are already in sets, and the ones of
+// change sets that were deleted or applied, so that only fetches since the last reload are kept.
+func (h *Handlers) pruneCanaryFetches(sets []pcconfig.ChangeSet) {
+	byID := make(map[string]pcconfig.ChangeSet, len(sets))
+	for _, set := range sets {
+		byID[set.ID] = set
+	}
+
+	h.canaryMu.Lock()
+	defer h.canaryMu.Unlock()
+
+	for id, hostnames := range h.canaryFetches {
+		set, ok := byID[id]
+		if !ok || set.Canary == nil || set.Applied {
+			delete(h.canaryFetches, id)
+			continue
+		}
+
+		for hostname := range hostnames {
+			if _, ok := set.Canary.Fetched[hostname]; ok {
+				delete(hostnames, hostname)
+			}
+		}
+
+		if len(hostnames) == 0 {
+			delete(h.canaryFetches, id)
+		}
+	}
+}
+
+// changes reports whether set changes config, which was built with set layered on top of the datastore.
+func changes(set pcconfig.ChangeSet, config pcConfig) bool {
+	for _, m := range set.Mappings {
+		if m.Hostname == config.mapping {
+			return true
+		}
+	}
+
+	for _, room := range set.Rooms {
+		if room.ID != config.room {
+			continue
+		}
+
+		for _, cg := range room.ControlGroups {
+			if cg.Name == config.controlGroup {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/schedule"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockScheduleService struct {
+	*mockConfigService
+
+	sets []pcconfig.ChangeSet
+	put  *pcconfig.ChangeSet
+
+	mu      sync.Mutex
+	fetches map[string][]string
+}
+
+func (m *mockScheduleService) ChangeSets(ctx context.Context) ([]pcconfig.ChangeSet, error) {
+	return m.sets, nil
+}
+
+func (m *mockScheduleService) PutChangeSet(ctx context.Context, cs pcconfig.ChangeSet) error {
+	m.put = &cs
+	return nil
+}
+
+func (m *mockScheduleService) DeleteChangeSet(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockScheduleService) ApplyChangeSet(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockScheduleService) RecordCanaryFetch(ctx context.Context, id, hostname string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.fetches == nil {
+		m.fetches = make(map[string][]string)
+	}
+
+	m.fetches[id] = append(m.fetches[id], hostname)
+	return nil
+}
+
+func TestConfigForPCAt(t *testing.T) {
+	fall := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	ss := &mockScheduleService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {{DisplayName: "Current"}},
+			},
+		},
+		sets: []pcconfig.ChangeSet{
+			{
+				ID:        "fall",
+				Effective: fall,
+				Rooms: []pcconfig.Room{
+					{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Fall"}}}}},
+				},
+			},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService:     ss,
+		ControlKeyService: mockControlKeyService{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+	r.PUT("/admin/changesets/:id", h.PutChangeSet)
+
+	camera := func(query string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var config pcconfig.Config
+		if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+			t.Fatalf("unable to parse config: %s", err)
+		}
+
+		return config.Cameras[0].DisplayName
+	}
+
+	if got := camera(""); got != "Current" {
+		t.Fatalf("expected current config before the change set takes effect, got %q", got)
+	}
+
+	if got := camera("?at=" + fall.Format(time.RFC3339)); got != "Fall" {
+		t.Fatalf("expected preview of the change set, got %q", got)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config?at=tomorrow", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid time, got %d", w.Code)
+	}
+
+	body := `{"effective": "2026-08-24T06:00:00Z", "rooms": [{"id": "ITB-1101", "controlGroups": [{"name": "Group 1", "cameras": [{"displayName": ""}]}]}]}`
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/changesets/fall", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest || ss.put != nil {
+		t.Fatalf("expected invalid change set to be rejected, got %d", w.Code)
+	}
+}
+
+func TestCanaryRollout(t *testing.T) {
+	ss := &mockScheduleService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+				"ITB-1101-CP2": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {{DisplayName: "Current"}},
+			},
+		},
+		sets: []pcconfig.ChangeSet{
+			{
+				ID:        "canary",
+				Effective: time.Now().Add(-time.Hour),
+				Canary:    &pcconfig.Canary{Hostnames: []string{"ITB-1101-CP1", "ITB-1101-CP3"}},
+				Rooms: []pcconfig.Room{
+					{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Canary"}}}}},
+				},
+			},
+			{
+				ID:        "other room",
+				Effective: time.Now().Add(-time.Hour),
+				Canary:    &pcconfig.Canary{Hostnames: []string{"ITB-1101-CP1"}},
+				Rooms: []pcconfig.Room{
+					{ID: "ITB-1102", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Canary"}}}}},
+				},
+			},
+			{ID: "scheduled", Effective: time.Now().Add(time.Hour)},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService:     ss,
+		ControlKeyService: mockControlKeyService{},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+	r.GET("/admin/changesets/:id/rollout", h.Rollout)
+	r.POST("/admin/changesets/:id/promote", h.PromoteRollout)
+
+	camera := func(hostname string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+hostname+"/config", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var config pcconfig.Config
+		if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+			t.Fatalf("unable to parse config: %s", err)
+		}
+
+		return config.Cameras[0].DisplayName
+	}
+
+	if got := camera("ITB-1101-CP1"); got != "Canary" {
+		t.Errorf("expected canary PC to get the change set, got %q", got)
+	}
+
+	if got := camera("ITB-1101-CP2"); got != "Current" {
+		t.Errorf("expected other PCs to get the current config, got %q", got)
+	}
+
+	// a fetch is only recorded once, and only for change sets that changed the config
+	camera("ITB-1101-CP1")
+	h.canaryWG.Wait()
+
+	if diff := cmp.Diff(map[string][]string{"canary": {"ITB-1101-CP1"}}, ss.fetches); diff != "" {
+		t.Errorf("recorded wrong fetches (-want, +got):\n%s", diff)
+	}
+
+	fetched := time.Now().UTC().Truncate(time.Second)
+	ss.sets[0].Canary.Fetched = map[string]time.Time{"ITB-1101-CP1": fetched}
+
+	// fetches are forgotten once they are in the change sets that were read
+	camera("ITB-1101-CP1")
+	if len(h.canaryFetches) != 0 {
+		t.Errorf("expected recorded fetches to be pruned, got %v", h.canaryFetches)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/changesets/canary/rollout", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var rollout rolloutStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &rollout); err != nil {
+		t.Fatalf("unable to parse rollout: %s", err)
+	}
+
+	if diff := cmp.Diff([]canaryFetch{{Hostname: "ITB-1101-CP1", At: fetched}}, rollout.Fetched); diff != "" {
+		t.Errorf("wrong fetches (-want, +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"ITB-1101-CP3"}, rollout.Waiting); diff != "" {
+		t.Errorf("wrong waiting PCs (-want, +got):\n%s", diff)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/changesets/scheduled/promote", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 promoting a change set that isn't a canary, got %d", w.Code)
+	}
+}
+
+func TestControlCameraChangeSet(t *testing.T) {
+	var tilted bool
+	cam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tilted = true
+	}))
+	defer cam.Close()
+
+	ss := &mockScheduleService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {{DisplayName: "Current"}},
+			},
+		},
+		sets: []pcconfig.ChangeSet{
+			{
+				ID:        "now",
+				Effective: time.Now().Add(-time.Minute),
+				Rooms: []pcconfig.Room{
+					{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Added", TiltUp: cam.URL}}}}},
+				},
+			},
+		},
+	}
+
+	h := &Handlers{
+		ConfigService: ss,
+		CameraProxy:   &CameraProxy{BaseURL: "https://pc-config"},
+		Schedule:      &schedule.Applier{Service: ss},
+	}
+
+	if err := h.Schedule.Reload(context.Background()); err != nil {
+		t.Fatalf("unable to load change sets: %s", err)
+	}
+
+	// the change sets in memory are used, rather than reading them again
+	ss.sets = nil
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/cameras/:camera/:action", h.ControlCamera)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/cameras/Added/tiltUp", nil))
+	switch {
+	case w.Code != http.StatusOK:
+		t.Fatalf("expected 200 for a camera in an effective change set, got %d: %s", w.Code, w.Body.String())
+	case !tilted:
+		t.Fatalf("expected the command to reach the camera")
+	}
+}
+
+type mockTemplateScheduleService struct {
+	*mockScheduleService
+	templates map[string]pcconfig.Camera
+}
+
+func (m *mockTemplateScheduleService) Templates(ctx context.Context) (map[string]pcconfig.Camera, error) {
+	return m.templates, nil
+}
+
+func TestPutChangeSetTemplates(t *testing.T) {
+	ss := &mockTemplateScheduleService{
+		mockScheduleService: &mockScheduleService{mockConfigService: &mockConfigService{}},
+		templates: map[string]pcconfig.Camera{
+			"ptz": {Stream: "rtsp://{{cameraAddress}}/main", TiltUp: "http://{{cameraAddress}}/tiltUp", PanTiltStop: "http://{{cameraAddress}}/stop"},
+		},
+	}
+
+	h := &Handlers{ConfigService: ss}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/admin/changesets/:id", h.PutChangeSet)
+
+	put := func(template string) *httptest.ResponseRecorder {
+		set := pcconfig.ChangeSet{
+			Effective: time.Now().Add(time.Hour),
+			Rooms: []pcconfig.Room{
+				{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{
+					{DisplayName: "Front", Template: template, Variables: map[string]string{"cameraAddress": "10.0.0.5"}},
+				}}}},
+			},
+		}
+
+		body, err := json.Marshal(set)
+		if err != nil {
+			t.Fatalf("unable to marshal change set: %s", err)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/changesets/fall", strings.NewReader(string(body))))
+		return w
+	}
+
+	if w := put("ptz"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a camera with a template, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// the camera is staged with its template, so it follows changes to the template
+	if cam := ss.put.Rooms[0].ControlGroups[0].Cameras[0]; cam.Template != "ptz" || cam.Stream != "" {
+		t.Errorf("expected the camera to be staged as it was given, got %+v", cam)
+	}
+
+	ss.put = nil
+	if w := put("missing"); w.Code != http.StatusBadRequest || ss.put != nil {
+		t.Fatalf("expected 400 for a template that doesn't exist, got %d: %s", w.Code, w.Body.String())
+	}
+}
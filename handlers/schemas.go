@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/jsonschema"
+	"github.com/gin-gonic/gin"
+)
+
+var _configSchemas = map[string]*jsonschema.Schema{
+	"config":    jsonschema.For(pcconfig.Config{}),
+	"config-v2": jsonschema.For(pcconfig.ConfigV2{}),
+}
+
+// Schemas returns the name of every JSON Schema that is published.
+func (h *Handlers) Schemas(c *gin.Context) {
+	names := []string{}
+	for name := range h.schemas() {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	c.JSON(http.StatusOK, names)
+}
+
+// Schema returns the JSON Schema called name.
+func (h *Handlers) Schema(c *gin.Context) {
+	schema, ok := h.schemas()[c.Param("name")]
+	if !ok {
+		c.String(http.StatusNotFound, fmt.Sprintf("no schema %q", c.Param("name")))
+		return
+	}
+
+	c.Header("Content-Type", "application/schema+json")
+	c.JSON(http.StatusOK, schema)
+}
+
+func (h *Handlers) schemas() map[string]*jsonschema.Schema {
+	schemas := make(map[string]*jsonschema.Schema, len(_configSchemas)+len(h.DocumentSchemas))
+	for name, s := range h.DocumentSchemas {
+		schemas[name] = s
+	}
+
+	for name, s := range _configSchemas {
+		schemas[name] = s
+	}
+
+	return schemas
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CameraStatuses returns the reachability of every camera the prober has checked.
+func (h *Handlers) CameraStatuses(c *gin.Context) {
+	if h.CameraStatus == nil {
+		c.String(http.StatusNotFound, "camera probing is not enabled")
+		return
+	}
+
+	statuses := h.CameraStatus.Statuses()
+	if room := c.Query("room"); room != "" {
+		filtered := statuses[:0]
+		for _, s := range statuses {
+			if s.Room == room {
+				filtered = append(filtered, s)
+			}
+		}
+
+		statuses = filtered
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// CameraMetrics writes the reachability of every camera in the prometheus text format.
+func (h *Handlers) CameraMetrics(c *gin.Context) {
+	if h.CameraStatus == nil {
+		c.String(http.StatusNotFound, "camera probing is not enabled")
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; version=0.0.4")
+	c.Status(http.StatusOK)
+	_ = h.CameraStatus.WriteMetrics(c.Writer)
+}
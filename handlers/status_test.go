@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/probe"
+	"github.com/gin-gonic/gin"
+)
+
+type mockLister struct {
+	*mockConfigService
+	rooms []pcconfig.Room
+}
+
+func (m *mockLister) PCMappings(ctx context.Context) ([]pcconfig.PCMapping, error) {
+	return nil, nil
+}
+
+func (m *mockLister) Rooms(ctx context.Context) ([]pcconfig.Room, error) {
+	return m.rooms, nil
+}
+
+type mockControlKeyService struct{}
+
+func (mockControlKeyService) ControlKey(ctx context.Context, room, controlGroup string) (string, error) {
+	return "", errors.New("no control key")
+}
+
+func TestCameraStatus(t *testing.T) {
+	stream := httptest.NewServer(http.NotFoundHandler())
+	defer stream.Close()
+
+	cams := []pcconfig.Camera{
+		{DisplayName: "Front", Stream: stream.URL + "/stream"},
+	}
+
+	cs := &mockLister{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: cams,
+			},
+		},
+		rooms: []pcconfig.Room{
+			{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: cams}}},
+		},
+	}
+
+	prober := &probe.Prober{Lister: cs}
+	if err := prober.ProbeAll(context.Background()); err != nil {
+		t.Fatalf("unable to probe: %s", err)
+	}
+
+	h := &Handlers{
+		ConfigService:        cs,
+		ControlKeyService:    mockControlKeyService{},
+		CameraStatus:         prober,
+		AnnotateCameraStatus: true,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/config", h.ConfigForPC)
+	r.GET("/metrics", h.CameraMetrics)
+	r.GET("/admin/cameras/status", h.CameraStatuses)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/config", nil))
+
+	var config pcconfig.Config
+	if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+		t.Fatalf("unable to parse config: %s", err)
+	}
+
+	if len(config.Cameras) != 1 || config.Cameras[0].Status != pcconfig.CameraStatusDown {
+		t.Fatalf("expected camera to be annotated as down, got %+v", config.Cameras)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/cameras/status?room=ITB-1102", nil))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("expected no statuses for another room, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `camera="Front"} 0`) {
+		t.Fatalf("expected Front to be down in metrics, got:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+)
+
+// _maxThumbnailSize is the largest thumbnail we'll store.
+const _maxThumbnailSize = 2 << 20
+
+// thumbnailKey returns the ThumbnailStore key for the preset in slot.
+func thumbnailKey(controlGroup, camera string, slot int) string {
+	sum := sha1.Sum([]byte(controlGroup + "/" + camera))
+	return fmt.Sprintf("thumbnail-%x-%d.jpg", sum[:8], slot)
+}
+
+// PresetThumbnail serves the thumbnail of one of the presets of a camera in the PC's control group.
+func (h *Handlers) PresetThumbnail(c *gin.Context) {
+	if h.Thumbnails == nil {
+		c.String(http.StatusNotFound, "thumbnails are not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	room, _, cam, status, err := h.cameraForPC(ctx, c.Param("hostname"), c.Param("camera"))
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	preset, _, ok := presetInSlot(cam, c.Param("slot"))
+	if !ok || preset.Thumbnail == "" {
+		c.String(http.StatusNotFound, "preset has no thumbnail")
+		return
+	}
+
+	jpeg, err := h.Thumbnails.Thumbnail(ctx, room, preset.Thumbnail)
+	if err != nil {
+		c.String(datastoreStatus(err), fmt.Sprintf("unable to get thumbnail: %s", err))
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum(jpeg))
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "image/jpeg", jpeg)
+}
+
+// CaptureThumbnail takes a snapshot from a camera in the PC's control group
+// and stores it as the thumbnail of one of its presets.
+func (h *Handlers) CaptureThumbnail(c *gin.Context) {
+	if h.Thumbnails == nil || h.CameraProxy == nil {
+		c.String(http.StatusNotFound, "thumbnails are not enabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	room, cg, cam, status, err := h.cameraForPC(ctx, c.Param("hostname"), c.Param("camera"))
+	if err != nil {
+		c.String(status, err.Error())
+		return
+	}
+
+	_, slot, ok := presetInSlot(cam, c.Param("slot"))
+	if !ok {
+		c.String(http.StatusNotFound, fmt.Sprintf("camera %q has no preset slot %s", cam.DisplayName, c.Param("slot")))
+		return
+	}
+
+	jpeg, err := h.CameraProxy.snapshot(ctx, cam)
+	if err != nil {
+		c.String(http.StatusBadGateway, fmt.Sprintf("unable to capture snapshot: %s", err))
+		return
+	}
+
+	if err := h.storeThumbnail(ctx, room, cg, cam, slot, jpeg); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UploadThumbnail stores the JPEG in the request body as the thumbnail of a preset.
+func (h *Handlers) UploadThumbnail(c *gin.Context) {
+	if h.Thumbnails == nil {
+		c.String(http.StatusNotFound, "thumbnails are not enabled")
+		return
+	}
+
+	jpeg, err := readJPEG(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	room, cg := c.Param("room"), c.Param("controlGroup")
+
+	cameras, err := h.ConfigService.Cameras(ctx, room, cg)
+	if err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to get cameras: %s", err))
+		return
+	}
+
+	for _, cam := range cameras {
+		if cam.DisplayName != c.Param("camera") {
+			continue
+		}
+
+		_, slot, ok := presetInSlot(cam, c.Param("slot"))
+		if !ok {
+			c.String(http.StatusNotFound, fmt.Sprintf("camera %q has no preset slot %s", cam.DisplayName, c.Param("slot")))
+			return
+		}
+
+		if err := h.storeThumbnail(ctx, room, cg, cam, slot, jpeg); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.String(http.StatusNotFound, fmt.Sprintf("no camera %q in %s %s", c.Param("camera"), room, cg))
+}
+
+// storeThumbnail stores jpeg and points the preset in slot at it.
+func (h *Handlers) storeThumbnail(ctx context.Context, room, cg string, cam pcconfig.Camera, slot int, jpeg []byte) error {
+	key := thumbnailKey(cg, cam.DisplayName, slot)
+	if err := h.Thumbnails.PutThumbnail(ctx, room, key, jpeg); err != nil {
+		return fmt.Errorf("unable to store thumbnail: %w", err)
+	}
+
+	preset := cam.Presets[slot]
+	if preset.Thumbnail == key {
+		return nil
+	}
+
+	presetService, ok := h.ConfigService.(pcconfig.CameraPresetService)
+	if !ok {
+		return fmt.Errorf("unable to update preset: presets can't be updated")
+	}
+
+	preset.Thumbnail = key
+	if err := presetService.SetCameraPreset(ctx, room, cg, cam.DisplayName, slot, preset); err != nil {
+		return fmt.Errorf("unable to update preset: %w", err)
+	}
+
+	return nil
+}
+
+// withoutThumbnails removes the thumbnail store keys from cams, since there isn't a url to serve them at.
+func withoutThumbnails(cams []pcconfig.Camera) []pcconfig.Camera {
+	stripped := make([]pcconfig.Camera, len(cams))
+
+	for i, cam := range cams {
+		presets := make([]pcconfig.CameraPreset, len(cam.Presets))
+		for j, preset := range cam.Presets {
+			preset.Thumbnail = ""
+			presets[j] = preset
+		}
+
+		cam.Presets = presets
+		stripped[i] = cam
+	}
+
+	return stripped
+}
+
+// presetInSlot returns the preset in the slot named by slot.
+func presetInSlot(cam pcconfig.Camera, slot string) (pcconfig.CameraPreset, int, bool) {
+	i, err := strconv.Atoi(slot)
+	if err != nil || i < 0 || i >= len(cam.Presets) {
+		return pcconfig.CameraPreset{}, 0, false
+	}
+
+	return cam.Presets[i], i, true
+}
+
+// snapshot gets a JPEG from cam's snapshot url.
+func (p *CameraProxy) snapshot(ctx context.Context, cam pcconfig.Camera) ([]byte, error) {
+	if cam.Snapshot == "" {
+		return nil, fmt.Errorf("camera %q has no snapshot url", cam.DisplayName)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cam.Snapshot, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build request: %w", err)
+	}
+
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("camera returned a %v", resp.StatusCode)
+	}
+
+	return readJPEG(resp.Body)
+}
+
+// readJPEG reads a JPEG from r, making sure it is reasonably sized and is actually a JPEG.
+func readJPEG(r io.Reader) ([]byte, error) {
+	jpeg, err := ioutil.ReadAll(io.LimitReader(r, _maxThumbnailSize+1))
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("unable to read image: %w", err)
+	case len(jpeg) > _maxThumbnailSize:
+		return nil, fmt.Errorf("image is larger than %d bytes", _maxThumbnailSize)
+	case !bytes.HasPrefix(jpeg, []byte{0xff, 0xd8, 0xff}):
+		return nil, fmt.Errorf("image is not a jpeg")
+	}
+
+	return jpeg, nil
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/gin-gonic/gin"
+	"github.com/go-kivik/kivik/v3"
+)
+
+var mockJPEG = []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F'}
+
+type mockThumbnailStore map[string][]byte
+
+func (m mockThumbnailStore) PutThumbnail(ctx context.Context, room, key string, jpeg []byte) error {
+	m[room+"/"+key] = jpeg
+	return nil
+}
+
+func (m mockThumbnailStore) Thumbnail(ctx context.Context, room, key string) ([]byte, error) {
+	jpeg, ok := m[room+"/"+key]
+	if !ok {
+		return nil, &kivik.Error{HTTPStatus: http.StatusNotFound, Err: errors.New("missing")}
+	}
+
+	return jpeg, nil
+}
+
+func TestCaptureThumbnail(t *testing.T) {
+	cam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write(mockJPEG)
+	}))
+	defer cam.Close()
+
+	ps := &mockPresetService{
+		mockConfigService: &mockConfigService{
+			mappings: map[string][2]string{
+				"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+			},
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {
+					{
+						DisplayName: "Front",
+						Snapshot:    cam.URL + "/snapshot.jpg",
+						Presets: []pcconfig.CameraPreset{
+							{DisplayName: "Podium", SetPreset: cam.URL + "/preset/1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	store := mockThumbnailStore{}
+	h := &Handlers{
+		ConfigService: ps,
+		CameraProxy:   &CameraProxy{BaseURL: "https://pc-config"},
+		Thumbnails:    store,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:hostname/cameras/:camera/presets/:slot/thumbnail", h.PresetThumbnail)
+	r.POST("/:hostname/cameras/:camera/presets/:slot/thumbnail", h.CaptureThumbnail)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ITB-1101-CP1/cameras/Front/presets/0/thumbnail", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	key := thumbnailKey("Group 1", "Front", 0)
+	switch {
+	case !bytes.Equal(store["ITB-1101/"+key], mockJPEG):
+		t.Fatalf("expected thumbnail to be stored under %q, got %v", key, store)
+	case ps.preset.Thumbnail != key:
+		t.Fatalf("expected preset to point at thumbnail, got %+v", ps.preset)
+	}
+
+	// pretend the preset was updated in the datastore
+	ps.cameras[[2]string{"ITB-1101", "Group 1"}][0].Presets[0].Thumbnail = key
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/cameras/Front/presets/0/thumbnail", nil))
+	switch {
+	case w.Code != http.StatusOK:
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	case !bytes.Equal(w.Body.Bytes(), mockJPEG):
+		t.Fatalf("got wrong thumbnail")
+	case w.Header().Get("ETag") == "":
+		t.Fatalf("expected an etag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/cameras/Front/presets/0/thumbnail", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+
+	// the preset points at a thumbnail that isn't stored anymore
+	delete(store, "ITB-1101/"+key)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ITB-1101-CP1/cameras/Front/presets/0/thumbnail", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing thumbnail, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUploadThumbnail(t *testing.T) {
+	ps := &mockPresetService{
+		mockConfigService: &mockConfigService{
+			cameras: map[[2]string][]pcconfig.Camera{
+				{"ITB-1101", "Group 1"}: {
+					{
+						DisplayName: "Front",
+						Presets:     []pcconfig.CameraPreset{{DisplayName: "Podium"}},
+					},
+				},
+			},
+		},
+	}
+
+	store := mockThumbnailStore{}
+	h := &Handlers{
+		ConfigService: ps,
+		Thumbnails:    store,
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	admin := r.Group("/admin", RequireToken("secret"))
+	admin.PUT("/rooms/:room/controlgroups/:controlGroup/cameras/:camera/presets/:slot/thumbnail", h.UploadThumbnail)
+
+	path := "/admin/rooms/ITB-1101/controlgroups/Group%201/cameras/Front/presets/0/thumbnail"
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, bytes.NewReader(mockJPEG)))
+	if w.Code != http.StatusUnauthorized || w.Body.String() != "invalid token" {
+		t.Fatalf("expected 401 without a token, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte("not a jpeg")))
+	req.Header.Set("Authorization", "Bearer secret")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-jpeg, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, path, bytes.NewReader(mockJPEG))
+	req.Header.Set("Authorization", "Bearer secret")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if ps.preset.Thumbnail != thumbnailKey("Group 1", "Front", 0) || len(store) != 1 {
+		t.Fatalf("expected thumbnail to be stored, got %+v %v", ps.preset, store)
+	}
+}
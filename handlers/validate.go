@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/byuoitav/pc-config/validate"
+	"github.com/gin-gonic/gin"
+)
+
+// Validate checks every room and pc mapping in the datastore and returns a report of the problems found.
+func (h *Handlers) Validate(c *gin.Context) {
+	lister, ok := h.ConfigService.(pcconfig.ConfigLister)
+	if !ok {
+		c.String(http.StatusNotFound, "validation is not supported by this datastore")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := validate.All(ctx, lister)
+	if err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to validate: %s", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Consistency compares every pc mapping against the room configurations and
+// returns a report of dangling mappings, unmapped control groups, and ambiguous mappings.
+func (h *Handlers) Consistency(c *gin.Context) {
+	lister, ok := h.ConfigService.(pcconfig.ConfigLister)
+	if !ok {
+		c.String(http.StatusNotFound, "consistency reports are not supported by this datastore")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := validate.Consistency(ctx, lister)
+	if err != nil {
+		c.String(http.StatusInternalServerError, fmt.Sprintf("unable to build report: %s", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,199 @@
+// Package jsonschema generates JSON Schemas from Go types and validates JSON documents against them.
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema draft standalone schemas are written in.
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is the subset of a JSON Schema that Go types are described with.
+type Schema struct {
+	Draft                string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// For returns a standalone schema for the JSON v is encoded as. Fields without omitempty are required.
+func For(v interface{}) *Schema {
+	return standalone(v, false)
+}
+
+// ForDecoding returns a standalone schema for the JSON documents that are decoded into v. Since
+// encoding/json doesn't need any fields to be present, only fields tagged `jsonschema:"required"` are required.
+func ForDecoding(v interface{}) *Schema {
+	return standalone(v, true)
+}
+
+func standalone(v interface{}, decoding bool) *Schema {
+	g := &Generator{
+		RefPrefix:   "#/definitions/",
+		Definitions: make(map[string]*Schema),
+		Decoding:    decoding,
+	}
+
+	s := g.Schema(reflect.TypeOf(v))
+
+	// put the root struct's schema at the root, so that it can be used without resolving a reference
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, g.RefPrefix)
+		root := *g.Definitions[name]
+		root.Definitions = g.Definitions
+		s = &root
+	} else {
+		s.Definitions = g.Definitions
+	}
+
+	s.Draft = Draft
+	if len(s.Definitions) == 0 {
+		s.Definitions = nil
+	}
+
+	return s
+}
+
+var _timeType = reflect.TypeOf(time.Time{})
+
+// Generator builds schemas for Go types, adding a definition for each named struct.
+type Generator struct {
+	// RefPrefix is prepended to the name of a definition to reference it.
+	RefPrefix string
+
+	// Definitions are the schemas of the named structs that have been referenced, by name.
+	Definitions map[string]*Schema
+
+	// Decoding is true if the schemas describe JSON that is decoded into the types.
+	Decoding bool
+
+	types map[string]reflect.Type
+}
+
+// Schema returns the schema for t.
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	switch {
+	case t == _timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Ptr:
+		return g.Schema(t.Elem())
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		return g.definition(t)
+	}
+
+	// interfaces can be anything
+	return &Schema{}
+}
+
+// definition adds a schema for the named struct t to the definitions, and returns a reference to it.
+func (g *Generator) definition(t reflect.Type) *Schema {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	ref := &Schema{Ref: g.RefPrefix + name}
+
+	if g.types == nil {
+		g.types = make(map[string]reflect.Type)
+	}
+
+	if prev, ok := g.types[name]; ok {
+		if prev != t {
+			panic(fmt.Sprintf("jsonschema: %s and %s have the same schema name", prev, t))
+		}
+
+		return ref
+	}
+
+	g.types[name] = t
+
+	// added before its fields, so that structs can refer to themselves
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.Definitions[name] = s
+	g.fields(t, s)
+
+	sort.Strings(s.Required)
+	return ref
+}
+
+// object returns the schema for an unnamed struct.
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+
+	sort.Strings(s.Required)
+	return s
+}
+
+// fields adds the json fields of the struct t to s.
+func (g *Generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i:]
+		}
+
+		// embedded structs without a name are flattened, like encoding/json does
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.fields(f.Type, s)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = g.Schema(f.Type)
+
+		required := !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr
+		if g.Decoding {
+			required = f.Tag.Get("jsonschema") == "required"
+		}
+
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
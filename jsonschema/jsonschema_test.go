@@ -0,0 +1,103 @@
+package jsonschema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testPreset struct {
+	Name string `json:"name" jsonschema:"required"`
+	Slot int    `json:"slot,omitempty"`
+}
+
+type testCamera struct {
+	Name    string            `json:"name" jsonschema:"required"`
+	Presets []testPreset      `json:"presets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Updated *time.Time        `json:"updated,omitempty"`
+	private string
+}
+
+func TestFor(t *testing.T) {
+	expected := &Schema{
+		Draft: Draft,
+		Type:  "object",
+		Properties: map[string]*Schema{
+			"name":    {Type: "string"},
+			"presets": {Type: "array", Items: &Schema{Ref: "#/definitions/TestPreset"}},
+			"labels":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"updated": {Type: "string", Format: "date-time"},
+		},
+		Required: []string{"name", "presets"},
+		Definitions: map[string]*Schema{
+			"TestPreset": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"name": {Type: "string"},
+					"slot": {Type: "integer"},
+				},
+				Required: []string{"name"},
+			},
+		},
+	}
+
+	got := For(testCamera{})
+	delete(got.Definitions, "TestCamera")
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("got incorrect schema (-want, +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"name"}, ForDecoding(testCamera{}).Required); diff != "" {
+		t.Errorf("decoding schema requires the wrong fields (-want, +got):\n%s", diff)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := ForDecoding(testCamera{})
+
+	tests := []struct {
+		name     string
+		doc      string
+		expected []ValidationError
+	}{
+		{
+			name: "Valid",
+			doc:  `{"name": "front", "presets": [{"name": "podium", "slot": 1}], "labels": {"a": "b"}, "extra": true}`,
+		},
+		{
+			name: "Null",
+			doc:  `{"name": "front", "presets": null, "updated": null}`,
+		},
+		{
+			name: "Invalid",
+			doc:  `{"presets": [{"name": 1}, {"name": "board", "slot": 1.5}], "labels": {"a": 2}}`,
+			expected: []ValidationError{
+				{Path: "", Message: "name is required"},
+				{Path: "labels.a", Message: "expected a string, got a number"},
+				{Path: "presets[0].name", Message: "expected a string, got a number"},
+				{Path: "presets[1].slot", Message: "expected an integer, got a number"},
+			},
+		},
+		{
+			name:     "NotAnObject",
+			doc:      `[]`,
+			expected: []ValidationError{{Message: "expected an object, got an array"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := s.ValidateJSON([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("unable to validate: %s", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, errs); diff != "" {
+				t.Errorf("got incorrect errors (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError is a single place a document doesn't match its schema.
+type ValidationError struct {
+	// Path is where in the document the error is, like presets[0].cameras[1].tiltUp.
+	// It is empty for the document itself.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+// ValidateJSON decodes data and validates it against s.
+func (s *Schema) ValidateJSON(data []byte) ([]ValidationError, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("unable to decode: %w", err)
+	}
+
+	return s.Validate(v), nil
+}
+
+// Validate validates v, which must be decoded JSON, against s. References are resolved using
+// the definitions of s. Like encoding/json, null is accepted for anything that isn't required.
+// Numbers may be float64s or json.Numbers.
+func (s *Schema) Validate(v interface{}) []ValidationError {
+	var errs []ValidationError
+	s.validate(s, "", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(root *Schema, path string, v interface{}, errs *[]ValidationError) {
+	if s.Ref != "" {
+		name := s.Ref[strings.LastIndex(s.Ref, "/")+1:]
+
+		def, ok := root.Definitions[name]
+		if !ok {
+			*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("unknown reference %q", s.Ref)})
+			return
+		}
+
+		def.validate(root, path, v, errs)
+		return
+	}
+
+	if v == nil {
+		return
+	}
+
+	fail := func(format string, a ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	switch s.Type {
+	case "string":
+		if _, ok := v.(string); !ok {
+			fail("expected a string, got %s", typeOf(v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected a boolean, got %s", typeOf(v))
+		}
+	case "integer":
+		if !isInteger(v) {
+			fail("expected an integer, got %s", typeOf(v))
+		}
+	case "number":
+		if !isNumber(v) {
+			fail("expected a number, got %s", typeOf(v))
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			fail("expected an array, got %s", typeOf(v))
+			return
+		}
+
+		if s.Items != nil {
+			for i, item := range arr {
+				s.Items.validate(root, fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("expected an object, got %s", typeOf(v))
+			return
+		}
+
+		for _, name := range s.Required {
+			if val, ok := obj[name]; !ok || val == nil {
+				fail("%s is required", name)
+			}
+		}
+
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		// unknown properties are allowed, since encoding/json ignores them
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+
+			if prop != nil {
+				prop.validate(root, join(path, k), obj[k], errs)
+			}
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func isInteger(v interface{}) bool {
+	switch v := v.(type) {
+	case json.Number:
+		_, err := v.Int64()
+		return err == nil
+	case float64:
+		return v == float64(int64(v))
+	}
+
+	return false
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case json.Number, float64:
+		return true
+	}
+
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case json.Number, float64:
+		return "a number"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,257 @@
+// Package openapi builds OpenAPI 3 documents from a list of routes and the Go types they send and receive.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/byuoitav/pc-config/jsonschema"
+)
+
+// Version is the version of the OpenAPI specification documents are written in.
+const Version = "3.0.3"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem is the operations on a path, keyed by lowercase http method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// Schema is an OpenAPI schema object.
+type Schema = jsonschema.Schema
+
+// Route is a single route served by pc-config.
+type Route struct {
+	// Method and Path are the route as it is registered with gin, like GET /:hostname/config.
+	Method  string
+	Path    string
+	Summary string
+	Tag     string
+
+	// Query is the description of each query parameter.
+	Query map[string]string
+
+	// Request is an example of the JSON request body, or nil if there isn't one.
+	Request interface{}
+
+	// RequestContentType is the type of a request body that isn't JSON.
+	RequestContentType string
+
+	// Status is the status code sent on success. Defaults to 200.
+	Status int
+
+	// Response is an example of the JSON response body, or nil if there isn't one.
+	Response interface{}
+
+	// ContentType is the type of the response body. Defaults to application/json if Response is set.
+	ContentType string
+
+	// Secured routes require the bearer token.
+	Secured bool
+}
+
+// New builds a document describing routes.
+func New(info Info, routes []Route) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+
+	g := &jsonschema.Generator{
+		RefPrefix:   "#/components/schemas/",
+		Definitions: doc.Components.Schemas,
+	}
+
+	for _, r := range routes {
+		path, params := convertPath(r.Path)
+
+		op := &Operation{
+			Summary:   r.Summary,
+			Responses: make(map[string]Response),
+		}
+
+		if r.Tag != "" {
+			op.Tags = []string{r.Tag}
+		}
+
+		for _, name := range params {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+
+		var query []string
+		for name := range r.Query {
+			query = append(query, name)
+		}
+
+		sort.Strings(query)
+		for _, name := range query {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:        name,
+				In:          "query",
+				Description: r.Query[name],
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+
+		switch {
+		case r.Request != nil:
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{
+					"application/json": {Schema: g.Schema(reflect.TypeOf(r.Request))},
+				},
+			}
+		case r.RequestContentType != "":
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{
+					r.RequestContentType: {Schema: &Schema{Type: "string", Format: "binary"}},
+				},
+			}
+		}
+
+		status := r.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		resp := Response{Description: http.StatusText(status)}
+		contentType := r.ContentType
+
+		switch {
+		case r.Response != nil:
+			if contentType == "" {
+				contentType = "application/json"
+			}
+
+			resp.Content = map[string]MediaType{
+				contentType: {Schema: g.Schema(reflect.TypeOf(r.Response))},
+			}
+		case contentType != "":
+			resp.Content = map[string]MediaType{
+				contentType: {Schema: &Schema{Type: "string", Format: "binary"}},
+			}
+		}
+
+		op.Responses[fmt.Sprintf("%d", status)] = resp
+
+		if r.Secured {
+			op.Security = []map[string][]string{{"bearer": {}}}
+			doc.Components.SecuritySchemes = map[string]SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer"},
+			}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+
+		doc.Paths[path][strings.ToLower(r.Method)] = op
+	}
+
+	return doc
+}
+
+// Routes returns the method and gin path of every operation in doc, like GET /:hostname/config.
+func (d *Document) Routes() []string {
+	var routes []string
+	for path, item := range d.Paths {
+		for method := range item {
+			routes = append(routes, strings.ToUpper(method)+" "+ginPath(path))
+		}
+	}
+
+	sort.Strings(routes)
+	return routes
+}
+
+// convertPath converts a gin path to an OpenAPI path, returning the names of its parameters.
+func convertPath(path string) (string, []string) {
+	var params []string
+
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+
+	return strings.Join(parts, "/"), params
+}
+
+// ginPath converts an OpenAPI path back to a gin path.
+func ginPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			parts[i] = ":" + strings.Trim(part, "{}")
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,105 @@
+package pcconfig
+
+// PCOverrides changes the config a single PC gets from its control group.
+type PCOverrides struct {
+	Cameras []CameraOverride `json:"cameras,omitempty"`
+}
+
+// CameraOverride changes a single camera for a PC.
+type CameraOverride struct {
+	// Camera is the name of the camera in the control group.
+	Camera string `json:"camera"`
+
+	// Hide removes the camera from the PC's config.
+	Hide bool `json:"hide,omitempty"`
+
+	// DisplayName renames the camera.
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Stream replaces the camera's stream url, e.g. with a lower-bandwidth variant.
+	Stream string `json:"stream,omitempty"`
+
+	// PresetOrder is the names of presets in the order they should be shown.
+	// Presets that aren't listed keep their order after the ones that are.
+	PresetOrder []string `json:"presetOrder,omitempty"`
+}
+
+// AppliedOverrides describes the overrides merged into a Config.
+type AppliedOverrides struct {
+	// Source is the pc mapping the overrides are stored on.
+	Source string `json:"source"`
+
+	Cameras []CameraOverride `json:"cameras"`
+}
+
+// Empty returns true if o doesn't change anything.
+func (o PCOverrides) Empty() bool {
+	return len(o.Cameras) == 0
+}
+
+// Camera returns the override for the camera named name.
+func (o PCOverrides) Camera(name string) (CameraOverride, bool) {
+	for _, c := range o.Cameras {
+		if c.Camera == name {
+			return c, true
+		}
+	}
+
+	return CameraOverride{}, false
+}
+
+// ApplyOverrides returns a copy of cams with o applied.
+func ApplyOverrides(cams []Camera, o PCOverrides) []Camera {
+	applied := make([]Camera, 0, len(cams))
+
+	for _, cam := range cams {
+		override, ok := o.Camera(cam.DisplayName)
+		switch {
+		case !ok:
+			applied = append(applied, cam)
+			continue
+		case override.Hide:
+			continue
+		}
+
+		if override.DisplayName != "" {
+			cam.DisplayName = override.DisplayName
+		}
+
+		if override.Stream != "" {
+			cam.Stream = override.Stream
+		}
+
+		if len(override.PresetOrder) > 0 {
+			cam.Presets = orderPresets(cam.Presets, override.PresetOrder)
+		}
+
+		applied = append(applied, cam)
+	}
+
+	return applied
+}
+
+// orderPresets returns a copy of presets with the ones named in order first.
+func orderPresets(presets []CameraPreset, order []string) []CameraPreset {
+	ordered := make([]CameraPreset, 0, len(presets))
+	used := make([]bool, len(presets))
+
+	for _, name := range order {
+		for i, p := range presets {
+			if !used[i] && p.DisplayName == name {
+				ordered = append(ordered, p)
+				used[i] = true
+				break
+			}
+		}
+	}
+
+	for i, p := range presets {
+		if !used[i] {
+			ordered = append(ordered, p)
+		}
+	}
+
+	return ordered
+}
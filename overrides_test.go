@@ -0,0 +1,52 @@
+package pcconfig
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	cams := []Camera{
+		{
+			DisplayName: "Front",
+			Stream:      "https://front/stream",
+			Presets: []CameraPreset{
+				{DisplayName: "Podium"},
+				{DisplayName: "Audience"},
+				{DisplayName: "Board"},
+			},
+		},
+		{DisplayName: "Back", Stream: "https://back/stream"},
+		{DisplayName: "Side", Stream: "https://side/stream"},
+	}
+
+	overrides := PCOverrides{
+		Cameras: []CameraOverride{
+			{Camera: "Front", DisplayName: "Lectern", Stream: "https://front/stream-low", PresetOrder: []string{"Board", "Missing"}},
+			{Camera: "Back", Hide: true},
+		},
+	}
+
+	expected := []Camera{
+		{
+			DisplayName: "Lectern",
+			Stream:      "https://front/stream-low",
+			Presets: []CameraPreset{
+				{DisplayName: "Board"},
+				{DisplayName: "Podium"},
+				{DisplayName: "Audience"},
+			},
+		},
+		{DisplayName: "Side", Stream: "https://side/stream"},
+	}
+
+	got := ApplyOverrides(cams, overrides)
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("applied overrides incorrectly (-want, +got):\n%s", diff)
+	}
+
+	if cams[0].DisplayName != "Front" || cams[0].Presets[0].DisplayName != "Podium" {
+		t.Errorf("expected original cameras to be unchanged, got %+v", cams[0])
+	}
+}
@@ -0,0 +1,51 @@
+package probe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMetrics writes the status of every camera to w in the prometheus text format.
+func (p *Prober) WriteMetrics(w io.Writer) error {
+	statuses := p.Statuses()
+	bw := bufio.NewWriter(w)
+
+	gauge := func(name, help string, value func(Status) float64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", name)
+
+		for _, s := range statuses {
+			fmt.Fprintf(bw, "%s{room=%s,control_group=%s,camera=%s} %g\n", name, label(s.Room), label(s.ControlGroup), label(s.Camera), value(s))
+		}
+	}
+
+	gauge("pc_config_camera_up", "Whether every check on the camera passed the last time it was probed.", func(s Status) float64 {
+		if s.Up {
+			return 1
+		}
+
+		return 0
+	})
+
+	gauge("pc_config_camera_probe_latency_seconds", "How long the slowest check on the camera took the last time it was probed.", func(s Status) float64 {
+		if len(s.History) == 0 {
+			return 0
+		}
+
+		return s.History[len(s.History)-1].LatencyMS / 1000
+	})
+
+	gauge("pc_config_camera_last_probe_timestamp_seconds", "When the camera was last probed.", func(s Status) float64 {
+		return float64(s.LastChecked.Unix())
+	})
+
+	return bw.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func label(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
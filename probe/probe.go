@@ -0,0 +1,432 @@
+// Package probe periodically checks that cameras can still be reached, without ever moving them.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// Target is the part of a camera that a Check looked at.
+type Target string
+
+const (
+	// TargetStream checks the camera's stream url.
+	TargetStream Target = "stream"
+
+	// TargetControl checks that the host the camera's controls go to accepts connections.
+	TargetControl Target = "control"
+)
+
+// Check is the result of checking a single target on a camera.
+type Check struct {
+	Target    Target  `json:"target"`
+	Up        bool    `json:"up"`
+	LatencyMS float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Sample is the overall result of probing a camera once.
+type Sample struct {
+	Time      time.Time `json:"time"`
+	Up        bool      `json:"up"`
+	LatencyMS float64   `json:"latencyMs"`
+}
+
+// Status is everything known about a camera's reachability.
+type Status struct {
+	Room         string `json:"room"`
+	ControlGroup string `json:"controlGroup"`
+	Camera       string `json:"camera"`
+
+	Up          bool      `json:"up"`
+	Since       time.Time `json:"since"`
+	LastChecked time.Time `json:"lastChecked"`
+
+	// Checks are the checks from the last probe.
+	Checks []Check `json:"checks"`
+
+	// History is the most recent samples, oldest first.
+	History []Sample `json:"history"`
+}
+
+type key struct {
+	room   string
+	cg     string
+	camera string
+}
+
+// Prober probes every camera in a datastore.
+type Prober struct {
+	Lister pcconfig.ConfigLister
+
+	// Client is used for http checks. http.DefaultClient is used if it is nil.
+	Client *http.Client
+
+	// Interval is how long to wait between probing every camera. Defaults to a minute.
+	Interval time.Duration
+
+	// Timeout is how long a single check can take. Defaults to 5 seconds.
+	Timeout time.Duration
+
+	// History is how many samples to keep for each camera. Defaults to 20.
+	History int
+
+	// Workers is how many cameras are probed at once. Defaults to 8.
+	Workers int
+
+	mu       sync.RWMutex
+	statuses map[key]*Status
+}
+
+// Run probes every camera each Interval until ctx is done.
+func (p *Prober) Run(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// a datastore that can't be listed right now might be back next time
+		_ = p.ProbeAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeAll probes every camera in the datastore once. Cameras that are no longer
+// in the datastore are forgotten.
+func (p *Prober) ProbeAll(ctx context.Context) error {
+	rooms, err := p.Lister.Rooms(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get rooms: %w", err)
+	}
+
+	type job struct {
+		key key
+		cam pcconfig.Camera
+	}
+
+	var jobs []job
+	for _, room := range rooms {
+		for _, cg := range room.ControlGroups {
+			for _, cam := range cg.Cameras {
+				jobs = append(jobs, job{key{room.ID, cg.Name, cam.DisplayName}, cam})
+			}
+		}
+	}
+
+	workers := p.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+
+	ch := make(chan job)
+	seen := make(map[key]bool)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				if checks := p.Probe(ctx, j.cam); len(checks) > 0 {
+					p.record(j.key, time.Now(), checks)
+				}
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		seen[j.key] = true
+		ch <- j
+	}
+
+	close(ch)
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k := range p.statuses {
+		if !seen[k] {
+			delete(p.statuses, k)
+		}
+	}
+
+	return nil
+}
+
+// Probe checks each of cam's targets. Cameras with nothing that can be safely checked get no checks.
+func (p *Prober) Probe(ctx context.Context, cam pcconfig.Camera) []Check {
+	var checks []Check
+
+	if cam.Stream != "" {
+		checks = append(checks, p.check(ctx, TargetStream, cam.Stream, p.checkStream))
+	}
+
+	if addr := controlAddress(cam); addr != "" {
+		checks = append(checks, p.check(ctx, TargetControl, addr, p.dial))
+	}
+
+	return checks
+}
+
+func (p *Prober) check(ctx context.Context, target Target, addr string, fn func(context.Context, string) error) Check {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx, addr)
+
+	check := Check{
+		Target:    target,
+		Up:        err == nil,
+		LatencyMS: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		check.Error = err.Error()
+	}
+
+	return check
+}
+
+// checkStream makes sure stream responds, without reading any of the stream itself.
+func (p *Prober) checkStream(ctx context.Context, stream string) error {
+	u, err := url.Parse(stream)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+	case "rtsp":
+		return p.dial(ctx, hostPort(u, "554"))
+	default:
+		return fmt.Errorf("unable to check %q urls", u.Scheme)
+	}
+
+	status, err := p.request(ctx, http.MethodHead, stream)
+	if status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented {
+		status, err = p.request(ctx, http.MethodGet, stream)
+	}
+
+	switch {
+	case err != nil:
+		return err
+	case status >= http.StatusBadRequest:
+		return fmt.Errorf("stream returned %d", status)
+	}
+
+	return nil
+}
+
+func (p *Prober) request(ctx context.Context, method, u string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to build request: %w", err)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to make request: %w", err)
+	}
+
+	// streams never end, so don't read the body
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func (p *Prober) dial(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to connect: %w", err)
+	}
+
+	return conn.Close()
+}
+
+// controlAddress returns the host:port that cam's controls go to, if it can be checked with a tcp connection.
+func controlAddress(cam pcconfig.Camera) string {
+	if cam.Protocol != "" {
+		// protocols like visca use udp, where there is no way to
+		// check the camera without sending it a command
+		u, err := url.Parse(cam.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return ""
+		}
+
+		return hostPort(u, "")
+	}
+
+	for _, a := range []string{cam.PanTiltStop, cam.ZoomStop, cam.TiltUp, cam.TiltDown, cam.PanLeft, cam.PanRight, cam.ZoomIn, cam.ZoomOut} {
+		if a == "" {
+			continue
+		}
+
+		u, err := url.Parse(a)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		return hostPort(u, "")
+	}
+
+	return ""
+}
+
+// hostPort returns the host:port in u, using port (or the scheme's default port) if it doesn't have one.
+func hostPort(u *url.URL, port string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func (p *Prober) record(k key, now time.Time, checks []Check) {
+	sample := Sample{
+		Time: now,
+		Up:   true,
+	}
+
+	for _, c := range checks {
+		sample.Up = sample.Up && c.Up
+		if c.LatencyMS > sample.LatencyMS {
+			sample.LatencyMS = c.LatencyMS
+		}
+	}
+
+	size := p.History
+	if size <= 0 {
+		size = 20
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.statuses == nil {
+		p.statuses = make(map[key]*Status)
+	}
+
+	s, ok := p.statuses[k]
+	if !ok {
+		s = &Status{
+			Room:         k.room,
+			ControlGroup: k.cg,
+			Camera:       k.camera,
+			Up:           sample.Up,
+			Since:        now,
+		}
+
+		p.statuses[k] = s
+	}
+
+	if s.Up != sample.Up {
+		s.Up = sample.Up
+		s.Since = now
+	}
+
+	s.LastChecked = now
+	s.Checks = checks
+	s.History = append(s.History, sample)
+	if len(s.History) > size {
+		s.History = append([]Sample(nil), s.History[len(s.History)-size:]...)
+	}
+}
+
+// Status returns the status of a single camera.
+func (p *Prober) Status(room, controlGroup, camera string) (Status, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, ok := p.statuses[key{room, controlGroup, camera}]
+	if !ok {
+		return Status{}, false
+	}
+
+	return s.copy(), true
+}
+
+// Statuses returns the status of every camera that has been probed, sorted by room, control group, and camera.
+func (p *Prober) Statuses() []Status {
+	p.mu.RLock()
+	statuses := make([]Status, 0, len(p.statuses))
+	for _, s := range p.statuses {
+		statuses = append(statuses, s.copy())
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		switch {
+		case a.Room != b.Room:
+			return a.Room < b.Room
+		case a.ControlGroup != b.ControlGroup:
+			return a.ControlGroup < b.ControlGroup
+		}
+
+		return a.Camera < b.Camera
+	})
+
+	return statuses
+}
+
+// Annotate returns a copy of cams with Status set on every camera that has been probed.
+func (p *Prober) Annotate(room, controlGroup string, cams []pcconfig.Camera) []pcconfig.Camera {
+	annotated := make([]pcconfig.Camera, len(cams))
+
+	for i, cam := range cams {
+		cam.Status = ""
+		if s, ok := p.Status(room, controlGroup, cam.DisplayName); ok {
+			cam.Status = pcconfig.CameraStatusDown
+			if s.Up {
+				cam.Status = pcconfig.CameraStatusUp
+			}
+		}
+
+		annotated[i] = cam
+	}
+
+	return annotated
+}
+
+func (s *Status) copy() Status {
+	c := *s
+	c.Checks = append([]Check(nil), s.Checks...)
+	c.History = append([]Sample(nil), s.History...)
+	return c
+}
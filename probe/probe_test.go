@@ -0,0 +1,125 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+type mockLister []pcconfig.Room
+
+func (m mockLister) PCMappings(ctx context.Context) ([]pcconfig.PCMapping, error) {
+	return nil, nil
+}
+
+func (m mockLister) Rooms(ctx context.Context) ([]pcconfig.Room, error) {
+	return m, nil
+}
+
+func TestProbeAll(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+
+	cam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		if r.URL.Path == "/nohead" && r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer cam.Close()
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	rtsp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %s", err)
+	}
+	defer rtsp.Close()
+
+	lister := mockLister{
+		{
+			ID: "ITB-1101",
+			ControlGroups: []pcconfig.ControlGroup{
+				{
+					Name: "Group 1",
+					Cameras: []pcconfig.Camera{
+						{DisplayName: "Front", Stream: cam.URL + "/stream", PanLeft: cam.URL + "/left", PanTiltStop: cam.URL + "/stop"},
+						{DisplayName: "Back", Stream: cam.URL + "/nohead"},
+						{DisplayName: "Side", Stream: dead.URL + "/stream"},
+						{DisplayName: "Rtsp", Stream: "rtsp://" + rtsp.Addr().String() + "/stream"},
+						{DisplayName: "Visca", Protocol: "visca", Address: "127.0.0.1:52381"},
+					},
+				},
+			},
+		},
+	}
+
+	p := &Prober{Lister: lister, History: 2}
+	for i := 0; i < 3; i++ {
+		if err := p.ProbeAll(context.Background()); err != nil {
+			t.Fatalf("unable to probe: %s", err)
+		}
+	}
+
+	for _, r := range requests {
+		if r != "HEAD /stream" && r != "HEAD /nohead" && r != "GET /nohead" {
+			t.Fatalf("expected only stream requests, got %q", r)
+		}
+	}
+
+	statuses := p.Statuses()
+	if len(statuses) != 4 {
+		t.Fatalf("expected 4 statuses, got %+v", statuses)
+	}
+
+	up := map[string]bool{"Back": true, "Front": true, "Rtsp": true, "Side": false}
+	for _, s := range statuses {
+		switch {
+		case s.Up != up[s.Camera]:
+			t.Fatalf("expected %s to be up=%v, got %+v", s.Camera, up[s.Camera], s)
+		case len(s.History) != 2:
+			t.Fatalf("expected 2 samples of history for %s, got %d", s.Camera, len(s.History))
+		}
+	}
+
+	front, _ := p.Status("ITB-1101", "Group 1", "Front")
+	if len(front.Checks) != 2 || front.Checks[1].Target != TargetControl || !front.Checks[1].Up {
+		t.Fatalf("expected stream and control checks on Front, got %+v", front.Checks)
+	}
+
+	cams := p.Annotate("ITB-1101", "Group 1", lister[0].ControlGroups[0].Cameras)
+	if cams[0].Status != pcconfig.CameraStatusUp || cams[2].Status != pcconfig.CameraStatusDown || cams[4].Status != "" {
+		t.Fatalf("got wrong annotations: %+v", cams)
+	}
+
+	var metrics strings.Builder
+	if err := p.WriteMetrics(&metrics); err != nil {
+		t.Fatalf("unable to write metrics: %s", err)
+	}
+
+	if !strings.Contains(metrics.String(), `pc_config_camera_up{room="ITB-1101",control_group="Group 1",camera="Side"} 0`) {
+		t.Fatalf("expected Side to be down in metrics, got:\n%s", metrics.String())
+	}
+
+	// cameras removed from the datastore are forgotten
+	lister[0].ControlGroups[0].Cameras = lister[0].ControlGroups[0].Cameras[:1]
+	if err := p.ProbeAll(context.Background()); err != nil {
+		t.Fatalf("unable to probe: %s", err)
+	}
+
+	if len(p.Statuses()) != 1 {
+		t.Fatalf("expected removed cameras to be forgotten, got %+v", p.Statuses())
+	}
+}
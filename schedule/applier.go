@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// Applier applies change sets to the datastore once they take effect. It keeps the change sets
+// it last read, so that they don't have to be read for every config that is served.
+type Applier struct {
+	Service pcconfig.ScheduleService
+
+	// Interval is how often to check for change sets that are due. Defaults to a minute.
+	Interval time.Duration
+
+	mu     sync.RWMutex
+	sets   []pcconfig.ChangeSet
+	loaded bool
+}
+
+// Run applies due change sets and reloads ChangeSets each Interval until ctx is done.
+func (a *Applier) Run(ctx context.Context) error {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// PCs are already being served due change sets, so there's no rush if this fails
+		_ = a.Refresh(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ChangeSets returns the change sets as of the last Refresh or Reload. ok is false until one of them succeeds.
+func (a *Applier) ChangeSets() (sets []pcconfig.ChangeSet, ok bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.sets, a.loaded
+}
+
+// Refresh applies every change set that is effective at now, and then reloads ChangeSets.
+func (a *Applier) Refresh(ctx context.Context, now time.Time) error {
+	sets, err := a.Service.ChangeSets(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get change sets: %w", err)
+	}
+
+	applied, applyErr := a.applyDue(ctx, sets, now)
+	if len(applied) == 0 {
+		a.store(sets)
+		return applyErr
+	}
+
+	// pick up which ones were applied
+	if err := a.Reload(ctx); err != nil {
+		return err
+	}
+
+	return applyErr
+}
+
+// Reload reads the change sets that ChangeSets returns again, without applying any of them.
+func (a *Applier) Reload(ctx context.Context) error {
+	sets, err := a.Service.ChangeSets(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get change sets: %w", err)
+	}
+
+	a.store(sets)
+	return nil
+}
+
+func (a *Applier) store(sets []pcconfig.ChangeSet) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sets, a.loaded = sets, true
+}
+
+// ApplyDue applies every change set that is effective at now, oldest first, and returns the ids of the ones it applied.
+func (a *Applier) ApplyDue(ctx context.Context, now time.Time) ([]string, error) {
+	sets, err := a.Service.ChangeSets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get change sets: %w", err)
+	}
+
+	return a.applyDue(ctx, sets, now)
+}
+
+func (a *Applier) applyDue(ctx context.Context, sets []pcconfig.ChangeSet, now time.Time) ([]string, error) {
+	var applied []string
+	for _, set := range Due(sets, now) {
+		// later change sets might depend on this one, so stop at the first failure
+		if err := a.Service.ApplyChangeSet(ctx, set.ID); err != nil {
+			return applied, fmt.Errorf("unable to apply change set %q: %w", set.ID, err)
+		}
+
+		applied = append(applied, set.ID)
+	}
+
+	return applied, nil
+}
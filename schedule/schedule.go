@@ -0,0 +1,222 @@
+// Package schedule serves and applies change sets that are staged to take effect in the future.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// View is a ConfigService that serves the config as it will be once its change sets are applied.
+type View struct {
+	pcconfig.ConfigService
+
+	// changeSets are the change sets to layer on top of ConfigService, oldest first.
+	changeSets []pcconfig.ChangeSet
+}
+
+// At returns a View of cs at time at, using the change sets in sets that haven't been applied yet.
+func At(cs pcconfig.ConfigService, sets []pcconfig.ChangeSet, at time.Time) *View {
+	v := &View{ConfigService: cs}
+
+	for _, set := range sets {
+		if !set.Applied && !set.Effective.After(at) {
+			v.changeSets = append(v.changeSets, set)
+		}
+	}
+
+	sort.SliceStable(v.changeSets, func(i, j int) bool {
+		return v.changeSets[i].Effective.Before(v.changeSets[j].Effective)
+	})
+
+	return v
+}
+
+// Due returns the change sets in sets that haven't been applied and are effective at at, oldest first.
+// Canary change sets are never due, since they are only applied once they are promoted.
+func Due(sets []pcconfig.ChangeSet, at time.Time) []pcconfig.ChangeSet {
+	var due []pcconfig.ChangeSet
+	for _, set := range At(nil, sets, at).changeSets {
+		if set.Canary == nil {
+			due = append(due, set)
+		}
+	}
+
+	return due
+}
+
+// ForHostname returns the change sets in sets that target hostname.
+func ForHostname(sets []pcconfig.ChangeSet, hostname string) []pcconfig.ChangeSet {
+	var targeted []pcconfig.ChangeSet
+	for _, set := range sets {
+		if set.Targets(hostname) {
+			targeted = append(targeted, set)
+		}
+	}
+
+	return targeted
+}
+
+// WithoutCanaries returns the change sets in sets that are served to every PC.
+func WithoutCanaries(sets []pcconfig.ChangeSet) []pcconfig.ChangeSet {
+	var released []pcconfig.ChangeSet
+	for _, set := range sets {
+		if set.Canary == nil {
+			released = append(released, set)
+		}
+	}
+
+	return released
+}
+
+func (v *View) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
+	if mapping, ok := v.mapping(ctx, hostname); ok {
+		return mapping.Room, mapping.ControlGroup, nil
+	}
+
+	return v.ConfigService.RoomAndControlGroup(ctx, hostname)
+}
+
+func (v *View) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
+	for i := len(v.changeSets) - 1; i >= 0; i-- {
+		for _, r := range v.changeSets[i].Rooms {
+			if r.ID != room {
+				continue
+			}
+
+			for _, cg := range r.ControlGroups {
+				if cg.Name == controlGroup {
+					return v.resolve(ctx, room, cg)
+				}
+			}
+		}
+	}
+
+	return v.ConfigService.Cameras(ctx, room, controlGroup)
+}
+
+// Room returns room from the datastore, with the control groups in the change sets replaced or added.
+func (v *View) Room(ctx context.Context, room string) (pcconfig.Room, error) {
+	rs, ok := v.ConfigService.(pcconfig.RoomService)
+	if !ok {
+		return pcconfig.Room{}, errors.New("rooms are not supported by this datastore")
+	}
+
+	r, err := rs.Room(ctx, room)
+	if err != nil {
+		return pcconfig.Room{}, err
+	}
+
+	for _, set := range v.changeSets {
+		for _, staged := range set.Rooms {
+			if staged.ID != room {
+				continue
+			}
+
+			for _, cg := range staged.ControlGroups {
+				cams, err := v.resolve(ctx, room, cg)
+				if err != nil {
+					return pcconfig.Room{}, err
+				}
+
+				r.ControlGroups = replaceControlGroup(r.ControlGroups, pcconfig.ControlGroup{Name: cg.Name, Cameras: cams})
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (v *View) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	mapping, ok := v.mapping(ctx, hostname)
+	switch {
+	case ok && mapping.Overrides != nil:
+		return *mapping.Overrides, mapping.Hostname, nil
+	case ok:
+		return pcconfig.PCOverrides{}, mapping.Hostname, nil
+	}
+
+	if overrider, ok := v.ConfigService.(pcconfig.OverrideService); ok {
+		return overrider.Overrides(ctx, hostname)
+	}
+
+	return pcconfig.PCOverrides{}, "", nil
+}
+
+// mapping returns the staged mapping for hostname, if it is a better match than the one in the datastore.
+func (v *View) mapping(ctx context.Context, hostname string) (pcconfig.PCMapping, bool) {
+	staged, ok := v.stagedMapping(hostname)
+	if !ok {
+		return pcconfig.PCMapping{}, false
+	}
+
+	// a longer mapping that already exists is still a better match
+	if overrider, ok := v.ConfigService.(pcconfig.OverrideService); ok {
+		if _, source, err := overrider.Overrides(ctx, hostname); err == nil && len(source) > len(staged.Hostname) {
+			return pcconfig.PCMapping{}, false
+		}
+	}
+
+	return staged, true
+}
+
+// stagedMapping returns the newest staged mapping for hostname, trimming it the same way the datastore does.
+func (v *View) stagedMapping(hostname string) (pcconfig.PCMapping, bool) {
+	for _, h := range pcconfig.MappingCandidates(hostname) {
+		for i := len(v.changeSets) - 1; i >= 0; i-- {
+			for _, m := range v.changeSets[i].Mappings {
+				if m.Hostname == h {
+					return m, true
+				}
+			}
+		}
+	}
+
+	return pcconfig.PCMapping{}, false
+}
+
+// resolve resolves the templates of the staged cameras in cg.
+func (v *View) resolve(ctx context.Context, room string, cg pcconfig.ControlGroup) ([]pcconfig.Camera, error) {
+	r := pcconfig.Room{ID: room, ControlGroups: []pcconfig.ControlGroup{cg}}
+
+	t, ok := v.ConfigService.(pcconfig.TemplateService)
+	if !ok || len(pcconfig.TemplateNames(r)) == 0 {
+		return cg.Cameras, nil
+	}
+
+	templates, err := t.Templates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get templates: %w", err)
+	}
+
+	resolved, err := pcconfig.ResolveRoomTemplates(r, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved.ControlGroups[0].Cameras, nil
+}
+
+// replaceControlGroup replaces the control group in cgs with the same name as cg, or adds cg if there isn't one.
+func replaceControlGroup(cgs []pcconfig.ControlGroup, cg pcconfig.ControlGroup) []pcconfig.ControlGroup {
+	replaced := make([]pcconfig.ControlGroup, 0, len(cgs)+1)
+	found := false
+
+	for _, existing := range cgs {
+		if existing.Name == cg.Name {
+			existing, found = cg, true
+		}
+
+		replaced = append(replaced, existing)
+	}
+
+	if !found {
+		replaced = append(replaced, cg)
+	}
+
+	return replaced
+}
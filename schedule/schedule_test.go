@@ -0,0 +1,221 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockConfigService struct {
+	mappings map[string]pcconfig.PCMapping
+	cameras  map[[2]string][]pcconfig.Camera
+}
+
+func (m *mockConfigService) mapping(hostname string) (pcconfig.PCMapping, bool) {
+	for _, h := range pcconfig.MappingCandidates(hostname) {
+		if mapping, ok := m.mappings[h]; ok {
+			return mapping, true
+		}
+	}
+
+	return pcconfig.PCMapping{}, false
+}
+
+func (m *mockConfigService) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
+	mapping, ok := m.mapping(hostname)
+	if !ok {
+		return "", "", errors.New("no mapping")
+	}
+
+	return mapping.Room, mapping.ControlGroup, nil
+}
+
+func (m *mockConfigService) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
+	cameras, ok := m.cameras[[2]string{room, controlGroup}]
+	if !ok {
+		return nil, errors.New("no matching control group found")
+	}
+
+	return cameras, nil
+}
+
+func (m *mockConfigService) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	mapping, ok := m.mapping(hostname)
+	if !ok {
+		return pcconfig.PCOverrides{}, "", errors.New("no mapping")
+	}
+
+	return pcconfig.PCOverrides{}, mapping.Hostname, nil
+}
+
+type mockScheduleService struct {
+	sets    []pcconfig.ChangeSet
+	applied []string
+}
+
+func (m *mockScheduleService) ChangeSets(ctx context.Context) ([]pcconfig.ChangeSet, error) {
+	return m.sets, nil
+}
+
+func (m *mockScheduleService) PutChangeSet(ctx context.Context, cs pcconfig.ChangeSet) error {
+	return nil
+}
+
+func (m *mockScheduleService) DeleteChangeSet(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *mockScheduleService) ApplyChangeSet(ctx context.Context, id string) error {
+	m.applied = append(m.applied, id)
+
+	for i := range m.sets {
+		if m.sets[i].ID == id {
+			m.sets[i].Applied = true
+		}
+	}
+
+	return nil
+}
+
+func (m *mockScheduleService) RecordCanaryFetch(ctx context.Context, id, hostname string, at time.Time) error {
+	return nil
+}
+
+var (
+	_summer = time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	_fall   = time.Date(2026, 8, 24, 0, 0, 0, 0, time.UTC)
+)
+
+func mockChangeSets() []pcconfig.ChangeSet {
+	return []pcconfig.ChangeSet{
+		{
+			ID:        "fall",
+			Effective: _fall,
+			Rooms: []pcconfig.Room{
+				{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Fall"}}}}},
+			},
+			Mappings: []pcconfig.PCMapping{
+				{Hostname: "ITB-1101-CP", Room: "ITB-1101", ControlGroup: "Group 2"},
+			},
+		},
+		{
+			ID:        "summer",
+			Effective: _summer,
+			Rooms: []pcconfig.Room{
+				{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Summer"}}}}},
+			},
+		},
+		{
+			ID:        "old",
+			Effective: _summer.Add(-time.Hour),
+			Applied:   true,
+			Rooms: []pcconfig.Room{
+				{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Old"}}}}},
+			},
+		},
+	}
+}
+
+func TestView(t *testing.T) {
+	cs := &mockConfigService{
+		mappings: map[string]pcconfig.PCMapping{
+			"ITB-1101-CP1": {Hostname: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1"},
+			"ITB-1101":     {Hostname: "ITB-1101", Room: "ITB-1101", ControlGroup: "Group 1"},
+		},
+		cameras: map[[2]string][]pcconfig.Camera{
+			{"ITB-1101", "Group 1"}: {{DisplayName: "Current"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		hostname string
+		cg       string
+		camera   string
+	}{
+		{name: "BeforeAnyChanges", at: _summer.Add(-time.Minute), hostname: "ITB-1101-CP2", cg: "Group 1", camera: "Current"},
+		{name: "Summer", at: _summer, hostname: "ITB-1101-CP2", cg: "Group 1", camera: "Summer"},
+		{name: "FallMapping", at: _fall, hostname: "ITB-1101-CP2", cg: "Group 2"},
+		{name: "FallLongerExistingMapping", at: _fall, hostname: "ITB-1101-CP1", cg: "Group 1", camera: "Fall"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := At(cs, mockChangeSets(), tt.at)
+
+			_, cg, err := v.RoomAndControlGroup(context.Background(), tt.hostname)
+			if err != nil {
+				t.Fatalf("unable to get room/control group: %s", err)
+			}
+
+			if cg != tt.cg {
+				t.Fatalf("got wrong control group: expected %q, got %q", tt.cg, cg)
+			}
+
+			if tt.camera == "" {
+				return
+			}
+
+			cams, err := v.Cameras(context.Background(), "ITB-1101", cg)
+			if err != nil {
+				t.Fatalf("unable to get cameras: %s", err)
+			}
+
+			if len(cams) != 1 || cams[0].DisplayName != tt.camera {
+				t.Fatalf("expected camera %q, got %+v", tt.camera, cams)
+			}
+		})
+	}
+}
+
+func TestApplyDue(t *testing.T) {
+	sets := append(mockChangeSets(), pcconfig.ChangeSet{
+		ID:        "canary",
+		Effective: _summer,
+		Canary:    &pcconfig.Canary{Hostnames: []string{"ITB-1101-CP1"}},
+	})
+
+	ss := &mockScheduleService{sets: sets}
+	a := &Applier{Service: ss}
+
+	applied, err := a.ApplyDue(context.Background(), _fall)
+	if err != nil {
+		t.Fatalf("unable to apply change sets: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"summer", "fall"}, applied); diff != "" {
+		t.Errorf("applied wrong change sets (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ss := &mockScheduleService{sets: mockChangeSets()}
+	a := &Applier{Service: ss}
+
+	if _, ok := a.ChangeSets(); ok {
+		t.Fatalf("expected no change sets before the first refresh")
+	}
+
+	if err := a.Refresh(context.Background(), _summer); err != nil {
+		t.Fatalf("unable to refresh: %s", err)
+	}
+
+	sets, ok := a.ChangeSets()
+	if !ok {
+		t.Fatalf("expected change sets after refreshing")
+	}
+
+	applied := make(map[string]bool)
+	for _, set := range sets {
+		applied[set.ID] = set.Applied
+	}
+
+	if diff := cmp.Diff(map[string]bool{"old": true, "summer": true, "fall": false}, applied); diff != "" {
+		t.Errorf("got wrong change sets (-want, +got):\n%s", diff)
+	}
+}
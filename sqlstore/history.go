@@ -0,0 +1,205 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// RoomHistory returns every revision of room, newest first. Revisions are kept forever, so they are always available.
+func (s *store) RoomHistory(ctx context.Context, room string) ([]pcconfig.Revision, error) {
+	return history(ctx, s.db, `SELECT rev FROM room_revisions WHERE room = $1 ORDER BY rev DESC`, room)
+}
+
+func (s *store) RoomAt(ctx context.Context, room, rev string) (pcconfig.Room, error) {
+	if rev == "" {
+		rooms, err := loadRooms(ctx, s.db, []string{room})
+		switch {
+		case err != nil:
+			return pcconfig.Room{}, fmt.Errorf("unable to get room: %w", err)
+		case len(rooms) == 0:
+			return pcconfig.Room{}, fmt.Errorf("no room %q: %w", room, ErrNotFound)
+		}
+
+		return rooms[0], nil
+	}
+
+	var r pcconfig.Room
+	if err := getRev(ctx, s.db, `SELECT room_config FROM room_revisions WHERE room = $1 AND rev = $2`, room, rev, &r); err != nil {
+		return pcconfig.Room{}, err
+	}
+
+	return r, nil
+}
+
+// RollbackRoom replaces room with the room at rev. Thumbnails aren't rolled back.
+func (s *store) RollbackRoom(ctx context.Context, room, rev string) (string, error) {
+	var newRev string
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var r pcconfig.Room
+		if err := getRev(ctx, tx, `SELECT room_config FROM room_revisions WHERE room = $1 AND rev = $2`, room, rev, &r); err != nil {
+			return err
+		}
+
+		if err := putRoom(ctx, tx, r); err != nil {
+			return err
+		}
+
+		var err error
+		newRev, err = recordRoom(ctx, tx, room)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to roll back room %q: %w", room, err)
+	}
+
+	return newRev, nil
+}
+
+// MappingHistory returns every revision of the pc mapping hostname, newest first.
+func (s *store) MappingHistory(ctx context.Context, hostname string) ([]pcconfig.Revision, error) {
+	return history(ctx, s.db, `SELECT rev FROM mapping_revisions WHERE id = $1 ORDER BY rev DESC`, hostname)
+}
+
+func (s *store) MappingAt(ctx context.Context, hostname, rev string) (pcconfig.PCMapping, error) {
+	if rev == "" {
+		found, err := s.mappings(ctx, []string{hostname})
+		if err != nil {
+			return pcconfig.PCMapping{}, fmt.Errorf("unable to get pc mapping: %w", err)
+		}
+
+		mapping, ok := found[hostname]
+		if !ok {
+			return pcconfig.PCMapping{}, fmt.Errorf("no pc mapping %q: %w", hostname, ErrNotFound)
+		}
+
+		return mapping, nil
+	}
+
+	var m pcconfig.PCMapping
+	if err := getRev(ctx, s.db, `SELECT mapping FROM mapping_revisions WHERE id = $1 AND rev = $2`, hostname, rev, &m); err != nil {
+		return pcconfig.PCMapping{}, err
+	}
+
+	return m, nil
+}
+
+func (s *store) RollbackMapping(ctx context.Context, hostname, rev string) (string, error) {
+	var newRev string
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var m pcconfig.PCMapping
+		if err := getRev(ctx, tx, `SELECT mapping FROM mapping_revisions WHERE id = $1 AND rev = $2`, hostname, rev, &m); err != nil {
+			return err
+		}
+
+		var err error
+		newRev, err = putMapping(ctx, tx, m)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to roll back pc mapping %q: %w", hostname, err)
+	}
+
+	return newRev, nil
+}
+
+// history returns the revisions query finds for id. Revisions are only recorded from schema version 2
+// on, so a room or mapping that hasn't changed since then doesn't have any.
+func history(ctx context.Context, q querier, query, id string) ([]pcconfig.Revision, error) {
+	revs := []pcconfig.Revision{}
+
+	err := queryRows(ctx, q, query, []interface{}{id}, func(rows *sql.Rows) error {
+		var rev int
+		if err := rows.Scan(&rev); err != nil {
+			return err
+		}
+
+		revs = append(revs, pcconfig.Revision{Rev: strconv.Itoa(rev), Available: true})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get revisions: %w", err)
+	}
+
+	return revs, nil
+}
+
+// getRev decodes the revision query finds for id and rev into dest.
+func getRev(ctx context.Context, q querier, query, id, rev string, dest interface{}) error {
+	n, err := strconv.Atoi(rev)
+	if err != nil {
+		return fmt.Errorf("no revision %q of %q: %w", rev, id, ErrNotFound)
+	}
+
+	var data string
+	err = q.QueryRowContext(ctx, query, id, n).Scan(&data)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("no revision %q of %q: %w", rev, id, ErrNotFound)
+	case err != nil:
+		return fmt.Errorf("unable to get %s at %q: %w", id, rev, err)
+	}
+
+	if err := json.Unmarshal([]byte(data), dest); err != nil {
+		return fmt.Errorf("unable to decode %s at %q: %w", id, rev, err)
+	}
+
+	return nil
+}
+
+// recordRoom stores room as it is now as its next revision, and returns the revision.
+func recordRoom(ctx context.Context, tx *sql.Tx, room string) (string, error) {
+	rooms, err := loadRooms(ctx, tx, []string{room})
+	switch {
+	case err != nil:
+		return "", fmt.Errorf("unable to get room: %w", err)
+	case len(rooms) == 0:
+		return "", fmt.Errorf("no room %q: %w", room, ErrNotFound)
+	}
+
+	return record(ctx, tx, "room_revisions", "room", "room_config", room, rooms[0])
+}
+
+// putMapping adds or replaces m, and returns its new revision.
+func putMapping(ctx context.Context, tx *sql.Tx, m pcconfig.PCMapping) (string, error) {
+	overrides, err := nullJSON(m.Overrides, m.Overrides == nil || m.Overrides.Empty())
+	if err != nil {
+		return "", fmt.Errorf("unable to encode overrides of pc mapping %q: %w", m.Hostname, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO pc_mappings (id, room, control_group, overrides) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET room = excluded.room, control_group = excluded.control_group, overrides = excluded.overrides`,
+		m.Hostname, m.Room, m.ControlGroup, overrides); err != nil {
+		return "", fmt.Errorf("unable to put pc mapping %q: %w", m.Hostname, err)
+	}
+
+	if !overrides.Valid {
+		m.Overrides = nil
+	}
+
+	return record(ctx, tx, "mapping_revisions", "id", "mapping", m.Hostname, m)
+}
+
+// record stores v as the next revision of id in table, and returns the revision.
+func record(ctx context.Context, tx *sql.Tx, table, idColumn, column, id string, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode revision: %w", err)
+	}
+
+	var rev int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(rev), 0) + 1 FROM `+table+` WHERE `+idColumn+` = $1`, id).Scan(&rev); err != nil {
+		return "", fmt.Errorf("unable to get next revision: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+table+` (`+idColumn+`, rev, `+column+`) VALUES ($1, $2, $3)`, id, rev, string(data)); err != nil {
+		return "", fmt.Errorf("unable to record revision: %w", err)
+	}
+
+	return strconv.Itoa(rev), nil
+}
@@ -0,0 +1,122 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoomHistory(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.Import(ctx, nil, testRooms, testTemplates); err != nil {
+		t.Fatalf("unable to import: %s", err)
+	}
+
+	if err := s.SetCameraPreset(ctx, "ITB-1101", "Group 1", "Back", 0, pcconfig.CameraPreset{DisplayName: "Lectern"}); err != nil {
+		t.Fatalf("unable to set preset: %s", err)
+	}
+
+	revs, err := s.RoomHistory(ctx, "ITB-1101")
+	if err != nil {
+		t.Fatalf("unable to get history: %s", err)
+	}
+
+	expected := []pcconfig.Revision{{Rev: "2", Available: true}, {Rev: "1", Available: true}}
+	if diff := cmp.Diff(expected, revs); diff != "" {
+		t.Fatalf("got wrong revisions (-want, +got):\n%s", diff)
+	}
+
+	// revisions are stored as they were given, without their templates resolved
+	old, err := s.RoomAt(ctx, "ITB-1101", "1")
+	if err != nil {
+		t.Fatalf("unable to get room at 1: %s", err)
+	}
+
+	if diff := cmp.Diff(testRooms[0], old); diff != "" {
+		t.Errorf("got wrong room at 1 (-want, +got):\n%s", diff)
+	}
+
+	// the handlers send a 404 for errors with a not found status code
+	_, err = s.RoomAt(ctx, "ITB-1101", "3")
+	var coder interface{ StatusCode() int }
+	switch {
+	case !errors.Is(err, ErrNotFound):
+		t.Fatalf("expected ErrNotFound for a revision that doesn't exist, got %v", err)
+	case !errors.As(err, &coder) || coder.StatusCode() != http.StatusNotFound:
+		t.Fatalf("expected a 404 status code, got %v", err)
+	}
+
+	rev, err := s.RollbackRoom(ctx, "ITB-1101", "1")
+	switch {
+	case err != nil:
+		t.Fatalf("unable to roll back: %s", err)
+	case rev != "3":
+		t.Fatalf("expected revision 3, got %q", rev)
+	}
+
+	current, err := s.RoomAt(ctx, "ITB-1101", "")
+	if err != nil {
+		t.Fatalf("unable to get room: %s", err)
+	}
+
+	if diff := cmp.Diff(testRooms[0], current); diff != "" {
+		t.Errorf("room wasn't rolled back (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMappingHistory(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	moved := pcconfig.PCMapping{Hostname: "ITB-1101-CP2", Room: "ITB-1101", ControlGroup: "Group 1"}
+	for _, mappings := range [][]pcconfig.PCMapping{testMappings, {moved}} {
+		if err := s.Import(ctx, mappings, nil, nil); err != nil {
+			t.Fatalf("unable to import: %s", err)
+		}
+	}
+
+	revs, err := s.MappingHistory(ctx, "ITB-1101-CP2")
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get history: %s", err)
+	case len(revs) != 2 || revs[0].Rev != "2":
+		t.Fatalf("got wrong revisions %+v", revs)
+	}
+
+	old, err := s.MappingAt(ctx, "ITB-1101-CP2", "1")
+	if err != nil {
+		t.Fatalf("unable to get mapping at 1: %s", err)
+	}
+
+	if diff := cmp.Diff(testMappings[1], old); diff != "" {
+		t.Errorf("got wrong mapping at 1 (-want, +got):\n%s", diff)
+	}
+
+	if _, err := s.RollbackMapping(ctx, "ITB-1101-CP2", "1"); err != nil {
+		t.Fatalf("unable to roll back: %s", err)
+	}
+
+	current, err := s.MappingAt(ctx, "ITB-1101-CP2", "")
+	if err != nil {
+		t.Fatalf("unable to get mapping: %s", err)
+	}
+
+	if diff := cmp.Diff(testMappings[1], current); diff != "" {
+		t.Errorf("mapping wasn't rolled back (-want, +got):\n%s", diff)
+	}
+
+	if _, err := s.MappingAt(ctx, "JFSB-CP1", ""); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
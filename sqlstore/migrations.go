@@ -0,0 +1,164 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations are the statements that build the schema, in order. Migration i+1 (its version) is applied
+// after migration i. Migrations that have been released can't be changed; add a new one instead.
+//
+// Every statement has to work on both PostgreSQL and SQLite.
+var migrations = [][]string{
+	{
+		`CREATE TABLE pc_mappings (
+			id TEXT PRIMARY KEY,
+			room TEXT NOT NULL,
+			control_group TEXT NOT NULL,
+			overrides TEXT
+		)`,
+		`CREATE TABLE rooms (
+			id TEXT PRIMARY KEY
+		)`,
+		`CREATE TABLE control_groups (
+			room TEXT NOT NULL REFERENCES rooms (id),
+			name TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			PRIMARY KEY (room, name)
+		)`,
+		`CREATE TABLE cameras (
+			room TEXT NOT NULL,
+			control_group TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			display_name TEXT NOT NULL,
+			template TEXT NOT NULL,
+			variables TEXT,
+			tilt_up TEXT NOT NULL,
+			tilt_down TEXT NOT NULL,
+			pan_left TEXT NOT NULL,
+			pan_right TEXT NOT NULL,
+			pan_tilt_stop TEXT NOT NULL,
+			zoom_in TEXT NOT NULL,
+			zoom_out TEXT NOT NULL,
+			zoom_stop TEXT NOT NULL,
+			stream TEXT NOT NULL,
+			snapshot TEXT NOT NULL,
+			address TEXT NOT NULL,
+			protocol TEXT NOT NULL,
+			capabilities TEXT,
+			PRIMARY KEY (room, control_group, position),
+			FOREIGN KEY (room, control_group) REFERENCES control_groups (room, name)
+		)`,
+		`CREATE TABLE presets (
+			room TEXT NOT NULL,
+			control_group TEXT NOT NULL,
+			camera INTEGER NOT NULL,
+			position INTEGER NOT NULL,
+			display_name TEXT NOT NULL,
+			set_preset TEXT NOT NULL,
+			save_preset TEXT NOT NULL,
+			thumbnail TEXT NOT NULL,
+			preset TEXT NOT NULL,
+			PRIMARY KEY (room, control_group, camera, position),
+			FOREIGN KEY (room, control_group, camera) REFERENCES cameras (room, control_group, position)
+		)`,
+		`CREATE TABLE camera_templates (
+			name TEXT PRIMARY KEY,
+			camera TEXT NOT NULL
+		)`,
+		`CREATE TABLE thumbnails (
+			room TEXT NOT NULL,
+			name TEXT NOT NULL,
+			jpeg BYTEA NOT NULL,
+			PRIMARY KEY (room, name)
+		)`,
+		`CREATE TABLE check_ins (
+			hostname TEXT PRIMARY KEY,
+			config_version TEXT NOT NULL,
+			app_version TEXT NOT NULL,
+			uptime_seconds BIGINT NOT NULL,
+			checked_in TIMESTAMP NOT NULL
+		)`,
+	},
+	{
+		`CREATE TABLE change_sets (
+			id TEXT PRIMARY KEY,
+			change_set TEXT NOT NULL
+		)`,
+		`CREATE TABLE canary_fetches (
+			change_set TEXT NOT NULL REFERENCES change_sets (id),
+			hostname TEXT NOT NULL,
+			fetched TIMESTAMP NOT NULL,
+			PRIMARY KEY (change_set, hostname)
+		)`,
+		`CREATE TABLE room_revisions (
+			room TEXT NOT NULL,
+			rev INTEGER NOT NULL,
+			room_config TEXT NOT NULL,
+			PRIMARY KEY (room, rev)
+		)`,
+		`CREATE TABLE mapping_revisions (
+			id TEXT NOT NULL,
+			rev INTEGER NOT NULL,
+			mapping TEXT NOT NULL,
+			PRIMARY KEY (id, rev)
+		)`,
+	},
+}
+
+// Migrate applies each migration that hasn't been applied to db yet, and returns the schema version db is at.
+// Each migration is applied in its own transaction.
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return 0, fmt.Errorf("unable to create schema_migrations: %w", err)
+	}
+
+	version, err := schemaVersion(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	if version > len(migrations) {
+		return version, fmt.Errorf("database is at schema version %d, which is newer than this version of pc-config (%d)", version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		if err := migrate(ctx, db, version+1, migrations[version]); err != nil {
+			return version, fmt.Errorf("unable to apply migration %d: %w", version+1, err)
+		}
+	}
+
+	return version, nil
+}
+
+func migrate(ctx context.Context, db *sql.DB, version int, stmts []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return fmt.Errorf("unable to record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("unable to get schema version: %w", err)
+	}
+
+	return int(version.Int64), nil
+}
@@ -0,0 +1,237 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// _cameraColumns are the columns of a camera's fields, in the order scanCamera and cameraValues use.
+const _cameraColumns = `display_name, template, variables, tilt_up, tilt_down, pan_left, pan_right, pan_tilt_stop,
+	zoom_in, zoom_out, zoom_stop, stream, snapshot, address, protocol, capabilities`
+
+// loadRooms gets the rooms in ids, sorted by id, without resolving their templates. Rooms that don't
+// exist aren't returned. If ids is nil, every room is returned.
+func loadRooms(ctx context.Context, q querier, ids []string) ([]pcconfig.Room, error) {
+	rooms := make(map[string]*pcconfig.Room)
+
+	err := inChunks(ids, func(where func(string) string, args []interface{}) error {
+		if err := queryRows(ctx, q, `SELECT id FROM rooms`+where("id"), args, func(rows *sql.Rows) error {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+
+			rooms[id] = &pcconfig.Room{ID: id, ControlGroups: []pcconfig.ControlGroup{}}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to get rooms: %w", err)
+		}
+
+		if err := queryRows(ctx, q, `SELECT room, name FROM control_groups`+where("room")+` ORDER BY room, position`, args, func(rows *sql.Rows) error {
+			var room, name string
+			if err := rows.Scan(&room, &name); err != nil {
+				return err
+			}
+
+			if r, ok := rooms[room]; ok {
+				r.ControlGroups = append(r.ControlGroups, pcconfig.ControlGroup{Name: name, Cameras: []pcconfig.Camera{}})
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to get control groups: %w", err)
+		}
+
+		if err := queryRows(ctx, q, `SELECT room, control_group, `+_cameraColumns+` FROM cameras`+where("room")+` ORDER BY room, control_group, position`, args, func(rows *sql.Rows) error {
+			var room, controlGroup string
+			cam, err := scanCamera(rows, &room, &controlGroup)
+			if err != nil {
+				return err
+			}
+
+			if cg := findControlGroup(rooms[room], controlGroup); cg != nil {
+				cg.Cameras = append(cg.Cameras, cam)
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to get cameras: %w", err)
+		}
+
+		if err := queryRows(ctx, q, `SELECT room, control_group, camera, display_name, set_preset, save_preset, thumbnail, preset FROM presets`+where("room")+` ORDER BY room, control_group, camera, position`, args, func(rows *sql.Rows) error {
+			var room, controlGroup string
+			var camera int
+			var p pcconfig.CameraPreset
+
+			if err := rows.Scan(&room, &controlGroup, &camera, &p.DisplayName, &p.SetPreset, &p.SavePreset, &p.Thumbnail, &p.Preset); err != nil {
+				return err
+			}
+
+			if cg := findControlGroup(rooms[room], controlGroup); cg != nil && camera < len(cg.Cameras) {
+				cg.Cameras[camera].Presets = append(cg.Cameras[camera].Presets, p)
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to get presets: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]pcconfig.Room, 0, len(rooms))
+	for _, room := range rooms {
+		sorted = append(sorted, *room)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return sorted, nil
+}
+
+// putRoom replaces room, and all of its control groups, cameras, and presets. Cameras are stored
+// as they are given, so their templates should not be resolved.
+func putRoom(ctx context.Context, tx *sql.Tx, room pcconfig.Room) error {
+	for _, stmt := range []string{
+		`DELETE FROM presets WHERE room = $1`,
+		`DELETE FROM cameras WHERE room = $1`,
+		`DELETE FROM control_groups WHERE room = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, room.ID); err != nil {
+			return fmt.Errorf("unable to clear room: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO rooms (id) VALUES ($1) ON CONFLICT (id) DO NOTHING`, room.ID); err != nil {
+		return fmt.Errorf("unable to insert room: %w", err)
+	}
+
+	for i, cg := range room.ControlGroups {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO control_groups (room, name, position) VALUES ($1, $2, $3)`, room.ID, cg.Name, i); err != nil {
+			return fmt.Errorf("unable to insert control group %q: %w", cg.Name, err)
+		}
+
+		for j, cam := range cg.Cameras {
+			values, err := cameraValues(cam)
+			if err != nil {
+				return fmt.Errorf("unable to encode camera %q: %w", cam.DisplayName, err)
+			}
+
+			args := append([]interface{}{room.ID, cg.Name, j}, values...)
+			if _, err := tx.ExecContext(ctx, `INSERT INTO cameras (room, control_group, position, `+_cameraColumns+`)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`, args...); err != nil {
+				return fmt.Errorf("unable to insert camera %q: %w", cam.DisplayName, err)
+			}
+
+			for k, p := range cam.Presets {
+				if err := insertPreset(ctx, tx, room.ID, cg.Name, j, k, p); err != nil {
+					return fmt.Errorf("unable to insert preset %q of camera %q: %w", p.DisplayName, cam.DisplayName, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func insertPreset(ctx context.Context, tx *sql.Tx, room, controlGroup string, camera, position int, p pcconfig.CameraPreset) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO presets (room, control_group, camera, position, display_name, set_preset, save_preset, thumbnail, preset)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, room, controlGroup, camera, position, p.DisplayName, p.SetPreset, p.SavePreset, p.Thumbnail, p.Preset)
+	return err
+}
+
+// scanCamera scans a row of room, control group, and then _cameraColumns.
+func scanCamera(rows *sql.Rows, room, controlGroup *string) (pcconfig.Camera, error) {
+	var cam pcconfig.Camera
+	var variables, capabilities sql.NullString
+
+	if err := rows.Scan(room, controlGroup, &cam.DisplayName, &cam.Template, &variables, &cam.TiltUp, &cam.TiltDown, &cam.PanLeft, &cam.PanRight, &cam.PanTiltStop,
+		&cam.ZoomIn, &cam.ZoomOut, &cam.ZoomStop, &cam.Stream, &cam.Snapshot, &cam.Address, &cam.Protocol, &capabilities); err != nil {
+		return cam, err
+	}
+
+	if variables.Valid {
+		if err := json.Unmarshal([]byte(variables.String), &cam.Variables); err != nil {
+			return cam, fmt.Errorf("unable to decode variables of camera %q: %w", cam.DisplayName, err)
+		}
+	}
+
+	if capabilities.Valid {
+		if err := json.Unmarshal([]byte(capabilities.String), &cam.Capabilities); err != nil {
+			return cam, fmt.Errorf("unable to decode capabilities of camera %q: %w", cam.DisplayName, err)
+		}
+	}
+
+	return cam, nil
+}
+
+// cameraValues returns the values of _cameraColumns for cam.
+func cameraValues(cam pcconfig.Camera) ([]interface{}, error) {
+	variables, err := nullJSON(cam.Variables, len(cam.Variables) == 0)
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities, err := nullJSON(cam.Capabilities, len(cam.Capabilities) == 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{cam.DisplayName, cam.Template, variables, cam.TiltUp, cam.TiltDown, cam.PanLeft, cam.PanRight, cam.PanTiltStop,
+		cam.ZoomIn, cam.ZoomOut, cam.ZoomStop, cam.Stream, cam.Snapshot, cam.Address, cam.Protocol, capabilities}, nil
+}
+
+func findControlGroup(room *pcconfig.Room, name string) *pcconfig.ControlGroup {
+	if room == nil {
+		return nil
+	}
+
+	for i := range room.ControlGroups {
+		if room.ControlGroups[i].Name == name {
+			return &room.ControlGroups[i]
+		}
+	}
+
+	return nil
+}
+
+// queryRows runs query and calls fn with each row.
+func queryRows(ctx context.Context, q querier, query string, args []interface{}, fn func(*sql.Rows) error) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// nullJSON encodes v as JSON, or returns NULL if empty is true.
+func nullJSON(v interface{}, empty bool) (sql.NullString, error) {
+	if empty {
+		return sql.NullString{}, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
@@ -0,0 +1,206 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+func (s *store) ChangeSets(ctx context.Context) ([]pcconfig.ChangeSet, error) {
+	sets := []pcconfig.ChangeSet{}
+	byID := make(map[string]int)
+
+	err := queryRows(ctx, s.db, `SELECT id, change_set FROM change_sets ORDER BY id`, nil, func(rows *sql.Rows) error {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return err
+		}
+
+		var set pcconfig.ChangeSet
+		if err := json.Unmarshal([]byte(data), &set); err != nil {
+			return fmt.Errorf("unable to decode change set %q: %w", id, err)
+		}
+
+		set.ID = id
+		byID[id] = len(sets)
+		sets = append(sets, set)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get change sets: %w", err)
+	}
+
+	err = queryRows(ctx, s.db, `SELECT change_set, hostname, fetched FROM canary_fetches`, nil, func(rows *sql.Rows) error {
+		var id, hostname string
+		var fetched time.Time
+		if err := rows.Scan(&id, &hostname, &fetched); err != nil {
+			return err
+		}
+
+		i, ok := byID[id]
+		if !ok || sets[i].Canary == nil {
+			return nil
+		}
+
+		if sets[i].Canary.Fetched == nil {
+			sets[i].Canary.Fetched = make(map[string]time.Time)
+		}
+
+		sets[i].Canary.Fetched[hostname] = fetched.UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get canary fetches: %w", err)
+	}
+
+	return sets, nil
+}
+
+// PutChangeSet creates or replaces cs. The canary fetches of a change set are kept when it is replaced.
+func (s *store) PutChangeSet(ctx context.Context, cs pcconfig.ChangeSet) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return putChangeSet(ctx, tx, cs)
+	})
+}
+
+func (s *store) DeleteChangeSet(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM canary_fetches WHERE change_set = $1`, id); err != nil {
+			return fmt.Errorf("unable to delete canary fetches: %w", err)
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM change_sets WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("unable to delete change set: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return fmt.Errorf("no change set %q: %w", id, ErrNotFound)
+		}
+
+		return nil
+	})
+}
+
+// RecordCanaryFetch records the first time hostname fetched the change set id.
+func (s *store) RecordCanaryFetch(ctx context.Context, id, hostname string, at time.Time) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := getChangeSet(ctx, tx, id); err != nil {
+			return fmt.Errorf("unable to record canary fetch: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO canary_fetches (change_set, hostname, fetched) VALUES ($1, $2, $3)
+			ON CONFLICT (change_set, hostname) DO NOTHING`, id, hostname, at.UTC()); err != nil {
+			return fmt.Errorf("unable to record canary fetch: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ApplyChangeSet writes each room and mapping in the change set, and marks it as applied, all in one transaction.
+func (s *store) ApplyChangeSet(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		set, err := getChangeSet(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		for _, staged := range set.Rooms {
+			rooms, err := loadRooms(ctx, tx, []string{staged.ID})
+			if err != nil {
+				return fmt.Errorf("unable to get room %q: %w", staged.ID, err)
+			}
+
+			room := pcconfig.Room{ID: staged.ID}
+			if len(rooms) > 0 {
+				room = rooms[0]
+			}
+
+			for _, cg := range staged.ControlGroups {
+				room.ControlGroups = replaceControlGroup(room.ControlGroups, cg)
+			}
+
+			if err := putRoom(ctx, tx, room); err != nil {
+				return fmt.Errorf("unable to put room %q: %w", room.ID, err)
+			}
+
+			if _, err := recordRoom(ctx, tx, room.ID); err != nil {
+				return fmt.Errorf("unable to record room %q: %w", room.ID, err)
+			}
+		}
+
+		for _, m := range set.Mappings {
+			if _, err := putMapping(ctx, tx, m); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		set.Applied, set.AppliedAt = true, &now
+
+		if err := putChangeSet(ctx, tx, set); err != nil {
+			return fmt.Errorf("unable to mark change set applied: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// getChangeSet gets the change set id, without its canary fetches.
+func getChangeSet(ctx context.Context, q querier, id string) (pcconfig.ChangeSet, error) {
+	var data string
+	err := q.QueryRowContext(ctx, `SELECT change_set FROM change_sets WHERE id = $1`, id).Scan(&data)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return pcconfig.ChangeSet{}, fmt.Errorf("no change set %q: %w", id, ErrNotFound)
+	case err != nil:
+		return pcconfig.ChangeSet{}, fmt.Errorf("unable to get change set: %w", err)
+	}
+
+	var set pcconfig.ChangeSet
+	if err := json.Unmarshal([]byte(data), &set); err != nil {
+		return pcconfig.ChangeSet{}, fmt.Errorf("unable to decode change set %q: %w", id, err)
+	}
+
+	set.ID = id
+	return set, nil
+}
+
+// putChangeSet stores cs. Its canary fetches are stored separately, so they aren't included.
+func putChangeSet(ctx context.Context, tx *sql.Tx, cs pcconfig.ChangeSet) error {
+	if cs.Canary != nil {
+		canary := *cs.Canary
+		canary.Fetched = nil
+		cs.Canary = &canary
+	}
+
+	data, err := json.Marshal(cs)
+	if err != nil {
+		return fmt.Errorf("unable to encode change set: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO change_sets (id, change_set) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET change_set = excluded.change_set`, cs.ID, string(data)); err != nil {
+		return fmt.Errorf("unable to put change set: %w", err)
+	}
+
+	return nil
+}
+
+// replaceControlGroup replaces the control group in cgs with the same name as cg, or adds cg if there isn't one.
+func replaceControlGroup(cgs []pcconfig.ControlGroup, cg pcconfig.ControlGroup) []pcconfig.ControlGroup {
+	for i := range cgs {
+		if cgs[i].Name == cg.Name {
+			cgs[i] = cg
+			return cgs
+		}
+	}
+
+	return append(cgs, cg)
+}
@@ -0,0 +1,122 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChangeSets(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.Import(ctx, testMappings, testRooms, testTemplates); err != nil {
+		t.Fatalf("unable to import: %s", err)
+	}
+
+	fetched := time.Date(2026, 8, 20, 6, 0, 0, 0, time.UTC)
+	set := pcconfig.ChangeSet{
+		ID:        "fall",
+		Effective: time.Date(2026, 8, 24, 6, 0, 0, 0, time.UTC),
+		Rooms: []pcconfig.Room{
+			{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{
+				{Name: "Group 2", Cameras: []pcconfig.Camera{{DisplayName: "New", Stream: "https://new"}}},
+				{Name: "Group 3", Cameras: []pcconfig.Camera{}},
+			}},
+		},
+		Mappings: []pcconfig.PCMapping{{Hostname: "ITB-1101-CP3", Room: "ITB-1101", ControlGroup: "Group 3"}},
+		Canary:   &pcconfig.Canary{Hostnames: []string{"ITB-1101-CP1"}},
+	}
+
+	if err := s.PutChangeSet(ctx, set); err != nil {
+		t.Fatalf("unable to put change set: %s", err)
+	}
+
+	for _, at := range []time.Time{fetched, fetched.Add(time.Hour)} {
+		if err := s.RecordCanaryFetch(ctx, "fall", "ITB-1101-CP1", at); err != nil {
+			t.Fatalf("unable to record canary fetch: %s", err)
+		}
+	}
+
+	if err := s.RecordCanaryFetch(ctx, "spring", "ITB-1101-CP1", fetched); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a change set that doesn't exist, got %v", err)
+	}
+
+	// replacing the change set keeps its fetches
+	set.Canary.Percent = 10
+	if err := s.PutChangeSet(ctx, set); err != nil {
+		t.Fatalf("unable to put change set: %s", err)
+	}
+
+	sets, err := s.ChangeSets(ctx)
+	if err != nil {
+		t.Fatalf("unable to get change sets: %s", err)
+	}
+
+	expected := set
+	expected.Canary = &pcconfig.Canary{
+		Hostnames: []string{"ITB-1101-CP1"},
+		Percent:   10,
+		Fetched:   map[string]time.Time{"ITB-1101-CP1": fetched},
+	}
+
+	if diff := cmp.Diff([]pcconfig.ChangeSet{expected}, sets); diff != "" {
+		t.Errorf("got wrong change sets (-want, +got):\n%s", diff)
+	}
+
+	if err := s.ApplyChangeSet(ctx, "fall"); err != nil {
+		t.Fatalf("unable to apply change set: %s", err)
+	}
+
+	room, cg, err := s.RoomAndControlGroup(ctx, "ITB-1101-CP3")
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get room and control group: %s", err)
+	case room != "ITB-1101" || cg != "Group 3":
+		t.Fatalf("got wrong room and control group %q, %q", room, cg)
+	}
+
+	// the staged control groups are replaced or added, and the rest are kept
+	r, err := s.Room(ctx, "ITB-1101")
+	if err != nil {
+		t.Fatalf("unable to get room: %s", err)
+	}
+
+	var names []string
+	for _, cg := range r.ControlGroups {
+		names = append(names, cg.Name)
+	}
+
+	switch {
+	case !cmp.Equal([]string{"Group 1", "Group 2", "Group 3"}, names):
+		t.Fatalf("got wrong control groups %v", names)
+	case !cmp.Equal(set.Rooms[0].ControlGroups[0], r.ControlGroups[1]):
+		t.Fatalf("control group wasn't replaced: %+v", r.ControlGroups[1])
+	}
+
+	sets, err = s.ChangeSets(ctx)
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get change sets: %s", err)
+	case !sets[0].Applied || sets[0].AppliedAt == nil:
+		t.Fatalf("expected the change set to be applied, got %+v", sets[0])
+	}
+
+	if err := s.DeleteChangeSet(ctx, "fall"); err != nil {
+		t.Fatalf("unable to delete change set: %s", err)
+	}
+
+	if err := s.DeleteChangeSet(ctx, "fall"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if sets, err := s.ChangeSets(ctx); err != nil || len(sets) != 0 {
+		t.Fatalf("expected no change sets, got %+v, %v", sets, err)
+	}
+}
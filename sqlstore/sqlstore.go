@@ -0,0 +1,365 @@
+// Package sqlstore stores pc-config's configuration in a SQL database, using database/sql. Its
+// queries work on both PostgreSQL and SQLite.
+//
+// Every change to a room or pc mapping is kept as a revision, starting at schema version 2.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// _maxParams is the most values put in a single IN clause, to stay under SQLite's limit on parameters.
+const _maxParams = 500
+
+// ErrNotFound is returned (wrapped) when a pc mapping, room, template, thumbnail, change set, or revision doesn't exist.
+// Like couch's errors, it has a StatusCode, so the handlers respond with a 404.
+var ErrNotFound error = notFoundError{}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string {
+	return "not found"
+}
+
+func (notFoundError) StatusCode() int {
+	return http.StatusNotFound
+}
+
+type store struct {
+	db *sql.DB
+}
+
+// querier is a *sql.DB or a *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// New creates a ConfigService that stores config in db. New doesn't change db's schema, since every
+// instance of pc-config would try to at once; it must already be up to date (see Migrate).
+func New(ctx context.Context, db *sql.DB) (pcconfig.ConfigService, error) {
+	version, err := schemaVersion(ctx, db)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("%w (has pc-config sql migrate been run?)", err)
+	case version < len(migrations):
+		return nil, fmt.Errorf("database is at schema version %d, but this version of pc-config needs %d. run pc-config sql migrate to update it", version, len(migrations))
+	case version > len(migrations):
+		return nil, fmt.Errorf("database is at schema version %d, which is newer than this version of pc-config (%d)", version, len(migrations))
+	}
+
+	return &store{db: db}, nil
+}
+
+func (s *store) RoomAndControlGroup(ctx context.Context, hostname string) (string, string, error) {
+	mapping, err := s.mapping(ctx, hostname)
+	if err != nil {
+		return "", "", err
+	}
+
+	return mapping.Room, mapping.ControlGroup, nil
+}
+
+func (s *store) Overrides(ctx context.Context, hostname string) (pcconfig.PCOverrides, string, error) {
+	mapping, err := s.mapping(ctx, hostname)
+	if err != nil {
+		return pcconfig.PCOverrides{}, "", err
+	}
+
+	if mapping.Overrides == nil {
+		return pcconfig.PCOverrides{}, mapping.Hostname, nil
+	}
+
+	return *mapping.Overrides, mapping.Hostname, nil
+}
+
+// mapping gets the longest pc mapping that hostname starts with. Its Hostname is the mapping's id.
+func (s *store) mapping(ctx context.Context, hostname string) (pcconfig.PCMapping, error) {
+	ids := pcconfig.MappingCandidates(hostname)
+
+	found, err := s.mappings(ctx, ids)
+	if err != nil {
+		return pcconfig.PCMapping{}, fmt.Errorf("unable to get pc mapping: %w", err)
+	}
+
+	for _, id := range ids {
+		if mapping, ok := found[id]; ok {
+			return mapping, nil
+		}
+	}
+
+	return pcconfig.PCMapping{}, fmt.Errorf("no pc mapping for %q: %w", hostname, ErrNotFound)
+}
+
+// mappings gets the pc mappings in ids, by id. If ids is nil, every mapping is returned.
+func (s *store) mappings(ctx context.Context, ids []string) (map[string]pcconfig.PCMapping, error) {
+	found := make(map[string]pcconfig.PCMapping)
+
+	err := inChunks(ids, func(where func(string) string, args []interface{}) error {
+		return queryRows(ctx, s.db, `SELECT id, room, control_group, overrides FROM pc_mappings`+where("id"), args, func(rows *sql.Rows) error {
+			var mapping pcconfig.PCMapping
+			var overrides sql.NullString
+
+			if err := rows.Scan(&mapping.Hostname, &mapping.Room, &mapping.ControlGroup, &overrides); err != nil {
+				return err
+			}
+
+			if overrides.Valid {
+				if err := json.Unmarshal([]byte(overrides.String), &mapping.Overrides); err != nil {
+					return fmt.Errorf("unable to decode overrides of pc mapping %q: %w", mapping.Hostname, err)
+				}
+			}
+
+			found[mapping.Hostname] = mapping
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (s *store) Cameras(ctx context.Context, room, controlGroup string) ([]pcconfig.Camera, error) {
+	rooms, err := loadRooms(ctx, s.db, []string{room})
+	switch {
+	case err != nil:
+		return []pcconfig.Camera{}, fmt.Errorf("unable to get room: %w", err)
+	case len(rooms) == 0:
+		return []pcconfig.Camera{}, fmt.Errorf("no room %q: %w", room, ErrNotFound)
+	}
+
+	for _, cg := range rooms[0].ControlGroups {
+		if cg.Name != controlGroup {
+			continue
+		}
+
+		r := pcconfig.Room{ID: room, ControlGroups: []pcconfig.ControlGroup{cg}}
+
+		templates, err := s.templates(ctx, pcconfig.TemplateNames(r))
+		if err != nil {
+			return []pcconfig.Camera{}, err
+		}
+
+		resolved, err := resolveRoom(r, templates)
+		if err != nil {
+			return []pcconfig.Camera{}, err
+		}
+
+		return resolved.ControlGroups[0].Cameras, nil
+	}
+
+	return []pcconfig.Camera{}, errors.New("no matching control group found")
+}
+
+// Room returns every control group in room, with their templates resolved.
+func (s *store) Room(ctx context.Context, room string) (pcconfig.Room, error) {
+	rooms, err := loadRooms(ctx, s.db, []string{room})
+	switch {
+	case err != nil:
+		return pcconfig.Room{}, fmt.Errorf("unable to get room: %w", err)
+	case len(rooms) == 0:
+		return pcconfig.Room{}, fmt.Errorf("no room %q: %w", room, ErrNotFound)
+	}
+
+	templates, err := s.templates(ctx, pcconfig.TemplateNames(rooms[0]))
+	if err != nil {
+		return pcconfig.Room{}, err
+	}
+
+	return resolveRoom(rooms[0], templates)
+}
+
+func (s *store) PCMappings(ctx context.Context) ([]pcconfig.PCMapping, error) {
+	found, err := s.mappings(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pc mappings: %w", err)
+	}
+
+	mappings := make([]pcconfig.PCMapping, 0, len(found))
+	for _, mapping := range found {
+		mappings = append(mappings, mapping)
+	}
+
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].Hostname < mappings[j].Hostname
+	})
+
+	return mappings, nil
+}
+
+func (s *store) Rooms(ctx context.Context) ([]pcconfig.Room, error) {
+	rooms, err := loadRooms(ctx, s.db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get rooms: %w", err)
+	}
+
+	templates, err := s.Templates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rooms {
+		// cameras that can't be resolved are left for validation to report
+		rooms[i], _ = pcconfig.ResolveRoomTemplates(rooms[i], templates)
+	}
+
+	return rooms, nil
+}
+
+func (s *store) PCMappingsFor(ctx context.Context, hostnames []string) (map[string]pcconfig.PCMapping, error) {
+	if len(hostnames) == 0 {
+		return map[string]pcconfig.PCMapping{}, nil
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+
+	for _, hostname := range hostnames {
+		for _, id := range pcconfig.MappingCandidates(hostname) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	found, err := s.mappings(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pc mappings: %w", err)
+	}
+
+	mappings := make(map[string]pcconfig.PCMapping)
+	for _, hostname := range hostnames {
+		for _, id := range pcconfig.MappingCandidates(hostname) {
+			if mapping, ok := found[id]; ok {
+				mappings[hostname] = mapping
+				break
+			}
+		}
+	}
+
+	return mappings, nil
+}
+
+func (s *store) RoomsFor(ctx context.Context, ids []string) (map[string]pcconfig.Room, error) {
+	if len(ids) == 0 {
+		return map[string]pcconfig.Room{}, nil
+	}
+
+	rooms, err := loadRooms(ctx, s.db, ids)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get rooms: %w", err)
+	}
+
+	templates, err := s.templates(ctx, pcconfig.TemplateNames(rooms...))
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]pcconfig.Room, len(rooms))
+	for _, room := range rooms {
+		if resolved, err := resolveRoom(room, templates); err == nil {
+			found[room.ID] = resolved
+		}
+	}
+
+	return found, nil
+}
+
+// Templates returns every camera template, keyed by name.
+func (s *store) Templates(ctx context.Context) (map[string]pcconfig.Camera, error) {
+	return s.templates(ctx, nil)
+}
+
+// templates gets the camera templates in names, by name. If names is nil, every template is returned.
+func (s *store) templates(ctx context.Context, names []string) (map[string]pcconfig.Camera, error) {
+	return loadTemplates(ctx, s.db, names)
+}
+
+// loadTemplates gets the camera templates in names from q, like templates.
+func loadTemplates(ctx context.Context, q querier, names []string) (map[string]pcconfig.Camera, error) {
+	templates := make(map[string]pcconfig.Camera)
+	if names != nil && len(names) == 0 {
+		return templates, nil
+	}
+
+	err := inChunks(names, func(where func(string) string, args []interface{}) error {
+		return queryRows(ctx, q, `SELECT name, camera FROM camera_templates`+where("name"), args, func(rows *sql.Rows) error {
+			var name, camera string
+			if err := rows.Scan(&name, &camera); err != nil {
+				return err
+			}
+
+			var tmpl pcconfig.Camera
+			if err := json.Unmarshal([]byte(camera), &tmpl); err != nil {
+				return fmt.Errorf("unable to decode template %q: %w", name, err)
+			}
+
+			templates[name] = tmpl
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// resolveRoom resolves the templates of every camera in room. A template that doesn't exist is reported as ErrNotFound.
+func resolveRoom(room pcconfig.Room, templates map[string]pcconfig.Camera) (pcconfig.Room, error) {
+	resolved, err := pcconfig.ResolveRoomTemplates(room, templates)
+	switch {
+	case errors.Is(err, pcconfig.ErrTemplateNotFound):
+		return pcconfig.Room{}, fmt.Errorf("%v: %w", err, ErrNotFound)
+	case err != nil:
+		return pcconfig.Room{}, err
+	}
+
+	return resolved, nil
+}
+
+// inChunks calls fn for each chunk of values, with a where func that returns a WHERE clause matching
+// column against the chunk, and the chunk as args. If values is nil, fn is called once with no
+// WHERE clause; if it is empty, fn isn't called.
+func inChunks(values []string, fn func(where func(column string) string, args []interface{}) error) error {
+	if values == nil {
+		return fn(func(string) string { return "" }, nil)
+	}
+
+	for start := 0; start < len(values); start += _maxParams {
+		end := start + _maxParams
+		if end > len(values) {
+			end = len(values)
+		}
+
+		args := make([]interface{}, 0, end-start)
+		params := make([]string, 0, end-start)
+		for i, v := range values[start:end] {
+			args = append(args, v)
+			params = append(params, fmt.Sprintf("$%d", i+1))
+		}
+
+		where := func(column string) string {
+			return fmt.Sprintf(" WHERE %s IN (%s)", column, strings.Join(params, ", "))
+		}
+
+		if err := fn(where, args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
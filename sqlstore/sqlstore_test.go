@@ -0,0 +1,265 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/google/go-cmp/cmp"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newDB opens a new SQLite database, without any migrations applied.
+func newDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "pc-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "pc-config.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("unable to open database: %s", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+
+	return db
+}
+
+// newStore creates a store backed by a new, migrated SQLite database.
+func newStore(t *testing.T) *store {
+	t.Helper()
+
+	db := newDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := Migrate(ctx, db); err != nil {
+		t.Fatalf("unable to migrate: %s", err)
+	}
+
+	cs, err := New(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	return cs.(*store)
+}
+
+var (
+	testTemplates = map[string]pcconfig.Camera{
+		"ptz": {
+			PanTiltStop: "http://control/{{room}}/{{cameraAddress}}/stop",
+			Stream:      "rtsp://{{cameraAddress}}/main",
+		},
+	}
+
+	testRooms = []pcconfig.Room{
+		{
+			ID: "ITB-1101",
+			ControlGroups: []pcconfig.ControlGroup{
+				{
+					Name: "Group 1",
+					Cameras: []pcconfig.Camera{
+						{DisplayName: "Front", Template: "ptz", Address: "10.0.0.5"},
+						{
+							DisplayName: "Back",
+							Stream:      "https://stream",
+							Variables:   map[string]string{"zone": "back"},
+							Presets: []pcconfig.CameraPreset{
+								{DisplayName: "Podium", SetPreset: "https://back/preset/1"},
+								{DisplayName: "Audience", SetPreset: "https://back/preset/2", Thumbnail: "back-2"},
+							},
+						},
+					},
+				},
+				{Name: "Group 2", Cameras: []pcconfig.Camera{}},
+			},
+		},
+		{
+			ID: "ITB-1102",
+			ControlGroups: []pcconfig.ControlGroup{
+				{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front", Template: "missing"}}},
+			},
+		},
+	}
+
+	testMappings = []pcconfig.PCMapping{
+		{Hostname: "ITB-1101-CP", Room: "ITB-1101", ControlGroup: "Group 1", Overrides: &pcconfig.PCOverrides{
+			Cameras: []pcconfig.CameraOverride{{Camera: "Back", Hide: true}},
+		}},
+		{Hostname: "ITB-1101-CP2", Room: "ITB-1101", ControlGroup: "Group 2"},
+		{Hostname: "ITB-1102", Room: "ITB-1102", ControlGroup: "Group 1"},
+	}
+)
+
+func TestImport(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.Import(ctx, testMappings, testRooms, testTemplates); err != nil {
+		t.Fatalf("unable to import: %s", err)
+	}
+
+	// the longest mapping is used
+	for hostname, expected := range map[string][2]string{
+		"ITB-1101-CP1": {"ITB-1101", "Group 1"},
+		"ITB-1101-CP2": {"ITB-1101", "Group 2"},
+		"ITB-1102-CP1": {"ITB-1102", "Group 1"},
+	} {
+		room, cg, err := s.RoomAndControlGroup(ctx, hostname)
+		switch {
+		case err != nil:
+			t.Fatalf("unable to get room and control group of %s: %s", hostname, err)
+		case room != expected[0] || cg != expected[1]:
+			t.Fatalf("got wrong room and control group for %s: %q, %q", hostname, room, cg)
+		}
+	}
+
+	if _, _, err := s.RoomAndControlGroup(ctx, "JFSB-CP1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	overrides, id, err := s.Overrides(ctx, "ITB-1101-CP1")
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get overrides: %s", err)
+	case id != "ITB-1101-CP":
+		t.Fatalf("got overrides from wrong mapping %q", id)
+	}
+
+	if diff := cmp.Diff(*testMappings[0].Overrides, overrides); diff != "" {
+		t.Errorf("got wrong overrides (-want, +got):\n%s", diff)
+	}
+
+	cams, err := s.Cameras(ctx, "ITB-1101", "Group 1")
+	if err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	expected := []pcconfig.Camera{
+		{
+			DisplayName: "Front",
+			PanTiltStop: "http://control/ITB-1101/10.0.0.5/stop",
+			Stream:      "rtsp://10.0.0.5/main",
+		},
+		testRooms[0].ControlGroups[0].Cameras[1],
+	}
+
+	if diff := cmp.Diff(expected, cams); diff != "" {
+		t.Errorf("got wrong cameras (-want, +got):\n%s", diff)
+	}
+
+	if _, err := s.Cameras(ctx, "ITB-1102", "Group 1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing template, got %v", err)
+	}
+
+	mappings, err := s.PCMappings(ctx)
+	if err != nil {
+		t.Fatalf("unable to list pc mappings: %s", err)
+	}
+
+	if diff := cmp.Diff(testMappings, mappings); diff != "" {
+		t.Errorf("got wrong pc mappings (-want, +got):\n%s", diff)
+	}
+
+	rooms, err := s.Rooms(ctx)
+	switch {
+	case err != nil:
+		t.Fatalf("unable to list rooms: %s", err)
+	case len(rooms) != 2:
+		t.Fatalf("expected 2 rooms, got %d", len(rooms))
+	}
+
+	found, err := s.PCMappingsFor(ctx, []string{"ITB-1101-CP1", "ITB-1102-CP1", "JFSB-CP1"})
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get pc mappings: %s", err)
+	case len(found) != 2 || found["ITB-1101-CP1"].Hostname != "ITB-1101-CP" || found["ITB-1102-CP1"].Hostname != "ITB-1102":
+		t.Fatalf("got wrong pc mappings: %+v", found)
+	}
+
+	// ITB-1102's template doesn't exist, so it can't be built
+	roomsFor, err := s.RoomsFor(ctx, []string{"ITB-1101", "ITB-1102", "ITB-1103"})
+	switch {
+	case err != nil:
+		t.Fatalf("unable to get rooms: %s", err)
+	case len(roomsFor) != 1:
+		t.Fatalf("expected 1 room, got %d", len(roomsFor))
+	}
+
+	if diff := cmp.Diff(expected, roomsFor["ITB-1101"].ControlGroups[0].Cameras); diff != "" {
+		t.Errorf("got wrong cameras (-want, +got):\n%s", diff)
+	}
+
+	// importing again replaces the room's control groups
+	replaced := pcconfig.Room{ID: "ITB-1101", ControlGroups: []pcconfig.ControlGroup{
+		{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Side", Stream: "https://side"}}},
+	}}
+
+	if err := s.Import(ctx, nil, []pcconfig.Room{replaced}, nil); err != nil {
+		t.Fatalf("unable to import: %s", err)
+	}
+
+	room, err := s.Room(ctx, "ITB-1101")
+	if err != nil {
+		t.Fatalf("unable to get room: %s", err)
+	}
+
+	if diff := cmp.Diff(replaced, room); diff != "" {
+		t.Errorf("room wasn't replaced (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := newDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// New doesn't migrate it
+	if _, err := New(ctx, db); err == nil {
+		t.Fatalf("expected an error for a database that hasn't been migrated")
+	}
+
+	for i := 0; i < 2; i++ {
+		version, err := Migrate(ctx, db)
+		switch {
+		case err != nil:
+			t.Fatalf("unable to migrate: %s", err)
+		case version != len(migrations):
+			t.Fatalf("expected version %d, got %d", len(migrations), version)
+		}
+	}
+
+	if _, err := New(ctx, db); err != nil {
+		t.Fatalf("unable to create config service: %s", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, len(migrations)+1); err != nil {
+		t.Fatalf("unable to add version: %s", err)
+	}
+
+	if _, err := Migrate(ctx, db); err == nil {
+		t.Fatalf("expected an error for a newer schema")
+	}
+
+	if _, err := New(ctx, db); err == nil {
+		t.Fatalf("expected an error creating a config service for a newer schema")
+	}
+}
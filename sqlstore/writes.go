@@ -0,0 +1,276 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// Import adds or replaces each pc mapping, room, and camera template, all in one transaction.
+// Rooms are stored as they are given, so their templates should not be resolved.
+func (s *store) Import(ctx context.Context, mappings []pcconfig.PCMapping, rooms []pcconfig.Room, templates map[string]pcconfig.Camera) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, name := range sortedKeys(templates) {
+			camera, err := json.Marshal(templates[name])
+			if err != nil {
+				return fmt.Errorf("unable to encode template %q: %w", name, err)
+			}
+
+			if _, err := tx.ExecContext(ctx, `INSERT INTO camera_templates (name, camera) VALUES ($1, $2)
+				ON CONFLICT (name) DO UPDATE SET camera = excluded.camera`, name, string(camera)); err != nil {
+				return fmt.Errorf("unable to put template %q: %w", name, err)
+			}
+		}
+
+		for _, room := range rooms {
+			if err := putRoom(ctx, tx, room); err != nil {
+				return fmt.Errorf("unable to put room %q: %w", room.ID, err)
+			}
+
+			if _, err := recordRoom(ctx, tx, room.ID); err != nil {
+				return fmt.Errorf("unable to record room %q: %w", room.ID, err)
+			}
+		}
+
+		for _, m := range mappings {
+			if _, err := putMapping(ctx, tx, m); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *store) SetCameraPreset(ctx context.Context, room, controlGroup, camera string, slot int, preset pcconfig.CameraPreset) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var position int
+		err := tx.QueryRowContext(ctx, `SELECT position FROM cameras WHERE room = $1 AND control_group = $2 AND display_name = $3 ORDER BY position`,
+			room, controlGroup, camera).Scan(&position)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			var n int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM control_groups WHERE room = $1 AND name = $2`, room, controlGroup).Scan(&n); err != nil {
+				return fmt.Errorf("unable to get control group: %w", err)
+			}
+
+			if n == 0 {
+				return errors.New("no matching control group found")
+			}
+
+			return fmt.Errorf("no camera %q in control group %q", camera, controlGroup)
+		case err != nil:
+			return fmt.Errorf("unable to get camera: %w", err)
+		}
+
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM presets WHERE room = $1 AND control_group = $2 AND camera = $3`,
+			room, controlGroup, position).Scan(&count); err != nil {
+			return fmt.Errorf("unable to count presets: %w", err)
+		}
+
+		// presets on a templated camera override the template's slot by slot, so only what is different
+		// from the template's preset is stored. the preset is a position of this camera, so it isn't
+		// written to the template, which other cameras use too.
+		tmpl, err := templatePresets(ctx, tx, room, controlGroup, camera)
+		if err != nil {
+			return err
+		}
+
+		slots := count
+		if len(tmpl) > slots {
+			slots = len(tmpl)
+		}
+
+		if slot < 0 || slot > slots {
+			return fmt.Errorf("invalid preset slot %d", slot)
+		}
+
+		if slot < len(tmpl) {
+			preset = pcconfig.PresetOverride(preset, tmpl[slot])
+		}
+
+		if slot >= count {
+			// empty presets follow the template
+			for i := count; i < slot; i++ {
+				if err := insertPreset(ctx, tx, room, controlGroup, position, i, pcconfig.CameraPreset{}); err != nil {
+					return fmt.Errorf("unable to insert preset: %w", err)
+				}
+			}
+
+			if err := insertPreset(ctx, tx, room, controlGroup, position, slot, preset); err != nil {
+				return fmt.Errorf("unable to insert preset: %w", err)
+			}
+
+			return recordPreset(ctx, tx, room)
+		}
+
+		var existing pcconfig.CameraPreset
+		if err := tx.QueryRowContext(ctx, `SELECT save_preset, thumbnail, preset FROM presets WHERE room = $1 AND control_group = $2 AND camera = $3 AND position = $4`,
+			room, controlGroup, position, slot).Scan(&existing.SavePreset, &existing.Thumbnail, &existing.Preset); err != nil {
+			return fmt.Errorf("unable to get preset: %w", err)
+		}
+
+		// like the other datastores, fields that aren't set keep their existing values
+		if preset.SavePreset == "" {
+			preset.SavePreset = existing.SavePreset
+		}
+
+		if preset.Thumbnail == "" {
+			preset.Thumbnail = existing.Thumbnail
+		}
+
+		if preset.Preset == "" {
+			preset.Preset = existing.Preset
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE presets SET display_name = $1, set_preset = $2, save_preset = $3, thumbnail = $4, preset = $5
+			WHERE room = $6 AND control_group = $7 AND camera = $8 AND position = $9`,
+			preset.DisplayName, preset.SetPreset, preset.SavePreset, preset.Thumbnail, preset.Preset, room, controlGroup, position, slot); err != nil {
+			return fmt.Errorf("unable to update preset: %w", err)
+		}
+
+		return recordPreset(ctx, tx, room)
+	})
+}
+
+// recordPreset records room after one of its presets was saved.
+func recordPreset(ctx context.Context, tx *sql.Tx, room string) error {
+	if _, err := recordRoom(ctx, tx, room); err != nil {
+		return fmt.Errorf("unable to record room: %w", err)
+	}
+
+	return nil
+}
+
+// templatePresets returns the presets camera gets from its template, without its own, if it has one.
+func templatePresets(ctx context.Context, tx *sql.Tx, room, controlGroup, camera string) ([]pcconfig.CameraPreset, error) {
+	rooms, err := loadRooms(ctx, tx, []string{room})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get room: %w", err)
+	}
+
+	for _, r := range rooms {
+		for _, cg := range r.ControlGroups {
+			if cg.Name != controlGroup {
+				continue
+			}
+
+			for _, cam := range cg.Cameras {
+				if cam.DisplayName != camera {
+					continue
+				}
+
+				if cam.Template == "" {
+					return nil, nil
+				}
+
+				cam.Presets = nil
+
+				templates, err := loadTemplates(ctx, tx, []string{cam.Template})
+				if err != nil {
+					return nil, err
+				}
+
+				resolved, err := resolveRoom(pcconfig.Room{ID: room, ControlGroups: []pcconfig.ControlGroup{{Name: cg.Name, Cameras: []pcconfig.Camera{cam}}}}, templates)
+				if err != nil {
+					return nil, err
+				}
+
+				return resolved.ControlGroups[0].Cameras[0].Presets, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *store) PutThumbnail(ctx context.Context, room, key string, jpeg []byte) error {
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO thumbnails (room, name, jpeg) VALUES ($1, $2, $3)
+		ON CONFLICT (room, name) DO UPDATE SET jpeg = excluded.jpeg`, room, key, jpeg); err != nil {
+		return fmt.Errorf("unable to put thumbnail: %w", err)
+	}
+
+	return nil
+}
+
+func (s *store) Thumbnail(ctx context.Context, room, key string) ([]byte, error) {
+	var jpeg []byte
+
+	err := s.db.QueryRowContext(ctx, `SELECT jpeg FROM thumbnails WHERE room = $1 AND name = $2`, room, key).Scan(&jpeg)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("unable to get thumbnail: %w", ErrNotFound)
+	case err != nil:
+		return nil, fmt.Errorf("unable to get thumbnail: %w", err)
+	}
+
+	return jpeg, nil
+}
+
+func (s *store) CheckIn(ctx context.Context, ci pcconfig.CheckIn) error {
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO check_ins (hostname, config_version, app_version, uptime_seconds, checked_in) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (hostname) DO UPDATE SET config_version = excluded.config_version, app_version = excluded.app_version,
+			uptime_seconds = excluded.uptime_seconds, checked_in = excluded.checked_in`,
+		ci.Hostname, ci.ConfigVersion, ci.AppVersion, ci.UptimeSeconds, ci.Time.UTC()); err != nil {
+		return fmt.Errorf("unable to store check in: %w", err)
+	}
+
+	return nil
+}
+
+func (s *store) CheckIns(ctx context.Context) ([]pcconfig.CheckIn, error) {
+	checkIns := []pcconfig.CheckIn{}
+
+	err := queryRows(ctx, s.db, `SELECT hostname, config_version, app_version, uptime_seconds, checked_in FROM check_ins ORDER BY hostname`, nil, func(rows *sql.Rows) error {
+		var ci pcconfig.CheckIn
+		if err := rows.Scan(&ci.Hostname, &ci.ConfigVersion, &ci.AppVersion, &ci.UptimeSeconds, &ci.Time); err != nil {
+			return err
+		}
+
+		ci.Time = ci.Time.UTC()
+		checkIns = append(checkIns, ci)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get check ins: %w", err)
+	}
+
+	return checkIns, nil
+}
+
+// inTx calls fn in a transaction, which is committed if fn doesn't return an error.
+func (s *store) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit: %w", err)
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]pcconfig.Camera) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+package sqlstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetCameraPreset(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.Import(ctx, nil, testRooms, testTemplates); err != nil {
+		t.Fatalf("unable to import: %s", err)
+	}
+
+	// replacing a preset keeps the fields that aren't set
+	if err := s.SetCameraPreset(ctx, "ITB-1101", "Group 1", "Back", 1, pcconfig.CameraPreset{DisplayName: "Crowd", SetPreset: "https://back/preset/2"}); err != nil {
+		t.Fatalf("unable to set preset: %s", err)
+	}
+
+	if err := s.SetCameraPreset(ctx, "ITB-1101", "Group 1", "Back", 2, pcconfig.CameraPreset{DisplayName: "Door", SetPreset: "https://back/preset/3"}); err != nil {
+		t.Fatalf("unable to add preset: %s", err)
+	}
+
+	cams, err := s.Cameras(ctx, "ITB-1101", "Group 1")
+	if err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	expected := []pcconfig.CameraPreset{
+		{DisplayName: "Podium", SetPreset: "https://back/preset/1"},
+		{DisplayName: "Crowd", SetPreset: "https://back/preset/2", Thumbnail: "back-2"},
+		{DisplayName: "Door", SetPreset: "https://back/preset/3"},
+	}
+
+	if diff := cmp.Diff(expected, cams[1].Presets); diff != "" {
+		t.Errorf("got wrong presets (-want, +got):\n%s", diff)
+	}
+
+	for _, tt := range []struct {
+		controlGroup, camera string
+		slot                 int
+	}{
+		{"Group 1", "Back", 5},
+		{"Group 1", "Side", 0},
+		{"Group 3", "Back", 0},
+	} {
+		if err := s.SetCameraPreset(ctx, "ITB-1101", tt.controlGroup, tt.camera, tt.slot, pcconfig.CameraPreset{DisplayName: "x"}); err == nil {
+			t.Errorf("expected an error setting preset %d of %s in %s", tt.slot, tt.camera, tt.controlGroup)
+		}
+	}
+}
+
+func TestSetCameraPresetTemplate(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	templates := map[string]pcconfig.Camera{
+		"ptz": {
+			Stream: "rtsp://{{cameraAddress}}/main",
+			Presets: []pcconfig.CameraPreset{
+				{DisplayName: "Podium", SetPreset: "http://{{cameraAddress}}/preset/1"},
+				{DisplayName: "Board", SetPreset: "http://{{cameraAddress}}/preset/2"},
+			},
+		},
+	}
+
+	if err := s.Import(ctx, nil, testRooms, templates); err != nil {
+		t.Fatalf("unable to import: %s", err)
+	}
+
+	if err := s.SetCameraPreset(ctx, "ITB-1101", "Group 1", "Front", 1, pcconfig.CameraPreset{DisplayName: "Whiteboard", SetPreset: "http://10.0.0.5/preset/2"}); err != nil {
+		t.Fatalf("unable to set preset: %s", err)
+	}
+
+	cams, err := s.Cameras(ctx, "ITB-1101", "Group 1")
+	if err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	// the camera keeps the template's other presets
+	expected := []pcconfig.CameraPreset{
+		{DisplayName: "Podium", SetPreset: "http://10.0.0.5/preset/1"},
+		{DisplayName: "Whiteboard", SetPreset: "http://10.0.0.5/preset/2"},
+	}
+
+	if diff := cmp.Diff(expected, cams[0].Presets); diff != "" {
+		t.Errorf("got wrong presets (-want, +got):\n%s", diff)
+	}
+
+	// only the name was different from the template, so the camera still follows the rest of it
+	templates["ptz"].Presets[0].DisplayName = "Lectern"
+	templates["ptz"].Presets[1].SetPreset = "http://{{cameraAddress}}/preset/3"
+
+	if err := s.Import(ctx, nil, nil, templates); err != nil {
+		t.Fatalf("unable to import: %s", err)
+	}
+
+	cams, err = s.Cameras(ctx, "ITB-1101", "Group 1")
+	if err != nil {
+		t.Fatalf("unable to get cameras: %s", err)
+	}
+
+	expected = []pcconfig.CameraPreset{
+		{DisplayName: "Lectern", SetPreset: "http://10.0.0.5/preset/1"},
+		{DisplayName: "Whiteboard", SetPreset: "http://10.0.0.5/preset/3"},
+	}
+
+	if diff := cmp.Diff(expected, cams[0].Presets); diff != "" {
+		t.Errorf("got wrong presets after the template changed (-want, +got):\n%s", diff)
+	}
+}
+
+func TestThumbnails(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := s.Thumbnail(ctx, "ITB-1101", "back-2"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	for _, jpeg := range [][]byte{{0xff, 0xd8, 0x01}, {0xff, 0xd8, 0x02}} {
+		if err := s.PutThumbnail(ctx, "ITB-1101", "back-2", jpeg); err != nil {
+			t.Fatalf("unable to put thumbnail: %s", err)
+		}
+
+		got, err := s.Thumbnail(ctx, "ITB-1101", "back-2")
+		switch {
+		case err != nil:
+			t.Fatalf("unable to get thumbnail: %s", err)
+		case !bytes.Equal(jpeg, got):
+			t.Fatalf("got wrong thumbnail %v, expected %v", got, jpeg)
+		}
+	}
+}
+
+func TestCheckIns(t *testing.T) {
+	s := newStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, ci := range []pcconfig.CheckIn{
+		{Hostname: "ITB-1101-CP1", ConfigVersion: "abc", Time: now},
+		{Hostname: "ITB-1101-CP2", ConfigVersion: "abc", AppVersion: "1.2.3", UptimeSeconds: 60, Time: now},
+		{Hostname: "ITB-1101-CP1", ConfigVersion: "def", Time: now.Add(time.Minute)},
+	} {
+		if err := s.CheckIn(ctx, ci); err != nil {
+			t.Fatalf("unable to check in: %s", err)
+		}
+	}
+
+	checkIns, err := s.CheckIns(ctx)
+	if err != nil {
+		t.Fatalf("unable to get check ins: %s", err)
+	}
+
+	expected := []pcconfig.CheckIn{
+		{Hostname: "ITB-1101-CP1", ConfigVersion: "def", Time: now.Add(time.Minute)},
+		{Hostname: "ITB-1101-CP2", ConfigVersion: "abc", AppVersion: "1.2.3", UptimeSeconds: 60, Time: now},
+	}
+
+	if diff := cmp.Diff(expected, checkIns); diff != "" {
+		t.Errorf("got wrong check ins (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,303 @@
+package pcconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Variables that are always bound when resolving a camera template.
+const (
+	VariableRoom          = "room"
+	VariableControlGroup  = "controlGroup"
+	VariableCameraAddress = "cameraAddress"
+)
+
+var placeholderRegex = regexp.MustCompile(`{{\s*([A-Za-z0-9_.-]+)\s*}}`)
+
+// UnboundVariablesError is returned when a template uses variables that weren't given a value.
+type UnboundVariablesError []string
+
+func (e UnboundVariablesError) Error() string {
+	return fmt.Sprintf("unbound template variables: %s", strings.Join(e, ", "))
+}
+
+// TemplateVariables returns the variables bound for cam in room and controlGroup.
+// Variables set on cam override the built in ones.
+func TemplateVariables(room, controlGroup string, cam Camera) map[string]string {
+	vars := map[string]string{
+		VariableRoom:         room,
+		VariableControlGroup: controlGroup,
+	}
+
+	if cam.Address != "" {
+		vars[VariableCameraAddress] = cam.Address
+	}
+
+	for k, v := range cam.Variables {
+		vars[k] = v
+	}
+
+	return vars
+}
+
+// ResolveTemplate builds cam from tmpl. Fields set on cam override the ones in tmpl,
+// and every {{variable}} in the result is replaced with its value in vars. Presets are
+// overridden slot by slot, the same way, and presets past the end of tmpl's are added.
+//
+// If any variables aren't in vars, an UnboundVariablesError is returned along with
+// the camera, which still has the unbound placeholders in it.
+func ResolveTemplate(cam, tmpl Camera, vars map[string]string) (Camera, error) {
+	base, err := toJSONMap(tmpl)
+	if err != nil {
+		return cam, err
+	}
+
+	overlay, err := toJSONMap(cam)
+	if err != nil {
+		return cam, err
+	}
+
+	for k, v := range overlay {
+		switch {
+		case k == "presets":
+			base[k] = mergePresets(base[k], v)
+		case !emptyJSON(v):
+			base[k] = v
+		}
+	}
+
+	delete(base, "template")
+	delete(base, "variables")
+
+	unbound := make(map[string]bool)
+	resolved := substitute(base, vars, unbound)
+
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		return cam, fmt.Errorf("unable to marshal camera: %w", err)
+	}
+
+	var out Camera
+	if err := json.Unmarshal(data, &out); err != nil {
+		return cam, fmt.Errorf("unable to unmarshal camera: %w", err)
+	}
+
+	// the address was only there to fill in the template
+	if out.Protocol == "" {
+		out.Address = ""
+	}
+
+	if len(unbound) > 0 {
+		var names UnboundVariablesError
+		for name := range unbound {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		out.Template = cam.Template
+		return out, names
+	}
+
+	return out, nil
+}
+
+// mergePresets overlays the presets on a camera onto its template's presets, slot by slot.
+func mergePresets(tmpl, cam interface{}) interface{} {
+	base, _ := tmpl.([]interface{})
+	overlay, _ := cam.([]interface{})
+	if len(overlay) == 0 {
+		return tmpl
+	}
+
+	merged := append([]interface{}(nil), base...)
+	for i, v := range overlay {
+		if i >= len(merged) {
+			merged = append(merged, v)
+			continue
+		}
+
+		preset, ok := merged[i].(map[string]interface{})
+		override, isMap := v.(map[string]interface{})
+		if !ok || !isMap {
+			merged[i] = v
+			continue
+		}
+
+		for k, field := range override {
+			if !emptyJSON(field) {
+				preset[k] = field
+			}
+		}
+	}
+
+	return merged
+}
+
+// PresetOverride returns what to store in a preset slot on a camera built from a template, so that the
+// camera's preset in that slot is preset. tmpl is the template's (resolved) preset in the slot. Only the
+// fields that are different from tmpl are kept, so that the rest of them keep following the template.
+func PresetOverride(preset, tmpl CameraPreset) CameraPreset {
+	var override CameraPreset
+	if preset.DisplayName != tmpl.DisplayName {
+		override.DisplayName = preset.DisplayName
+	}
+
+	if preset.SetPreset != tmpl.SetPreset {
+		override.SetPreset = preset.SetPreset
+	}
+
+	if preset.SavePreset != tmpl.SavePreset {
+		override.SavePreset = preset.SavePreset
+	}
+
+	if preset.Thumbnail != tmpl.Thumbnail {
+		override.Thumbnail = preset.Thumbnail
+	}
+
+	if preset.Preset != tmpl.Preset {
+		override.Preset = preset.Preset
+	}
+
+	return override
+}
+
+// ErrTemplateNotFound is returned (wrapped) by ResolveRoomTemplates when a camera's template doesn't exist.
+var ErrTemplateNotFound = errors.New("template doesn't exist")
+
+// ResolveRoomTemplates resolves the template of every camera in room that has one. Cameras whose template
+// can't be resolved keep their Template, so that they can be found by validation, and an error for the
+// first of them is returned along with the room. Anything serving the room to PCs should fail on it.
+func ResolveRoomTemplates(room Room, templates map[string]Camera) (Room, error) {
+	resolved := Room{
+		ID:            room.ID,
+		ControlGroups: make([]ControlGroup, len(room.ControlGroups)),
+	}
+
+	var firstErr error
+	for i, cg := range room.ControlGroups {
+		cams := make([]Camera, len(cg.Cameras))
+		for j, cam := range cg.Cameras {
+			cams[j] = cam
+			if cam.Template == "" {
+				continue
+			}
+
+			tmpl, ok := templates[cam.Template]
+			if !ok {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("unable to resolve camera %q: template %q: %w", cam.DisplayName, cam.Template, ErrTemplateNotFound)
+				}
+
+				continue
+			}
+
+			var err error
+			if cams[j], err = ResolveTemplate(cam, tmpl, TemplateVariables(room.ID, cg.Name, cam)); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("unable to resolve template %q for camera %q: %w", cam.Template, cam.DisplayName, err)
+			}
+		}
+
+		cg.Cameras = cams
+		resolved.ControlGroups[i] = cg
+	}
+
+	return resolved, firstErr
+}
+
+// TemplateNames returns the name of every template used in rooms, or an empty (not nil) slice if there aren't any.
+func TemplateNames(rooms ...Room) []string {
+	names := []string{}
+	seen := make(map[string]bool)
+
+	for _, room := range rooms {
+		for _, cg := range room.ControlGroups {
+			for _, cam := range cg.Cameras {
+				if cam.Template != "" && !seen[cam.Template] {
+					seen[cam.Template] = true
+					names = append(names, cam.Template)
+				}
+			}
+		}
+	}
+
+	return names
+}
+
+// Placeholders returns the name of every {{variable}} in cam.
+func Placeholders(cam Camera) []string {
+	m, err := toJSONMap(cam)
+	if err != nil {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	substitute(m, nil, found)
+
+	var names []string
+	for name := range found {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// substitute replaces the placeholders in every string in v, adding the names of any
+// placeholders that aren't in vars to unbound.
+func substitute(v interface{}, vars map[string]string, unbound map[string]bool) interface{} {
+	switch v := v.(type) {
+	case string:
+		return placeholderRegex.ReplaceAllStringFunc(v, func(match string) string {
+			name := placeholderRegex.FindStringSubmatch(match)[1]
+			if val, ok := vars[name]; ok {
+				return val
+			}
+
+			unbound[name] = true
+			return match
+		})
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = substitute(val, vars, unbound)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = substitute(val, vars, unbound)
+		}
+	}
+
+	return v
+}
+
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal: %w", err)
+	}
+
+	return m, nil
+}
+
+func emptyJSON(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
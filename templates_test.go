@@ -0,0 +1,141 @@
+package pcconfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveTemplate(t *testing.T) {
+	tmpl := Camera{
+		PanLeft:     "http://control/{{room}}/{{cameraAddress}}/left",
+		PanTiltStop: "http://control/{{room}}/{{cameraAddress}}/stop",
+		Stream:      "rtsp://{{cameraAddress}}/{{ streamPath }}",
+		Presets: []CameraPreset{
+			{DisplayName: "Home", SetPreset: "http://control/{{room}}/{{cameraAddress}}/preset/1"},
+		},
+	}
+
+	cam := Camera{
+		DisplayName: "Front",
+		Template:    "ptz",
+		Address:     "10.0.0.5",
+		PanLeft:     "http://other/{{controlGroup}}/left",
+		Variables:   map[string]string{"streamPath": "main"},
+	}
+
+	expected := Camera{
+		DisplayName: "Front",
+		PanLeft:     "http://other/Group 1/left",
+		PanTiltStop: "http://control/ITB-1101/10.0.0.5/stop",
+		Stream:      "rtsp://10.0.0.5/main",
+		Presets: []CameraPreset{
+			{DisplayName: "Home", SetPreset: "http://control/ITB-1101/10.0.0.5/preset/1"},
+		},
+	}
+
+	got, err := ResolveTemplate(cam, tmpl, TemplateVariables("ITB-1101", "Group 1", cam))
+	if err != nil {
+		t.Fatalf("unable to resolve template: %s", err)
+	}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("resolved template incorrectly (-want, +got):\n%s", diff)
+	}
+}
+
+func TestResolveTemplateUnbound(t *testing.T) {
+	tmpl := Camera{Stream: "rtsp://{{cameraAddress}}/{{streamPath}}"}
+	cam := Camera{DisplayName: "Front", Template: "ptz"}
+
+	got, err := ResolveTemplate(cam, tmpl, TemplateVariables("ITB-1101", "Group 1", cam))
+
+	var unbound UnboundVariablesError
+	if !errors.As(err, &unbound) {
+		t.Fatalf("expected an UnboundVariablesError, got %v", err)
+	}
+
+	if diff := cmp.Diff(UnboundVariablesError{"cameraAddress", "streamPath"}, unbound); diff != "" {
+		t.Errorf("got wrong unbound variables (-want, +got):\n%s", diff)
+	}
+
+	if got.Template != "ptz" || len(Placeholders(got)) != 2 {
+		t.Errorf("expected unresolved camera to keep its template and placeholders, got %+v", got)
+	}
+}
+
+func TestResolveRoomTemplates(t *testing.T) {
+	room := Room{
+		ID: "ITB-1101",
+		ControlGroups: []ControlGroup{
+			{
+				Name: "Group 1",
+				Cameras: []Camera{
+					{DisplayName: "Front", Template: "ptz", Address: "10.0.0.5"},
+					{DisplayName: "Side", Template: "missing"},
+					{DisplayName: "Back", Stream: "rtsp://back/main"},
+				},
+			},
+		},
+	}
+
+	templates := map[string]Camera{
+		"ptz": {Stream: "rtsp://{{cameraAddress}}/main"},
+	}
+
+	expected := []Camera{
+		{DisplayName: "Front", Stream: "rtsp://10.0.0.5/main"},
+		{DisplayName: "Side", Template: "missing"},
+		{DisplayName: "Back", Stream: "rtsp://back/main"},
+	}
+
+	got, err := ResolveRoomTemplates(room, templates)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound, got %v", err)
+	}
+
+	if diff := cmp.Diff(expected, got.ControlGroups[0].Cameras); diff != "" {
+		t.Errorf("resolved templates incorrectly (-want, +got):\n%s", diff)
+	}
+
+	if names := TemplateNames(room); len(names) != 2 || names[0] != "ptz" || names[1] != "missing" {
+		t.Errorf("got wrong template names: %v", names)
+	}
+}
+
+func TestResolveTemplatePresets(t *testing.T) {
+	tmpl := Camera{
+		Presets: []CameraPreset{
+			{DisplayName: "Podium", SetPreset: "http://{{cameraAddress}}/preset/1"},
+			{DisplayName: "Board", SetPreset: "http://{{cameraAddress}}/preset/2"},
+		},
+	}
+
+	// the first slot follows the template, the second is renamed, and the third is added
+	cam := Camera{
+		DisplayName: "Front",
+		Template:    "ptz",
+		Address:     "10.0.0.5",
+		Presets: []CameraPreset{
+			{},
+			PresetOverride(CameraPreset{DisplayName: "Whiteboard", SetPreset: "http://10.0.0.5/preset/2"}, CameraPreset{DisplayName: "Board", SetPreset: "http://10.0.0.5/preset/2"}),
+			{DisplayName: "Door", SetPreset: "http://10.0.0.5/preset/3"},
+		},
+	}
+
+	expected := []CameraPreset{
+		{DisplayName: "Podium", SetPreset: "http://10.0.0.5/preset/1"},
+		{DisplayName: "Whiteboard", SetPreset: "http://10.0.0.5/preset/2"},
+		{DisplayName: "Door", SetPreset: "http://10.0.0.5/preset/3"},
+	}
+
+	got, err := ResolveTemplate(cam, tmpl, TemplateVariables("ITB-1101", "Group 1", cam))
+	if err != nil {
+		t.Fatalf("unable to resolve template: %s", err)
+	}
+
+	if diff := cmp.Diff(expected, got.Presets); diff != "" {
+		t.Errorf("resolved presets incorrectly (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,171 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// ConsistencyReport lists the places where the pc mappings and room configurations don't agree.
+type ConsistencyReport struct {
+	// MissingRooms are mappings pointing at rooms that don't exist.
+	MissingRooms []pcconfig.PCMapping `json:"missingRooms"`
+
+	// MissingControlGroups are mappings pointing at control groups that aren't in their room.
+	MissingControlGroups []pcconfig.PCMapping `json:"missingControlGroups"`
+
+	// UnmappedControlGroups are control groups with cameras that no PC is mapped to.
+	UnmappedControlGroups []ControlGroupRef `json:"unmappedControlGroups"`
+
+	// AmbiguousMappings are mappings that overlap in a way that makes it hard to tell which one a PC gets.
+	AmbiguousMappings []AmbiguousMapping `json:"ambiguousMappings"`
+}
+
+// ControlGroupRef identifies a control group.
+type ControlGroupRef struct {
+	Room         string `json:"room"`
+	ControlGroup string `json:"controlGroup"`
+	Cameras      int    `json:"cameras"`
+}
+
+// AmbiguousMapping is a mapping that overlaps with another one.
+type AmbiguousMapping struct {
+	Mapping pcconfig.PCMapping `json:"mapping"`
+
+	// Other is the mapping it overlaps with, if there is one.
+	Other *pcconfig.PCMapping `json:"other,omitempty"`
+
+	Reason string `json:"reason"`
+}
+
+// Consistent returns true if nothing was found.
+func (r ConsistencyReport) Consistent() bool {
+	return len(r.MissingRooms) == 0 && len(r.MissingControlGroups) == 0 && len(r.UnmappedControlGroups) == 0 && len(r.AmbiguousMappings) == 0
+}
+
+// Consistency compares every pc mapping in lister against every room in lister.
+func Consistency(ctx context.Context, lister pcconfig.ConfigLister) (ConsistencyReport, error) {
+	rooms, err := lister.Rooms(ctx)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("unable to get rooms: %w", err)
+	}
+
+	mappings, err := lister.PCMappings(ctx)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("unable to get pc mappings: %w", err)
+	}
+
+	return CompareMappings(mappings, rooms), nil
+}
+
+// CompareMappings builds a ConsistencyReport from mappings and rooms.
+func CompareMappings(mappings []pcconfig.PCMapping, rooms []pcconfig.Room) ConsistencyReport {
+	report := ConsistencyReport{
+		MissingRooms:          []pcconfig.PCMapping{},
+		MissingControlGroups:  []pcconfig.PCMapping{},
+		UnmappedControlGroups: []ControlGroupRef{},
+		AmbiguousMappings:     []AmbiguousMapping{},
+	}
+
+	type key struct {
+		room string
+		cg   string
+	}
+
+	roomExists := make(map[string]bool)
+	mapped := make(map[key]bool)
+	for _, room := range rooms {
+		roomExists[room.ID] = true
+		for _, cg := range room.ControlGroups {
+			mapped[key{room.ID, cg.Name}] = false
+		}
+	}
+
+	for _, m := range mappings {
+		k := key{m.Room, m.ControlGroup}
+		_, cgExists := mapped[k]
+
+		switch {
+		case !roomExists[m.Room]:
+			report.MissingRooms = append(report.MissingRooms, m)
+		case !cgExists:
+			report.MissingControlGroups = append(report.MissingControlGroups, m)
+		default:
+			mapped[k] = true
+		}
+	}
+
+	for _, room := range rooms {
+		for _, cg := range room.ControlGroups {
+			if len(cg.Cameras) > 0 && !mapped[key{room.ID, cg.Name}] {
+				report.UnmappedControlGroups = append(report.UnmappedControlGroups, ControlGroupRef{
+					Room:         room.ID,
+					ControlGroup: cg.Name,
+					Cameras:      len(cg.Cameras),
+				})
+			}
+		}
+	}
+
+	report.AmbiguousMappings = append(report.AmbiguousMappings, ambiguousMappings(mappings)...)
+	return report
+}
+
+// ambiguousMappings finds mappings that are too short to ever be used, that only
+// differ by case, or that are a prefix of a mapping to a different control group.
+func ambiguousMappings(mappings []pcconfig.PCMapping) []AmbiguousMapping {
+	var ambiguous []AmbiguousMapping
+
+	sorted := make([]pcconfig.PCMapping, len(mappings))
+	copy(sorted, mappings)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Hostname < sorted[j].Hostname
+	})
+
+	folded := make(map[string]pcconfig.PCMapping)
+	for i, m := range sorted {
+		if len(m.Hostname) < pcconfig.MinMappingLength {
+			ambiguous = append(ambiguous, AmbiguousMapping{
+				Mapping: m,
+				Reason:  fmt.Sprintf("shorter than %d characters, so hostnames are never trimmed down to it", pcconfig.MinMappingLength),
+			})
+
+			continue
+		}
+
+		lower := strings.ToLower(m.Hostname)
+		if other, ok := folded[lower]; ok {
+			other := other
+			ambiguous = append(ambiguous, AmbiguousMapping{
+				Mapping: m,
+				Other:   &other,
+				Reason:  "only differs by case",
+			})
+		}
+
+		folded[lower] = m
+
+		// sorted, so every mapping m is a prefix of comes right after it
+		for _, longer := range sorted[i+1:] {
+			if !strings.HasPrefix(longer.Hostname, m.Hostname) {
+				break
+			}
+
+			if longer.Room == m.Room && longer.ControlGroup == m.ControlGroup {
+				continue
+			}
+
+			longer := longer
+			ambiguous = append(ambiguous, AmbiguousMapping{
+				Mapping: m,
+				Other:   &longer,
+				Reason:  "is a prefix of a mapping to a different control group, so which one a PC gets depends on its full hostname",
+			})
+		}
+	}
+
+	return ambiguous
+}
@@ -0,0 +1,91 @@
+package validate
+
+import (
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareMappings(t *testing.T) {
+	rooms := []pcconfig.Room{
+		{
+			ID: "ITB-1101",
+			ControlGroups: []pcconfig.ControlGroup{
+				{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front"}}},
+				{Name: "Group 2", Cameras: []pcconfig.Camera{{DisplayName: "Back"}}},
+				{Name: "No Cameras"},
+			},
+		},
+	}
+
+	mappings := []pcconfig.PCMapping{
+		{Hostname: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1"},
+		{Hostname: "ITB-1101-CP", Room: "ITB-1101", ControlGroup: "Group 1"},
+		{Hostname: "ITB-1101", Room: "ITB-1101", ControlGroup: "Group 3"},
+		{Hostname: "itb-1101-cp1", Room: "ITB-1101", ControlGroup: "Group 1"},
+		{Hostname: "IT", Room: "ITB-1102", ControlGroup: "Group 1"},
+	}
+
+	got := CompareMappings(mappings, rooms)
+
+	expected := ConsistencyReport{
+		MissingRooms: []pcconfig.PCMapping{
+			{Hostname: "IT", Room: "ITB-1102", ControlGroup: "Group 1"},
+		},
+		MissingControlGroups: []pcconfig.PCMapping{
+			{Hostname: "ITB-1101", Room: "ITB-1101", ControlGroup: "Group 3"},
+		},
+		UnmappedControlGroups: []ControlGroupRef{
+			{Room: "ITB-1101", ControlGroup: "Group 2", Cameras: 1},
+		},
+		AmbiguousMappings: []AmbiguousMapping{
+			{
+				Mapping: mappings[4],
+				Reason:  "shorter than 3 characters, so hostnames are never trimmed down to it",
+			},
+			{
+				Mapping: mappings[2],
+				Other:   &mappings[1],
+				Reason:  "is a prefix of a mapping to a different control group, so which one a PC gets depends on its full hostname",
+			},
+			{
+				Mapping: mappings[2],
+				Other:   &mappings[0],
+				Reason:  "is a prefix of a mapping to a different control group, so which one a PC gets depends on its full hostname",
+			},
+			{
+				Mapping: mappings[3],
+				Other:   &mappings[0],
+				Reason:  "only differs by case",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("got incorrect report (-want, +got):\n%s", diff)
+	}
+
+	if got.Consistent() {
+		t.Fatalf("expected report to be inconsistent")
+	}
+}
+
+func TestCompareMappingsConsistent(t *testing.T) {
+	rooms := []pcconfig.Room{
+		{
+			ID: "ITB-1101",
+			ControlGroups: []pcconfig.ControlGroup{
+				{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front"}}},
+			},
+		},
+	}
+
+	mappings := []pcconfig.PCMapping{
+		{Hostname: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1"},
+	}
+
+	if report := CompareMappings(mappings, rooms); !report.Consistent() {
+		t.Fatalf("expected report to be consistent, got %+v", report)
+	}
+}
@@ -0,0 +1,393 @@
+// Package validate checks pc-config configuration for mistakes before a PC runs into them.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	pcconfig "github.com/byuoitav/pc-config"
+)
+
+// Severity is how bad a Problem is.
+type Severity string
+
+const (
+	// SeverityError means something is broken for users.
+	SeverityError Severity = "error"
+
+	// SeverityWarning means something is probably wrong, but still works.
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a single issue found in the configuration.
+type Problem struct {
+	Severity Severity `json:"severity"`
+
+	Room         string `json:"room,omitempty"`
+	ControlGroup string `json:"controlGroup,omitempty"`
+	Camera       string `json:"camera,omitempty"`
+	Preset       string `json:"preset,omitempty"`
+	PC           string `json:"pc,omitempty"`
+	Field        string `json:"field,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	var where string
+	for _, s := range []string{p.PC, p.Room, p.ControlGroup, p.Camera, p.Preset, p.Field} {
+		if s == "" {
+			continue
+		}
+
+		if where != "" {
+			where += " > "
+		}
+
+		where += s
+	}
+
+	return fmt.Sprintf("%s: %s: %s", p.Severity, where, p.Message)
+}
+
+// Report is the result of validating a datastore.
+type Report struct {
+	Rooms      int       `json:"rooms"`
+	PCMappings int       `json:"pcMappings"`
+	Errors     int       `json:"errors"`
+	Warnings   int       `json:"warnings"`
+	Problems   []Problem `json:"problems"`
+}
+
+// Valid returns true if the report doesn't have any errors.
+func (r Report) Valid() bool {
+	return r.Errors == 0
+}
+
+// All validates every room and pc mapping in lister.
+func All(ctx context.Context, lister pcconfig.ConfigLister) (Report, error) {
+	rooms, err := lister.Rooms(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("unable to get rooms: %w", err)
+	}
+
+	mappings, err := lister.PCMappings(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("unable to get pc mappings: %w", err)
+	}
+
+	var problems []Problem
+	for _, room := range rooms {
+		problems = append(problems, Room(room)...)
+	}
+
+	problems = append(problems, PCMappings(mappings, rooms)...)
+	return NewReport(len(rooms), len(mappings), problems), nil
+}
+
+// NewReport builds a report from problems.
+func NewReport(rooms, mappings int, problems []Problem) Report {
+	report := Report{
+		Rooms:      rooms,
+		PCMappings: mappings,
+		Problems:   problems,
+	}
+
+	if report.Problems == nil {
+		report.Problems = []Problem{}
+	}
+
+	for _, p := range problems {
+		switch p.Severity {
+		case SeverityError:
+			report.Errors++
+		case SeverityWarning:
+			report.Warnings++
+		}
+	}
+
+	return report
+}
+
+// Room checks every control group in room.
+func Room(room pcconfig.Room) []Problem {
+	var problems []Problem
+	seen := make(map[string]bool)
+
+	for _, cg := range room.ControlGroups {
+		if seen[cg.Name] {
+			problems = append(problems, Problem{
+				Severity:     SeverityError,
+				Room:         room.ID,
+				ControlGroup: cg.Name,
+				Message:      "duplicate control group name",
+			})
+		}
+
+		seen[cg.Name] = true
+		problems = append(problems, ControlGroup(room.ID, cg)...)
+	}
+
+	return problems
+}
+
+// ControlGroup checks every camera in cg.
+func ControlGroup(room string, cg pcconfig.ControlGroup) []Problem {
+	problems := Cameras(cg.Cameras)
+	for i := range problems {
+		problems[i].Room = room
+		problems[i].ControlGroup = cg.Name
+	}
+
+	return problems
+}
+
+// Cameras checks each camera in cams, and that their names are unique.
+func Cameras(cams []pcconfig.Camera) []Problem {
+	var problems []Problem
+	seen := make(map[string]bool)
+
+	for _, cam := range cams {
+		if cam.DisplayName != "" && seen[cam.DisplayName] {
+			problems = append(problems, Problem{
+				Severity: SeverityError,
+				Camera:   cam.DisplayName,
+				Message:  "duplicate camera name",
+			})
+		}
+
+		seen[cam.DisplayName] = true
+		problems = append(problems, Camera(cam)...)
+	}
+
+	return problems
+}
+
+// Camera checks a single camera and its presets.
+func Camera(cam pcconfig.Camera) []Problem {
+	var problems []Problem
+	add := func(severity Severity, preset, field, format string, a ...interface{}) {
+		problems = append(problems, Problem{
+			Severity: severity,
+			Camera:   cam.DisplayName,
+			Preset:   preset,
+			Field:    field,
+			Message:  fmt.Sprintf(format, a...),
+		})
+	}
+
+	checkURL := func(preset, field, u string, schemes ...string) {
+		if u == "" {
+			return
+		}
+
+		if msg := badURL(u, schemes...); msg != "" {
+			add(SeverityError, preset, field, "%s", msg)
+		}
+	}
+
+	if cam.DisplayName == "" {
+		add(SeverityError, "", "displayName", "camera has no name")
+	}
+
+	controls := []struct {
+		field string
+		url   string
+	}{
+		{"tiltUp", cam.TiltUp},
+		{"tiltDown", cam.TiltDown},
+		{"panLeft", cam.PanLeft},
+		{"panRight", cam.PanRight},
+		{"panTiltStop", cam.PanTiltStop},
+		{"zoomIn", cam.ZoomIn},
+		{"zoomOut", cam.ZoomOut},
+		{"zoomStop", cam.ZoomStop},
+	}
+
+	for _, c := range controls {
+		checkURL("", c.field, c.url, "http", "https")
+	}
+
+	checkURL("", "stream", cam.Stream, "http", "https", "rtsp")
+	checkURL("", "snapshot", cam.Snapshot, "http", "https")
+
+	switch {
+	case cam.Protocol != "" && cam.Address == "":
+		add(SeverityError, "", "address", "camera has a protocol but no address")
+	case cam.Protocol == "" && cam.Address != "" && cam.Template == "":
+		add(SeverityWarning, "", "protocol", "camera has an address but no protocol, so the address is ignored")
+	}
+
+	// cameras only keep their template if it couldn't be resolved
+	placeholders := pcconfig.Placeholders(cam)
+	for _, name := range placeholders {
+		add(SeverityError, "", "template", "template variable %q is not bound", name)
+	}
+
+	if cam.Template != "" && len(placeholders) == 0 {
+		add(SeverityError, "", "template", "template %q doesn't exist", cam.Template)
+	}
+
+	// moving a camera without a way to stop it is worse than not moving it at all
+	moves := cam.TiltUp != "" || cam.TiltDown != "" || cam.PanLeft != "" || cam.PanRight != ""
+	if moves && cam.PanTiltStop == "" {
+		add(SeverityError, "", "panTiltStop", "camera can pan/tilt but has no stop url")
+	}
+
+	if (cam.ZoomIn != "" || cam.ZoomOut != "") && cam.ZoomStop == "" {
+		add(SeverityError, "", "zoomStop", "camera can zoom but has no stop url")
+	}
+
+	if cam.Stream == "" {
+		add(SeverityWarning, "", "stream", "camera has no stream url")
+	}
+
+	for _, capability := range cam.Capabilities {
+		for _, action := range capability.Actions {
+			field := fmt.Sprintf("capabilities.%s.%s", capability.Type, action.Name)
+
+			switch {
+			case action.Name == "":
+				add(SeverityError, "", fmt.Sprintf("capabilities.%s", capability.Type), "action has no name")
+			case action.URL == "" && cam.Protocol == "":
+				add(SeverityError, "", field, "action has no url")
+			default:
+				checkURL("", field, action.URL, "http", "https")
+			}
+		}
+	}
+
+	presets := make(map[string]bool)
+	for i, preset := range cam.Presets {
+		name := preset.DisplayName
+		if name == "" {
+			name = fmt.Sprintf("preset %d", i)
+			add(SeverityError, name, "displayName", "preset has no name")
+		} else if presets[name] {
+			add(SeverityError, name, "displayName", "duplicate preset name")
+		}
+
+		presets[name] = true
+
+		switch {
+		case cam.Protocol != "" && preset.Preset == "":
+			add(SeverityError, name, "preset", "preset has no camera preset")
+		case cam.Protocol == "" && preset.SetPreset == "":
+			add(SeverityError, name, "setPreset", "preset has no setPreset url")
+		}
+
+		checkURL(name, "setPreset", preset.SetPreset, "http", "https")
+		checkURL(name, "savePreset", preset.SavePreset, "http", "https")
+	}
+
+	return problems
+}
+
+// PCMappings checks that every mapping points at a room and control group that exist,
+// and that its overrides only refer to cameras in that control group.
+func PCMappings(mappings []pcconfig.PCMapping, rooms []pcconfig.Room) []Problem {
+	var problems []Problem
+
+	groups := make(map[string]map[string][]pcconfig.Camera)
+	for _, room := range rooms {
+		groups[room.ID] = make(map[string][]pcconfig.Camera)
+		for _, cg := range room.ControlGroups {
+			groups[room.ID][cg.Name] = cg.Cameras
+		}
+	}
+
+	for _, m := range mappings {
+		p := Problem{
+			Severity:     SeverityError,
+			PC:           m.Hostname,
+			Room:         m.Room,
+			ControlGroup: m.ControlGroup,
+		}
+
+		cgs, ok := groups[m.Room]
+		cams, cgOK := cgs[m.ControlGroup]
+		switch {
+		case m.Room == "":
+			p.Message = "mapping has no room"
+		case m.ControlGroup == "":
+			p.Message = "mapping has no control group"
+		case !ok:
+			p.Message = "mapping points at a room that doesn't exist"
+		case !cgOK:
+			p.Message = "mapping points at a control group that doesn't exist"
+		default:
+			problems = append(problems, overrides(m, cams)...)
+			continue
+		}
+
+		problems = append(problems, p)
+	}
+
+	return problems
+}
+
+// overrides checks that every camera m's overrides refer to is in cams.
+func overrides(m pcconfig.PCMapping, cams []pcconfig.Camera) []Problem {
+	if m.Overrides == nil {
+		return nil
+	}
+
+	var problems []Problem
+	for _, o := range m.Overrides.Cameras {
+		found := false
+		for _, cam := range cams {
+			if cam.DisplayName == o.Camera {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			problems = append(problems, Problem{
+				Severity:     SeverityWarning,
+				PC:           m.Hostname,
+				Room:         m.Room,
+				ControlGroup: m.ControlGroup,
+				Camera:       o.Camera,
+				Field:        "overrides",
+				Message:      "override refers to a camera that isn't in the control group",
+			})
+		}
+
+		if o.Stream != "" {
+			if msg := badURL(o.Stream, "http", "https", "rtsp"); msg != "" {
+				problems = append(problems, Problem{
+					Severity:     SeverityError,
+					PC:           m.Hostname,
+					Room:         m.Room,
+					ControlGroup: m.ControlGroup,
+					Camera:       o.Camera,
+					Field:        "overrides.stream",
+					Message:      msg,
+				})
+			}
+		}
+	}
+
+	return problems
+}
+
+// badURL returns why u isn't a usable url, or an empty string if it is.
+func badURL(u string, schemes ...string) string {
+	parsed, err := url.Parse(u)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("invalid url: %s", err)
+	case parsed.Scheme == "" || parsed.Host == "":
+		return fmt.Sprintf("%q is not an absolute url", u)
+	}
+
+	for _, s := range schemes {
+		if parsed.Scheme == s {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("PCs can't use %q urls", parsed.Scheme)
+}
@@ -0,0 +1,138 @@
+package validate
+
+import (
+	"testing"
+
+	pcconfig "github.com/byuoitav/pc-config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidCamera(t *testing.T) {
+	cam := pcconfig.Camera{
+		DisplayName: "Front",
+		TiltUp:      "http://camera/tiltUp",
+		PanTiltStop: "http://camera/stop",
+		Stream:      "rtsp://camera/stream",
+		Presets: []pcconfig.CameraPreset{
+			{DisplayName: "Podium", SetPreset: "http://camera/preset/1"},
+		},
+	}
+
+	if problems := Camera(cam); len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+}
+
+func TestCameras(t *testing.T) {
+	cams := []pcconfig.Camera{
+		{
+			DisplayName: "Front",
+			ZoomIn:      "camera/zoomIn",
+			ZoomStop:    "ftp://camera/zoomStop",
+			Stream:      "https://camera/stream",
+			Presets: []pcconfig.CameraPreset{
+				{DisplayName: "Podium"},
+				{DisplayName: "Podium", SetPreset: "http://camera/preset/2"},
+			},
+		},
+		{
+			DisplayName: "Front",
+			Protocol:    "visca",
+			Stream:      "https://camera/stream",
+			Presets: []pcconfig.CameraPreset{
+				{DisplayName: "Podium", Preset: "1"},
+			},
+		},
+	}
+
+	expected := []Problem{
+		{Severity: SeverityError, Camera: "Front", Field: "zoomIn", Message: `"camera/zoomIn" is not an absolute url`},
+		{Severity: SeverityError, Camera: "Front", Field: "zoomStop", Message: `PCs can't use "ftp" urls`},
+		{Severity: SeverityError, Camera: "Front", Preset: "Podium", Field: "setPreset", Message: "preset has no setPreset url"},
+		{Severity: SeverityError, Camera: "Front", Preset: "Podium", Field: "displayName", Message: "duplicate preset name"},
+		{Severity: SeverityError, Camera: "Front", Message: "duplicate camera name"},
+		{Severity: SeverityError, Camera: "Front", Field: "address", Message: "camera has a protocol but no address"},
+	}
+
+	if diff := cmp.Diff(expected, Cameras(cams)); diff != "" {
+		t.Errorf("got incorrect problems (-want, +got):\n%s", diff)
+	}
+}
+
+func TestUnresolvedTemplates(t *testing.T) {
+	cams := []pcconfig.Camera{
+		{
+			DisplayName: "Front",
+			Template:    "ptz",
+			Address:     "10.0.0.5",
+			PanTiltStop: "http://control/{{streamPath}}/stop",
+			Stream:      "https://camera/{{streamPath}}",
+		},
+		{
+			DisplayName: "Back",
+			Template:    "missing",
+			Stream:      "https://camera/stream",
+		},
+	}
+
+	expected := []Problem{
+		{Severity: SeverityError, Camera: "Front", Field: "template", Message: `template variable "streamPath" is not bound`},
+		{Severity: SeverityError, Camera: "Back", Field: "template", Message: `template "missing" doesn't exist`},
+	}
+
+	if diff := cmp.Diff(expected, Cameras(cams)); diff != "" {
+		t.Errorf("got incorrect problems (-want, +got):\n%s", diff)
+	}
+}
+
+func TestPCMappings(t *testing.T) {
+	rooms := []pcconfig.Room{
+		{
+			ID: "ITB-1101",
+			ControlGroups: []pcconfig.ControlGroup{
+				{Name: "Group 1", Cameras: []pcconfig.Camera{{DisplayName: "Front"}}},
+			},
+		},
+	}
+
+	overrides := &pcconfig.PCOverrides{
+		Cameras: []pcconfig.CameraOverride{
+			{Camera: "Front", Stream: "ftp://front/stream"},
+			{Camera: "Back", Hide: true},
+		},
+	}
+
+	mappings := []pcconfig.PCMapping{
+		{Hostname: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1", Overrides: overrides},
+		{Hostname: "ITB-1101-CP2", Room: "ITB-1101", ControlGroup: "Group 2"},
+		{Hostname: "ITB-1102-CP1", Room: "ITB-1102", ControlGroup: "Group 1"},
+	}
+
+	expected := []Problem{
+		{Severity: SeverityError, PC: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1", Camera: "Front", Field: "overrides.stream", Message: `PCs can't use "ftp" urls`},
+		{Severity: SeverityWarning, PC: "ITB-1101-CP1", Room: "ITB-1101", ControlGroup: "Group 1", Camera: "Back", Field: "overrides", Message: "override refers to a camera that isn't in the control group"},
+		{Severity: SeverityError, PC: "ITB-1101-CP2", Room: "ITB-1101", ControlGroup: "Group 2", Message: "mapping points at a control group that doesn't exist"},
+		{Severity: SeverityError, PC: "ITB-1102-CP1", Room: "ITB-1102", ControlGroup: "Group 1", Message: "mapping points at a room that doesn't exist"},
+	}
+
+	if diff := cmp.Diff(expected, PCMappings(mappings, rooms)); diff != "" {
+		t.Errorf("got incorrect problems (-want, +got):\n%s", diff)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	report := NewReport(1, 2, []Problem{
+		{Severity: SeverityError},
+		{Severity: SeverityWarning},
+		{Severity: SeverityWarning},
+	})
+
+	switch {
+	case report.Errors != 1 || report.Warnings != 2:
+		t.Fatalf("got wrong counts: %+v", report)
+	case report.Valid():
+		t.Fatalf("expected report with errors to be invalid")
+	case !NewReport(0, 0, nil).Valid():
+		t.Fatalf("expected empty report to be valid")
+	}
+}